@@ -21,6 +21,10 @@ func main() {
 	}
 
 	if err := Run(afero.NewOsFs(), homeDir, cli.NewPromptUI(), os.Stdout, os.Stderr, os.Args[1:]); err != nil {
+		var exitErr *cli.ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.Code)
+		}
 		if errors.Is(err, cli.ErrPromptCancelled) {
 			fmt.Fprintln(os.Stderr, "Cancelled by user.")
 		} else {
@@ -40,6 +44,9 @@ func Run(fs afero.Fs, homeDir string, prompter cli.Prompter, stdout, stderr io.W
 	if err := manager.InitInfra(); err != nil {
 		return fmt.Errorf("failed to initialize directories: %w", err)
 	}
+	if err := manager.LoadConfig(); err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
 
 	root := cli.NewRootCommand(manager, prompter, stdout, stderr)
 	root.SilenceUsage = true
@@ -89,3 +89,38 @@ func TestRunPromptCancelled(t *testing.T) {
 		t.Fatalf("expected prompt cancelled error, got %v", err)
 	}
 }
+
+func TestRunWarnsAboutCorruptSettings(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	home := "/home/test"
+	if err := afero.WriteFile(fs, filepath.Join(home, ".claude", "settings.json"), []byte(`{"model": `), 0o644); err != nil {
+		t.Fatalf("write settings: %v", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if err := Run(fs, home, noopPrompter{}, &stdout, &stderr, []string{"list"}); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if !bytes.Contains(stderr.Bytes(), []byte("not valid JSON")) || !bytes.Contains(stderr.Bytes(), []byte("ccs recover")) {
+		t.Fatalf("expected corruption warning, got stderr %q", stderr.String())
+	}
+}
+
+func TestRunLintReturnsExitError(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	home := "/home/test"
+	settings := `{"permissions": {"allow": ["Bash(*)"]}}`
+	if err := afero.WriteFile(fs, filepath.Join(home, ".claude", "settings.json"), []byte(settings), 0o644); err != nil {
+		t.Fatalf("write settings: %v", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	err := Run(fs, home, noopPrompter{}, &stdout, &stderr, []string{"lint"})
+	var exitErr *cli.ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 3 {
+		t.Fatalf("expected exit status 3, got %v", err)
+	}
+	if !bytes.Contains(stdout.Bytes(), []byte("no-bash-wildcard")) {
+		t.Fatalf("expected lint findings, got %q", stdout.String())
+	}
+}
@@ -6,6 +6,7 @@ require (
 	github.com/manifoldco/promptui v0.9.0
 	github.com/spf13/afero v1.11.0
 	github.com/spf13/cobra v1.10.1
+	golang.org/x/crypto v0.17.0
 )
 
 require (
@@ -0,0 +1,100 @@
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ErrBackupNotFound indicates that no stored blob matches the requested hash.
+var ErrBackupNotFound = errors.New("backup not found")
+
+// ResolveHash returns the blob whose hash equals hash or, failing that, the
+// only blob whose hash starts with it, so users can type the short hashes
+// shown in listings.
+func (s *Service) ResolveHash(hash string) (string, error) {
+	hash = strings.ToLower(strings.TrimSpace(hash))
+	if hash == "" {
+		return "", errors.New("backup hash cannot be empty")
+	}
+	var matches []string
+	err := s.forEachBlob(func(id, path string, info os.FileInfo) error {
+		if strings.HasPrefix(id, hash) {
+			matches = append(matches, id)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	for _, id := range matches {
+		if id == hash {
+			return id, nil
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("%w: %s", ErrBackupNotFound, hash)
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("backup hash %q is ambiguous (%d matches)", hash, len(matches))
+	}
+}
+
+// Annotations returns the pins and notes of all annotated blobs, keyed by hash.
+func (s *Service) Annotations() (map[string]BlobMeta, error) {
+	idx, err := s.loadIndex()
+	if err != nil {
+		return nil, err
+	}
+	annotations := make(map[string]BlobMeta, len(idx.Blobs))
+	for id, meta := range idx.Blobs {
+		annotations[id] = meta
+	}
+	return annotations, nil
+}
+
+// SetPinned pins or unpins the blob with the given hash (or unique prefix)
+// and returns its full hash.
+func (s *Service) SetPinned(hash string, pinned bool) (string, error) {
+	return s.annotate(hash, func(meta *BlobMeta) {
+		meta.Pinned = pinned
+	})
+}
+
+// SetNote attaches note to the blob with the given hash (or unique prefix)
+// and returns its full hash. An empty note removes the existing one.
+func (s *Service) SetNote(hash, note string) (string, error) {
+	return s.annotate(hash, func(meta *BlobMeta) {
+		meta.Note = strings.TrimSpace(note)
+	})
+}
+
+func (s *Service) annotate(hash string, update func(*BlobMeta)) (string, error) {
+	id, err := s.ResolveHash(hash)
+	if err != nil {
+		return "", err
+	}
+	idx, err := s.loadIndex()
+	if err != nil {
+		return "", err
+	}
+	meta := idx.Blobs[id]
+	update(&meta)
+	if meta == (BlobMeta{}) {
+		delete(idx.Blobs, id)
+	} else {
+		if idx.Blobs == nil {
+			idx.Blobs = make(map[string]BlobMeta)
+		}
+		idx.Blobs[id] = meta
+	}
+	if err := s.saveIndex(idx); err != nil {
+		return "", err
+	}
+	s.mirrorIndex(idx)
+	s.logger.Info("backup annotated", "hash", id, "pinned", meta.Pinned, "note", meta.Note)
+	return id, nil
+}
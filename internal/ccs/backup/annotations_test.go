@@ -0,0 +1,113 @@
+package backup
+
+// Tests for backup pins and notes stored in meta/index.json.
+//
+// Focus: ResolveHash (prefix lookup), SetPinned/SetNote, pruning of pinned blobs.
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+func writeBlob(t *testing.T, svc *Service, fs afero.Fs, content string) string {
+	t.Helper()
+	if err := afero.WriteFile(fs, "/source.json", []byte(content), 0o644); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	hash, err := svc.backupFile("/source.json")
+	if err != nil {
+		t.Fatalf("backupFile failed: %v", err)
+	}
+	return hash
+}
+
+func TestResolveHash_PrefixAndErrors(t *testing.T) {
+	svc, fs := newTestService(t)
+	hash := writeBlob(t, svc, fs, `{"a":1}`)
+
+	resolved, err := svc.ResolveHash(hash[:8])
+	if err != nil {
+		t.Fatalf("ResolveHash failed: %v", err)
+	}
+	if resolved != hash {
+		t.Errorf("expected %s, got %s", hash, resolved)
+	}
+	if _, err := svc.ResolveHash("not-a-hash"); !errors.Is(err, ErrBackupNotFound) {
+		t.Errorf("expected ErrBackupNotFound, got %v", err)
+	}
+	if _, err := svc.ResolveHash(""); err == nil {
+		t.Error("expected error for empty hash")
+	}
+}
+
+func TestSetNote_AddAndClear(t *testing.T) {
+	svc, fs := newTestService(t)
+	hash := writeBlob(t, svc, fs, `{"a":1}`)
+
+	if _, err := svc.SetNote(hash, " known-good "); err != nil {
+		t.Fatalf("SetNote failed: %v", err)
+	}
+	annotations, err := svc.Annotations()
+	if err != nil {
+		t.Fatalf("Annotations failed: %v", err)
+	}
+	if annotations[hash].Note != "known-good" {
+		t.Errorf("expected trimmed note, got %+v", annotations[hash])
+	}
+
+	if _, err := svc.SetNote(hash, ""); err != nil {
+		t.Fatalf("SetNote clear failed: %v", err)
+	}
+	annotations, err = svc.Annotations()
+	if err != nil {
+		t.Fatalf("Annotations failed: %v", err)
+	}
+	if _, ok := annotations[hash]; ok {
+		t.Errorf("expected annotation to be removed, got %+v", annotations[hash])
+	}
+}
+
+func TestPruneBackups_SkipsPinnedBlobs(t *testing.T) {
+	svc, fs := newTestService(t)
+	now := time.Now()
+	svc.SetNow(func() time.Time { return now })
+	pinned := writeBlob(t, svc, fs, `{"keep":true}`)
+	noted := writeBlob(t, svc, fs, `{"keep":false}`)
+
+	if _, err := svc.SetPinned(pinned, true); err != nil {
+		t.Fatalf("SetPinned failed: %v", err)
+	}
+	if _, err := svc.SetNote(noted, "not pinned"); err != nil {
+		t.Fatalf("SetNote failed: %v", err)
+	}
+	old := now.Add(-48 * time.Hour)
+	for _, hash := range []string{pinned, noted} {
+		if err := fs.Chtimes("/backups/"+hash+".json", old, old); err != nil {
+			t.Fatalf("chtimes: %v", err)
+		}
+	}
+
+	deleted, err := svc.PruneBackups(24 * time.Hour)
+	if err != nil {
+		t.Fatalf("PruneBackups failed: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("expected 1 deleted blob, got %d", deleted)
+	}
+	if exists, _ := afero.Exists(fs, "/backups/"+pinned+".json"); !exists {
+		t.Error("pinned blob should survive pruning")
+	}
+	annotations, err := svc.Annotations()
+	if err != nil {
+		t.Fatalf("Annotations failed: %v", err)
+	}
+	if _, ok := annotations[noted]; ok {
+		t.Error("annotation of deleted blob should be forgotten")
+	}
+	if !annotations[pinned].Pinned {
+		t.Error("pin should be kept")
+	}
+}
@@ -0,0 +1,279 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// Archive layout: blobs are stored verbatim (keeping their compression and
+// encryption) under blobs/, followed by the index.
+const (
+	archiveBlobDir   = "blobs/"
+	archiveIndexName = "meta/index.json"
+
+	// maxArchiveEntrySize bounds every archive member so a malicious archive
+	// cannot exhaust memory. Settings files are a few kilobytes.
+	maxArchiveEntrySize = 16 << 20
+)
+
+// ImportResult reports what Import added to the store.
+type ImportResult struct {
+	// Imported counts blobs written to the store.
+	Imported int
+	// Skipped counts blobs already present locally.
+	Skipped int
+	// Snapshots counts snapshots added to the index.
+	Snapshots int
+}
+
+// Export writes every blob and the index to w as a gzip-compressed tar
+// archive. Blobs keep their on-disk encoding and modification time.
+//
+// Returns the number of blobs exported.
+func (s *Service) Export(w io.Writer) (int, error) {
+	zw := gzip.NewWriter(w)
+	tw := tar.NewWriter(zw)
+	count := 0
+	err := s.forEachBlob(func(id, blobPath string, info os.FileInfo) error {
+		stored, err := s.storage.ReadFile(blobPath)
+		if err != nil {
+			return fmt.Errorf("failed to read backup %s: %w", id, err)
+		}
+		if err := writeArchiveEntry(tw, archiveBlobDir+id+".json", stored, info.ModTime()); err != nil {
+			return err
+		}
+		count++
+		return nil
+	})
+	if err != nil {
+		return count, err
+	}
+
+	idx, err := s.loadIndex()
+	if err != nil {
+		return count, err
+	}
+	data, err := json.MarshalIndent(idx, "", "  ")
+	if err != nil {
+		return count, fmt.Errorf("failed to encode backup index: %w", err)
+	}
+	if err := writeArchiveEntry(tw, archiveIndexName, append(data, '\n'), s.now()); err != nil {
+		return count, err
+	}
+	if err := tw.Close(); err != nil {
+		return count, fmt.Errorf("failed to finish archive: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return count, fmt.Errorf("failed to finish archive: %w", err)
+	}
+	return count, nil
+}
+
+func writeArchiveEntry(tw *tar.Writer, name string, data []byte, mtime time.Time) error {
+	hdr := &tar.Header{
+		Name:     name,
+		Mode:     0o600,
+		Size:     int64(len(data)),
+		ModTime:  mtime,
+		Typeflag: tar.TypeReg,
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("failed to write archive entry %s: %w", name, err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("failed to write archive entry %s: %w", name, err)
+	}
+	return nil
+}
+
+type archivedBlob struct {
+	stored []byte
+	mtime  time.Time
+}
+
+// Import merges an archive produced by Export into the store.
+//
+// The whole archive is read and every blob is decrypted, decoded and checked
+// against the hash in its name before anything is written, so a corrupted or
+// tampered archive changes nothing. Blobs already present locally are
+// skipped. Snapshots are merged by content: a snapshot with the same time,
+// operation and files as a local one is not duplicated, and a different
+// snapshot whose ID is taken locally gets a fresh ID, so importing the same
+// archive again adds nothing. Local
+// pins and notes take precedence over imported ones.
+func (s *Service) Import(r io.Reader) (ImportResult, error) {
+	return s.ImportChecked(r, nil)
+}
+
+// ImportChecked is Import with a final check of the archive: check receives
+// the decoded content of every archived blob, keyed by hash, and the archived
+// snapshots after the archive has been verified and before anything is
+// written. An error from check aborts the import. A nil check accepts every
+// archive.
+func (s *Service) ImportChecked(r io.Reader, check func(contents map[string][]byte, snapshots []Snapshot) error) (ImportResult, error) {
+	var result ImportResult
+	blobs, imported, err := readArchive(r)
+	if err != nil {
+		return result, err
+	}
+	contents := make(map[string][]byte, len(blobs))
+	for id, blob := range blobs {
+		_, plain, ok := s.verifiedContent(id, blob.stored)
+		if !ok {
+			return result, fmt.Errorf("archive backup %s is unreadable or does not match its hash", id)
+		}
+		contents[id] = plain
+	}
+	if check != nil {
+		var snapshots []Snapshot
+		if imported != nil {
+			snapshots = imported.Snapshots
+		}
+		if err := check(contents, snapshots); err != nil {
+			return result, err
+		}
+	}
+
+	for id, blob := range blobs {
+		if stored, _, err := s.readBlob(id); err == nil && s.blobIntact(id, stored) {
+			result.Skipped++
+			continue
+		} else if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return result, fmt.Errorf("failed to inspect backup %s: %w", id, err)
+		}
+		if err := s.writeBlob(id, blob.stored, blob.mtime, s.keyring); err != nil {
+			return result, err
+		}
+		s.mirrorBlob(id, blob.stored, blob.mtime)
+		result.Imported++
+	}
+
+	if imported != nil {
+		idx, err := s.loadIndex()
+		if err != nil {
+			return result, err
+		}
+		result.Snapshots = mergeIndex(idx, imported)
+		if err := s.saveIndex(idx); err != nil {
+			return result, err
+		}
+	}
+	s.logger.Info("backup archive imported",
+		"imported", result.Imported,
+		"skipped", result.Skipped,
+		"snapshots", result.Snapshots)
+	return result, nil
+}
+
+func readArchive(r io.Reader) (map[string]archivedBlob, *index, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer zr.Close()
+
+	tr := tar.NewReader(zr)
+	blobs := make(map[string]archivedBlob)
+	var idx *index
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if hdr.Size > maxArchiveEntrySize {
+			return nil, nil, fmt.Errorf("archive entry %s is too large", hdr.Name)
+		}
+		data, err := io.ReadAll(io.LimitReader(tr, maxArchiveEntrySize))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read archive entry %s: %w", hdr.Name, err)
+		}
+
+		name := path.Clean(hdr.Name)
+		switch {
+		case name == archiveIndexName:
+			var parsed index
+			if err := json.Unmarshal(data, &parsed); err != nil {
+				return nil, nil, fmt.Errorf("failed to parse archive index: %w", err)
+			}
+			if parsed.Version > indexVersion {
+				return nil, nil, fmt.Errorf("archive index version %d is newer than supported version %d", parsed.Version, indexVersion)
+			}
+			idx = &parsed
+		case strings.HasPrefix(name, archiveBlobDir) && strings.HasSuffix(name, ".json"):
+			id := strings.TrimSuffix(strings.TrimPrefix(name, archiveBlobDir), ".json")
+			if !blobIDPattern.MatchString(id) {
+				return nil, nil, fmt.Errorf("archive entry %s is not a backup", hdr.Name)
+			}
+			blobs[id] = archivedBlob{stored: data, mtime: hdr.ModTime}
+		default:
+			return nil, nil, fmt.Errorf("unexpected archive entry %s", hdr.Name)
+		}
+	}
+	return blobs, idx, nil
+}
+
+// mergeIndex adds the snapshots and annotations of src to dst and returns the
+// number of snapshots added. A snapshot already in dst with the same time,
+// operation and files is not added again, whatever its ID; a different
+// snapshot whose ID is taken in dst gets a fresh ID.
+func mergeIndex(dst, src *index) int {
+	byID := make(map[string]Snapshot, len(dst.Snapshots))
+	byContent := make(map[string]bool, len(dst.Snapshots))
+	for _, snap := range dst.Snapshots {
+		byID[snap.ID] = snap
+		byContent[snapshotKey(snap)] = true
+	}
+	added := 0
+	for _, snap := range src.Snapshots {
+		key := snapshotKey(snap)
+		if byContent[key] {
+			continue
+		}
+		if _, ok := byID[snap.ID]; ok {
+			snap.ID = uniqueSnapshotID(dst, snap.Time)
+		}
+		dst.Snapshots = append(dst.Snapshots, snap)
+		byID[snap.ID] = snap
+		byContent[key] = true
+		added++
+	}
+	for id, meta := range src.Blobs {
+		if _, ok := dst.Blobs[id]; ok {
+			continue
+		}
+		if dst.Blobs == nil {
+			dst.Blobs = make(map[string]BlobMeta)
+		}
+		dst.Blobs[id] = meta
+	}
+	return added
+}
+
+// snapshotKey identifies a snapshot by its content, ignoring its ID.
+func snapshotKey(snap Snapshot) string {
+	var b strings.Builder
+	b.WriteString(snap.Time.UTC().Format(time.RFC3339Nano))
+	b.WriteByte(0)
+	b.WriteString(snap.Operation)
+	for _, f := range snap.Files {
+		b.WriteByte(0)
+		b.WriteString(f.Path)
+		b.WriteByte('=')
+		b.WriteString(f.Hash)
+	}
+	return b.String()
+}
@@ -0,0 +1,202 @@
+package backup
+
+// Tests for exporting and importing the backup store.
+//
+// Focus: Export/Import round trip, hash verification, snapshot merging and
+// import checks.
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+func TestExportImport_RoundTripMergesSnapshots(t *testing.T) {
+	src, srcFS := newTestService(t)
+	src.SetCompression(CompressionGzip)
+	mtime := time.Date(2024, 2, 3, 4, 5, 6, 0, time.UTC)
+	src.SetNow(func() time.Time { return mtime })
+	if err := afero.WriteFile(srcFS, "/settings.json", []byte(`{"a":1}`), 0o644); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	snap, err := src.Snapshot("use work", "/settings.json")
+	if err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+	hash := snap.Files[0].Hash
+	if _, err := src.SetPinned(hash, true); err != nil {
+		t.Fatalf("SetPinned failed: %v", err)
+	}
+
+	var archive bytes.Buffer
+	count, err := src.Export(&archive)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("expected 1 exported blob, got %d", count)
+	}
+
+	dst, dstFS := newTestService(t)
+	result, err := dst.Import(bytes.NewReader(archive.Bytes()))
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if result.Imported != 1 || result.Skipped != 0 || result.Snapshots != 1 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	info, err := dstFS.Stat("/backups/" + hash + ".json")
+	if err != nil {
+		t.Fatalf("stat imported blob: %v", err)
+	}
+	if !info.ModTime().Equal(mtime) {
+		t.Errorf("expected mtime %v, got %v", mtime, info.ModTime())
+	}
+	annotations, err := dst.Annotations()
+	if err != nil {
+		t.Fatalf("Annotations failed: %v", err)
+	}
+	if !annotations[hash].Pinned {
+		t.Error("expected pin to be imported")
+	}
+
+	// Importing again skips the blob and does not duplicate the snapshot
+	result, err = dst.Import(bytes.NewReader(archive.Bytes()))
+	if err != nil {
+		t.Fatalf("second Import failed: %v", err)
+	}
+	if result.Imported != 0 || result.Skipped != 1 || result.Snapshots != 0 {
+		t.Fatalf("unexpected second result: %+v", result)
+	}
+	snaps, err := dst.Snapshots()
+	if err != nil {
+		t.Fatalf("Snapshots failed: %v", err)
+	}
+	if len(snaps) != 1 || snaps[0].ID != snap.ID {
+		t.Fatalf("expected the single original snapshot, got %+v", snaps)
+	}
+}
+
+func TestImport_SameArchiveTwiceWithCollidingIDAddsNothing(t *testing.T) {
+	at := time.Date(2024, 2, 3, 4, 5, 6, 0, time.UTC)
+	src, srcFS := newTestService(t)
+	src.SetNow(func() time.Time { return at })
+	if err := afero.WriteFile(srcFS, "/settings.json", []byte(`{"a":1}`), 0o644); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	if _, err := src.Snapshot("use work", "/settings.json"); err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+	var archive bytes.Buffer
+	if _, err := src.Export(&archive); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	// The local snapshot taken at the same second has the same ID but
+	// different content, so the imported one is renamed on the first import
+	dst, dstFS := newTestService(t)
+	dst.SetNow(func() time.Time { return at })
+	if err := afero.WriteFile(dstFS, "/settings.json", []byte(`{"b":2}`), 0o644); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	if _, err := dst.Snapshot("use home", "/settings.json"); err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+	for i, want := range []int{1, 0} {
+		result, err := dst.Import(bytes.NewReader(archive.Bytes()))
+		if err != nil {
+			t.Fatalf("Import %d failed: %v", i+1, err)
+		}
+		if result.Snapshots != want {
+			t.Fatalf("import %d: expected %d added snapshot(s), got %+v", i+1, want, result)
+		}
+	}
+	snaps, err := dst.Snapshots()
+	if err != nil {
+		t.Fatalf("Snapshots failed: %v", err)
+	}
+	if len(snaps) != 2 {
+		t.Fatalf("expected the local and one imported snapshot, got %+v", snaps)
+	}
+}
+
+func TestImport_RejectsBlobNotMatchingHash(t *testing.T) {
+	svc, fs := newTestService(t)
+	name := strings.Repeat("a", 64)
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(zw)
+	if err := writeArchiveEntry(tw, archiveBlobDir+name+".json", []byte(`{"tampered":true}`), time.Now()); err != nil {
+		t.Fatalf("write entry: %v", err)
+	}
+	tw.Close()
+	zw.Close()
+
+	if _, err := svc.Import(&buf); err == nil {
+		t.Fatal("expected error for blob not matching its hash")
+	}
+	if exists, _ := afero.Exists(fs, "/backups/"+name+".json"); exists {
+		t.Error("tampered blob must not be written")
+	}
+}
+
+func TestMergeIndex_RenamesCollidingSnapshot(t *testing.T) {
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	dst := &index{Snapshots: []Snapshot{{ID: "20240101T000000Z", Time: at, Operation: "use home"}}}
+	src := &index{Snapshots: []Snapshot{{ID: "20240101T000000Z", Time: at, Operation: "use work"}}}
+
+	if added := mergeIndex(dst, src); added != 1 {
+		t.Fatalf("expected 1 added snapshot, got %d", added)
+	}
+	if dst.Snapshots[1].ID != "20240101T000000Z-2" {
+		t.Errorf("expected colliding snapshot to be renamed, got %s", dst.Snapshots[1].ID)
+	}
+}
+
+func TestImportChecked_RejectedArchiveWritesNothing(t *testing.T) {
+	src, srcFS := newTestService(t)
+	src.SetCompression(CompressionGzip)
+	if err := afero.WriteFile(srcFS, "/settings.json", []byte(`{"a":1}`), 0o644); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	if _, err := src.Snapshot("use work", "/settings.json"); err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+	var decoded []string
+	if err := src.Contents(func(hash string, content []byte) error {
+		decoded = append(decoded, string(content))
+		return nil
+	}); err != nil {
+		t.Fatalf("Contents failed: %v", err)
+	}
+	if len(decoded) != 1 || decoded[0] != `{"a":1}` {
+		t.Fatalf("Contents = %q", decoded)
+	}
+
+	var archive bytes.Buffer
+	if _, err := src.Export(&archive); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	dst, _ := newTestService(t)
+	var checked map[string][]byte
+	_, err := dst.ImportChecked(bytes.NewReader(archive.Bytes()), func(contents map[string][]byte, _ []Snapshot) error {
+		checked = contents
+		return errors.New("rejected")
+	})
+	if err == nil || err.Error() != "rejected" {
+		t.Fatalf("expected the check error, got %v", err)
+	}
+	if len(checked) != 1 {
+		t.Fatalf("check received %d blobs", len(checked))
+	}
+	if snaps, _ := dst.Snapshots(); len(snaps) != 0 {
+		t.Fatalf("rejected import must not add snapshots: %+v", snaps)
+	}
+}
@@ -0,0 +1,104 @@
+package backup
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"regexp"
+)
+
+// Compression selects how new backup blobs are encoded on disk.
+//
+// Blobs are always addressed by the SHA-256 of their uncompressed content, so
+// compressed and plain blobs of the same content share one name and the
+// encoding can change without invalidating any reference.
+type Compression string
+
+const (
+	// CompressionNone stores blobs as plain JSON (the legacy format).
+	CompressionNone Compression = "none"
+	// CompressionGzip stores blobs as gzip streams.
+	CompressionGzip Compression = "gzip"
+)
+
+// gzipMagic is the two-byte header that starts every gzip stream. Settings
+// files are JSON text and can never begin with these bytes, which makes
+// content sniffing a reliable way to tell encodings apart.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// metaDirName is the backup subdirectory holding store metadata rather than
+// blobs. Blob iteration only considers top-level files, so it never collides.
+const metaDirName = "meta"
+
+// blobIDPattern matches the names a blob may have: a SHA-256 hex digest or
+// the "empty" marker used for zero-length files.
+var blobIDPattern = regexp.MustCompile(`^([0-9a-f]{64}|empty)$`)
+
+// ParseCompression converts a configuration value into a Compression.
+// An empty value selects CompressionNone.
+func ParseCompression(value string) (Compression, error) {
+	switch Compression(value) {
+	case "", CompressionNone:
+		return CompressionNone, nil
+	case CompressionGzip:
+		return CompressionGzip, nil
+	default:
+		return "", fmt.Errorf("unsupported compression %q (supported: none, gzip)", value)
+	}
+}
+
+// encode converts plain content into the on-disk representation for c.
+func encode(plain []byte, c Compression) ([]byte, error) {
+	if c != CompressionGzip {
+		return plain, nil
+	}
+	var buf bytes.Buffer
+	zw, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gzip writer: %w", err)
+	}
+	if _, err := zw.Write(plain); err != nil {
+		return nil, fmt.Errorf("failed to compress backup: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress backup: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// decode returns the plain content of a stored blob, whichever encoding it uses.
+func decode(stored []byte) ([]byte, error) {
+	if !bytes.HasPrefix(stored, gzipMagic) {
+		return stored, nil
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(stored))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open compressed backup: %w", err)
+	}
+	defer zr.Close()
+	plain, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress backup: %w", err)
+	}
+	return plain, nil
+}
+
+// compressionOf reports the encoding of a stored blob.
+func compressionOf(stored []byte) Compression {
+	if bytes.HasPrefix(stored, gzipMagic) {
+		return CompressionGzip
+	}
+	return CompressionNone
+}
+
+// contentID returns the blob name for plain content.
+func contentID(plain []byte) string {
+	if len(plain) == 0 {
+		return "empty"
+	}
+	sum := sha256.Sum256(plain)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,53 @@
+package backup
+
+// Tests for blob encoding.
+//
+// Focus: ParseCompression (accepted values), encode/decode round trips.
+
+import "testing"
+
+func TestParseCompression(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Compression
+		wantErr bool
+	}{
+		{"", CompressionNone, false},
+		{"none", CompressionNone, false},
+		{"gzip", CompressionGzip, false},
+		{"zstd", "", true},
+		{"GZIP", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseCompression(tt.input)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseCompression(%q) expected error", tt.input)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParseCompression(%q) = %q, %v; want %q", tt.input, got, err, tt.want)
+		}
+	}
+}
+
+func TestEncodeDecode_RoundTrip(t *testing.T) {
+	inputs := [][]byte{{}, []byte("{}"), []byte(`{"model": "sonnet"}`)}
+	for _, c := range []Compression{CompressionNone, CompressionGzip} {
+		for _, plain := range inputs {
+			encoded, err := encode(plain, c)
+			if err != nil {
+				t.Fatalf("encode(%s): %v", c, err)
+			}
+			decoded, err := decode(encoded)
+			if err != nil {
+				t.Fatalf("decode(%s): %v", c, err)
+			}
+			if string(decoded) != string(plain) {
+				t.Errorf("%s round trip mismatch: got %q, want %q", c, decoded, plain)
+			}
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package backup
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// Encrypted blob layout:
+//
+//	magic (8) | mode (1) | salt (16) | nonce (12) | AES-256-GCM ciphertext
+//
+// The blob hash is bound as additional authenticated data, so an encrypted
+// blob cannot be renamed to stand in for different content.
+var encMagic = []byte("CCSENC1\x00")
+
+const (
+	keyModeFile       byte = 1
+	keyModePassphrase byte = 2
+
+	keySize   = 32
+	saltSize  = 16
+	nonceSize = 12
+
+	// scrypt parameters recommended for interactive use (2017).
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+var encHeaderSize = len(encMagic) + 1 + saltSize + nonceSize
+
+// ErrBackupEncrypted indicates that an encrypted blob was read without a key.
+var ErrBackupEncrypted = errors.New("backup is encrypted; configure backup.encryption to read it")
+
+// ErrUnreadableBackups indicates that Rekey stopped because some blobs could
+// not be opened with the current key.
+var ErrUnreadableBackups = errors.New("unreadable backups")
+
+// Keyring supplies the key used to encrypt new blobs and decrypt existing ones.
+//
+// A key-file keyring uses a random 256-bit key directly. A passphrase keyring
+// derives keys with scrypt; the salt is stored in every blob header, and
+// derived keys are cached per salt so a store written under one salt costs a
+// single derivation.
+type Keyring struct {
+	mode       byte
+	key        []byte
+	passphrase []byte
+	salt       []byte
+	derived    map[string][]byte
+	err        error
+}
+
+// NewKeyFileKeyring parses key file content: a base64 or hex encoded 256-bit key.
+func NewKeyFileKeyring(content []byte) (*Keyring, error) {
+	text := strings.TrimSpace(string(content))
+	key, err := base64.StdEncoding.DecodeString(text)
+	if err != nil || len(key) != keySize {
+		key, err = hex.DecodeString(text)
+	}
+	if err != nil || len(key) != keySize {
+		return nil, fmt.Errorf("key file must contain a base64 or hex encoded %d-byte key", keySize)
+	}
+	return &Keyring{mode: keyModeFile, key: key}, nil
+}
+
+// NewPassphraseKeyring creates a keyring that derives keys from passphrase.
+func NewPassphraseKeyring(passphrase string) (*Keyring, error) {
+	if passphrase == "" {
+		return nil, errors.New("backup passphrase cannot be empty")
+	}
+	return &Keyring{
+		mode:       keyModePassphrase,
+		passphrase: []byte(passphrase),
+		derived:    make(map[string][]byte),
+	}, nil
+}
+
+// UnavailableKeyring returns a keyring that fails every operation with err.
+// It lets configuration problems (such as an unset passphrase variable)
+// surface only when a backup actually needs to be written or read.
+func UnavailableKeyring(err error) *Keyring {
+	return &Keyring{err: err}
+}
+
+// GenerateKeyFile returns the content of a new random key file.
+func GenerateKeyFile() ([]byte, error) {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	return []byte(base64.StdEncoding.EncodeToString(key) + "\n"), nil
+}
+
+func (k *Keyring) keyFor(mode byte, salt []byte) ([]byte, error) {
+	if k.err != nil {
+		return nil, k.err
+	}
+	if mode != k.mode {
+		return nil, errors.New("backup was encrypted with a different kind of key")
+	}
+	if mode == keyModeFile {
+		return k.key, nil
+	}
+	if key, ok := k.derived[string(salt)]; ok {
+		return key, nil
+	}
+	key, err := scrypt.Key(k.passphrase, salt, scryptN, scryptR, scryptP, keySize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+	k.derived[string(salt)] = key
+	return key, nil
+}
+
+// seal encrypts data for the blob named id.
+func (k *Keyring) seal(id string, data []byte) ([]byte, error) {
+	if k.err != nil {
+		return nil, k.err
+	}
+	salt := make([]byte, saltSize)
+	if k.mode == keyModePassphrase {
+		if k.salt == nil {
+			return nil, errors.New("passphrase keyring has no salt")
+		}
+		copy(salt, k.salt)
+	}
+	key, err := k.keyFor(k.mode, salt)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, nonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	out := make([]byte, 0, encHeaderSize+len(data)+aead.Overhead())
+	out = append(out, encMagic...)
+	out = append(out, k.mode)
+	out = append(out, salt...)
+	out = append(out, nonce...)
+	return aead.Seal(out, nonce, data, []byte(id)), nil
+}
+
+// open decrypts an encrypted blob named id.
+func (k *Keyring) open(id string, stored []byte) ([]byte, error) {
+	if k == nil {
+		return nil, ErrBackupEncrypted
+	}
+	if len(stored) < encHeaderSize {
+		return nil, errors.New("encrypted backup is truncated")
+	}
+	offset := len(encMagic)
+	mode := stored[offset]
+	salt := stored[offset+1 : offset+1+saltSize]
+	nonce := stored[offset+1+saltSize : encHeaderSize]
+
+	key, err := k.keyFor(mode, salt)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := aead.Open(nil, nonce, stored[encHeaderSize:], []byte(id))
+	if err != nil {
+		return nil, errors.New("failed to decrypt backup: wrong key or corrupted data")
+	}
+	return plain, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+func isEncrypted(stored []byte) bool {
+	return bytes.HasPrefix(stored, encMagic)
+}
+
+func newSalt() ([]byte, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	return salt, nil
+}
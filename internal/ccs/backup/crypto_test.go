@@ -0,0 +1,254 @@
+package backup
+
+// Tests for backup encryption.
+//
+// Focus: key file parsing, seal/open round trips, authentication failures,
+// Rekey rotation and decryption.
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+func newTestKeyFileKeyring(t *testing.T) *Keyring {
+	t.Helper()
+	content, err := GenerateKeyFile()
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	k, err := NewKeyFileKeyring(content)
+	if err != nil {
+		t.Fatalf("parse key: %v", err)
+	}
+	return k
+}
+
+func TestNewKeyFileKeyring_RejectsInvalidKeys(t *testing.T) {
+	inputs := []string{"", "not a key", "c2hvcnQ=", strings.Repeat("ab", 16)}
+	for _, input := range inputs {
+		if _, err := NewKeyFileKeyring([]byte(input)); err == nil {
+			t.Errorf("expected error for key %q", input)
+		}
+	}
+	if _, err := NewKeyFileKeyring([]byte(strings.Repeat("ab", 32) + "\n")); err != nil {
+		t.Errorf("hex key should be accepted: %v", err)
+	}
+}
+
+func TestBackupFile_EncryptedRoundTrip(t *testing.T) {
+	svc, fs := newTestService(t)
+	svc.SetCompression(CompressionGzip)
+	svc.SetKeyring(newTestKeyFileKeyring(t))
+
+	original := []byte(`{"env": {"ANTHROPIC_API_KEY": "sk-secret"}}`)
+	path := "/test/file.json"
+	if err := afero.WriteFile(fs, path, original, 0o644); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	if err := svc.BackupFile(path); err != nil {
+		t.Fatalf("BackupFile failed: %v", err)
+	}
+
+	hash := contentID(original)
+	stored, err := afero.ReadFile(fs, filepath.Join(svc.BackupDir(), hash+".json"))
+	if err != nil {
+		t.Fatalf("read blob: %v", err)
+	}
+	if !isEncrypted(stored) || strings.Contains(string(stored), "sk-secret") {
+		t.Fatal("blob should be encrypted at rest")
+	}
+
+	plain, err := svc.ReadBackup(hash)
+	if err != nil {
+		t.Fatalf("ReadBackup failed: %v", err)
+	}
+	if string(plain) != string(original) {
+		t.Errorf("content mismatch: got %q", plain)
+	}
+}
+
+func TestReadBackup_EncryptedWithoutKey(t *testing.T) {
+	svc, fs := newTestService(t)
+	svc.SetKeyring(newTestKeyFileKeyring(t))
+
+	path := "/test/file.json"
+	if err := afero.WriteFile(fs, path, []byte("{}"), 0o644); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	if err := svc.BackupFile(path); err != nil {
+		t.Fatalf("BackupFile failed: %v", err)
+	}
+
+	svc.SetKeyring(nil)
+	if _, err := svc.ReadBackup(contentID([]byte("{}"))); !errors.Is(err, ErrBackupEncrypted) {
+		t.Fatalf("expected ErrBackupEncrypted, got %v", err)
+	}
+
+	svc.SetKeyring(newTestKeyFileKeyring(t))
+	if _, err := svc.ReadBackup(contentID([]byte("{}"))); err == nil {
+		t.Fatal("expected error when decrypting with the wrong key")
+	}
+}
+
+func TestSeal_BindsBlobName(t *testing.T) {
+	k := newTestKeyFileKeyring(t)
+	sealed, err := k.seal("aaa", []byte("data"))
+	if err != nil {
+		t.Fatalf("seal: %v", err)
+	}
+	if _, err := k.open("bbb", sealed); err == nil {
+		t.Fatal("ciphertext moved to another blob name must not decrypt")
+	}
+	if _, err := k.open("aaa", sealed); err != nil {
+		t.Fatalf("open under original name: %v", err)
+	}
+}
+
+func TestRekey_RotatesPassphraseAndDecrypts(t *testing.T) {
+	svc, fs := newTestService(t)
+	oldKey, err := NewPassphraseKeyring("old passphrase")
+	if err != nil {
+		t.Fatalf("old keyring: %v", err)
+	}
+	svc.SetKeyring(oldKey)
+
+	contents := []string{`{"a": 1}`, `{"b": 2}`}
+	for i, c := range contents {
+		path := filepath.Join("/test", string(rune('a'+i))+".json")
+		if err := afero.WriteFile(fs, path, []byte(c), 0o644); err != nil {
+			t.Fatalf("setup: %v", err)
+		}
+		if err := svc.BackupFile(path); err != nil {
+			t.Fatalf("BackupFile: %v", err)
+		}
+	}
+
+	newKey, err := NewPassphraseKeyring("new passphrase")
+	if err != nil {
+		t.Fatalf("new keyring: %v", err)
+	}
+	rewritten, unreadable, err := svc.Rekey(newKey)
+	if err != nil {
+		t.Fatalf("Rekey failed: %v", err)
+	}
+	if rewritten != len(contents) || unreadable != 0 {
+		t.Errorf("expected %d rewritten and none unreadable, got %d and %d", len(contents), rewritten, unreadable)
+	}
+
+	// Old passphrase no longer opens the store; the new one does
+	svc.SetKeyring(oldKey)
+	if _, err := svc.ReadBackup(contentID([]byte(contents[0]))); err == nil {
+		t.Fatal("old passphrase should not decrypt rekeyed blobs")
+	}
+	fresh, err := NewPassphraseKeyring("new passphrase")
+	if err != nil {
+		t.Fatalf("fresh keyring: %v", err)
+	}
+	svc.SetKeyring(fresh)
+	for _, c := range contents {
+		plain, err := svc.ReadBackup(contentID([]byte(c)))
+		if err != nil {
+			t.Fatalf("ReadBackup after rekey: %v", err)
+		}
+		if string(plain) != c {
+			t.Errorf("content mismatch after rekey: got %q", plain)
+		}
+	}
+
+	// Decrypting leaves plain blobs readable without any key
+	if _, _, err := svc.Rekey(nil); err != nil {
+		t.Fatalf("Rekey(nil) failed: %v", err)
+	}
+	stored, err := afero.ReadFile(fs, filepath.Join(svc.BackupDir(), contentID([]byte(contents[1]))+".json"))
+	if err != nil {
+		t.Fatalf("read blob: %v", err)
+	}
+	if string(stored) != contents[1] {
+		t.Errorf("expected plain blob after decryption, got %q", stored)
+	}
+}
+
+func TestRekey_RewritesNothingWhenABlobCannotBeOpened(t *testing.T) {
+	svc, fs := newTestService(t)
+	current, err := NewPassphraseKeyring("current")
+	if err != nil {
+		t.Fatalf("current keyring: %v", err)
+	}
+	older, err := NewPassphraseKeyring("older")
+	if err != nil {
+		t.Fatalf("older keyring: %v", err)
+	}
+
+	contents := []string{`{"a": 1}`, `{"b": 2}`}
+	for i, k := range []*Keyring{current, older} {
+		svc.SetKeyring(k)
+		path := filepath.Join("/test", string(rune('a'+i))+".json")
+		if err := afero.WriteFile(fs, path, []byte(contents[i]), 0o644); err != nil {
+			t.Fatalf("setup: %v", err)
+		}
+		if err := svc.BackupFile(path); err != nil {
+			t.Fatalf("BackupFile: %v", err)
+		}
+	}
+	readable := filepath.Join(svc.BackupDir(), contentID([]byte(contents[0]))+".json")
+	before, err := afero.ReadFile(fs, readable)
+	if err != nil {
+		t.Fatalf("read blob: %v", err)
+	}
+
+	svc.SetKeyring(current)
+	newKey, err := NewPassphraseKeyring("new")
+	if err != nil {
+		t.Fatalf("new keyring: %v", err)
+	}
+	rewritten, unreadable, err := svc.Rekey(newKey)
+	if !errors.Is(err, ErrUnreadableBackups) {
+		t.Fatalf("expected ErrUnreadableBackups, got %v", err)
+	}
+	if rewritten != 0 || unreadable != 1 {
+		t.Fatalf("expected 0 rewritten and 1 unreadable, got %d and %d", rewritten, unreadable)
+	}
+
+	// Nothing was rewritten: the readable blob is byte-identical and still
+	// opens with the current key, which the service keeps using
+	after, err := afero.ReadFile(fs, readable)
+	if err != nil {
+		t.Fatalf("read blob: %v", err)
+	}
+	if !bytes.Equal(before, after) {
+		t.Error("a blob was rewritten although Rekey failed")
+	}
+	if _, err := svc.ReadBackup(contentID([]byte(contents[0]))); err != nil {
+		t.Errorf("blob should still open with the current key: %v", err)
+	}
+	svc.SetKeyring(older)
+	if _, err := svc.ReadBackup(contentID([]byte(contents[1]))); err != nil {
+		t.Errorf("unreadable blob should still open with its own key: %v", err)
+	}
+}
+
+func TestUnavailableKeyring_FailsOnUse(t *testing.T) {
+	svc, fs := newTestService(t)
+	svc.SetKeyring(UnavailableKeyring(errors.New("passphrase not set")))
+
+	path := "/test/file.json"
+	if err := afero.WriteFile(fs, path, []byte("{}"), 0o644); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	err := svc.BackupFile(path)
+	if err == nil || !strings.Contains(err.Error(), "passphrase not set") {
+		t.Fatalf("expected keyring error, got %v", err)
+	}
+	entries, err := afero.ReadDir(fs, svc.BackupDir())
+	if err != nil {
+		t.Fatalf("read backup dir: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("no plaintext backup may be written when encryption is unavailable, got %d entries", len(entries))
+	}
+}
@@ -0,0 +1,349 @@
+package backup
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const indexVersion = 1
+
+// Snapshot groups the backups taken by one ccs operation, so every file the
+// operation overwrote can be restored consistently as of one point in time.
+type Snapshot struct {
+	ID        string         `json:"id"`
+	Time      time.Time      `json:"time"`
+	Operation string         `json:"operation"`
+	Files     []SnapshotFile `json:"files"`
+}
+
+// SnapshotFile records the content a file had when a snapshot was taken.
+type SnapshotFile struct {
+	// Path is slash-separated and relative to the directory containing the
+	// backup directory (~/.claude), so snapshots survive a moved home directory.
+	Path string `json:"path"`
+	// Hash names the backup blob. It is empty when the file did not exist.
+	Hash string `json:"hash,omitempty"`
+}
+
+// BlobMeta holds user annotations for one backup blob.
+type BlobMeta struct {
+	// Pinned blobs are never deleted by pruning.
+	Pinned bool `json:"pinned,omitempty"`
+	// Note is a free-form description, such as "known-good config".
+	Note string `json:"note,omitempty"`
+}
+
+// index is the on-disk catalogue of snapshots and blob annotations.
+type index struct {
+	Version   int                 `json:"version"`
+	Snapshots []Snapshot          `json:"snapshots"`
+	Blobs     map[string]BlobMeta `json:"blobs,omitempty"`
+	// Deleted lists blobs deleted locally whose mirror copy could not be
+	// removed. SyncMirror removes those copies instead of restoring them.
+	Deleted []string `json:"deleted,omitempty"`
+}
+
+// ErrSnapshotNotFound indicates that no snapshot matches the requested ID.
+var ErrSnapshotNotFound = errors.New("snapshot not found")
+
+func (s *Service) indexPath() string {
+	return filepath.Join(s.backupDir, metaDirName, "index.json")
+}
+
+// rootDir is the directory snapshot paths are relative to.
+func (s *Service) rootDir() string {
+	return filepath.Dir(s.backupDir)
+}
+
+func (s *Service) loadIndex() (*index, error) {
+	data, err := s.storage.ReadFile(s.indexPath())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return &index{Version: indexVersion}, nil
+		}
+		return nil, fmt.Errorf("failed to read backup index: %w", err)
+	}
+	var idx index
+	if err := json.Unmarshal(data, &idx); err != nil {
+		return nil, fmt.Errorf("failed to parse backup index: %w", err)
+	}
+	if idx.Version > indexVersion {
+		return nil, fmt.Errorf("backup index version %d is newer than supported version %d", idx.Version, indexVersion)
+	}
+	idx.Version = indexVersion
+	return &idx, nil
+}
+
+func (s *Service) saveIndex(idx *index) error {
+	data, err := json.MarshalIndent(idx, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode backup index: %w", err)
+	}
+	if err := s.storage.WriteFileAtomic(s.indexPath(), append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write backup index: %w", err)
+	}
+	return nil
+}
+
+// Snapshot backs up every file in paths and records them as one snapshot
+// labelled with operation.
+//
+// Missing files are recorded with an empty hash so a restore can bring back
+// their absence too. When none of the files exist, nothing is recorded and
+// the returned snapshot has no ID. Every path must be inside the directory
+// that contains the backup directory.
+func (s *Service) Snapshot(operation string, paths ...string) (Snapshot, error) {
+	now := s.now()
+	snap := Snapshot{Time: now.UTC(), Operation: operation}
+	captured := false
+	for _, path := range paths {
+		rel, err := s.relPath(path)
+		if err != nil {
+			return Snapshot{}, err
+		}
+		hash, err := s.backupFile(path)
+		if err != nil {
+			return Snapshot{}, err
+		}
+		if hash != "" {
+			captured = true
+		}
+		snap.Files = append(snap.Files, SnapshotFile{Path: rel, Hash: hash})
+	}
+	if !captured {
+		return Snapshot{}, nil
+	}
+
+	idx, err := s.loadIndex()
+	if err != nil {
+		return Snapshot{}, err
+	}
+	snap.ID = uniqueSnapshotID(idx, now)
+	idx.Snapshots = append(idx.Snapshots, snap)
+	if err := s.saveIndex(idx); err != nil {
+		return Snapshot{}, err
+	}
+	s.mirrorIndex(idx)
+	s.logger.Info("snapshot recorded",
+		"id", snap.ID,
+		"operation", operation,
+		"files", len(snap.Files))
+	return snap, nil
+}
+
+// Snapshots returns all recorded snapshots, newest first.
+func (s *Service) Snapshots() ([]Snapshot, error) {
+	idx, err := s.loadIndex()
+	if err != nil {
+		return nil, err
+	}
+	// Walk the index backwards so snapshots sharing a timestamp keep
+	// newest-first order after the stable sort.
+	snaps := make([]Snapshot, 0, len(idx.Snapshots))
+	for i := len(idx.Snapshots) - 1; i >= 0; i-- {
+		snaps = append(snaps, idx.Snapshots[i])
+	}
+	sort.SliceStable(snaps, func(i, j int) bool {
+		return snaps[i].Time.After(snaps[j].Time)
+	})
+	return snaps, nil
+}
+
+// FindSnapshot returns the snapshot whose ID equals id or, failing that, the
+// only snapshot whose ID starts with id.
+func (s *Service) FindSnapshot(id string) (Snapshot, error) {
+	idx, err := s.loadIndex()
+	if err != nil {
+		return Snapshot{}, err
+	}
+	var matches []Snapshot
+	for _, snap := range idx.Snapshots {
+		if snap.ID == id {
+			return snap, nil
+		}
+		if id != "" && strings.HasPrefix(snap.ID, id) {
+			matches = append(matches, snap)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return Snapshot{}, fmt.Errorf("%w: %s", ErrSnapshotNotFound, id)
+	case 1:
+		return matches[0], nil
+	default:
+		return Snapshot{}, fmt.Errorf("snapshot ID %q is ambiguous (%d matches)", id, len(matches))
+	}
+}
+
+// RestoreSnapshot writes every file of snap back to the content it had when
+// the snapshot was taken, removing files that did not exist then.
+//
+// All blobs are read and decoded before any file is touched, so a missing or
+// unreadable blob aborts the restore without partial changes. Each file is
+// then replaced atomically.
+func (s *Service) RestoreSnapshot(snap Snapshot) error {
+	contents := make([][]byte, len(snap.Files))
+	targets := make([]string, len(snap.Files))
+	for i, f := range snap.Files {
+		target, err := s.absPath(f.Path)
+		if err != nil {
+			return err
+		}
+		targets[i] = target
+		if f.Hash == "" {
+			continue
+		}
+		data, err := s.ReadBackup(f.Hash)
+		if err != nil {
+			return fmt.Errorf("cannot restore %s: %w", f.Path, err)
+		}
+		contents[i] = data
+	}
+
+	for i, f := range snap.Files {
+		if f.Hash == "" {
+			if err := s.storage.ValidatePathSafety(targets[i]); err != nil {
+				return err
+			}
+			if err := s.storage.Remove(targets[i]); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("failed to remove %s: %w", f.Path, err)
+			}
+			continue
+		}
+		if err := s.storage.WriteFileAtomic(targets[i], contents[i]); err != nil {
+			return fmt.Errorf("failed to restore %s: %w", f.Path, err)
+		}
+	}
+	s.logger.Info("snapshot restored", "id", snap.ID, "files", len(snap.Files))
+	return nil
+}
+
+// FileVersion is the content one file had when a snapshot was taken.
+type FileVersion struct {
+	Snapshot Snapshot
+	// Hash names the backup blob. It is empty when the file did not exist.
+	Hash string
+}
+
+// History returns the recorded versions of the file at path, oldest first.
+//
+// Snapshots are taken before an operation overwrites a file, so each version
+// is the content the file had just before its snapshot's operation ran.
+func (s *Service) History(path string) ([]FileVersion, error) {
+	rel, err := s.relPath(path)
+	if err != nil {
+		return nil, err
+	}
+	snaps, err := s.Snapshots()
+	if err != nil {
+		return nil, err
+	}
+	var versions []FileVersion
+	for i := len(snaps) - 1; i >= 0; i-- {
+		for _, f := range snaps[i].Files {
+			if f.Path == rel {
+				versions = append(versions, FileVersion{Snapshot: snaps[i], Hash: f.Hash})
+				break
+			}
+		}
+	}
+	return versions, nil
+}
+
+// SnapshotPath returns the absolute path of a snapshot file.
+func (s *Service) SnapshotPath(f SnapshotFile) (string, error) {
+	return s.absPath(f.Path)
+}
+
+// forgetBlobs removes the annotations of deleted blobs and the snapshots that
+// reference any of them; a snapshot missing part of its content can no longer
+// be restored consistently. With a mirror configured, the mirror copies are
+// removed too, and the index is mirrored so the forgotten snapshots do not
+// come back from it. A mirror copy that cannot be removed is recorded in the
+// index for SyncMirror.
+func (s *Service) forgetBlobs(removed map[string]bool) error {
+	if len(removed) == 0 {
+		return nil
+	}
+	idx, err := s.loadIndex()
+	if err != nil {
+		return err
+	}
+	changed := false
+	if s.mirrorDir != "" {
+		for id := range removed {
+			if !s.removeMirrorCopy(id) {
+				idx.Deleted = appendUnique(idx.Deleted, id)
+				changed = true
+			}
+		}
+	}
+	kept := idx.Snapshots[:0]
+	for _, snap := range idx.Snapshots {
+		if !snapshotReferences(snap, removed) {
+			kept = append(kept, snap)
+		}
+	}
+	changed = changed || len(kept) != len(idx.Snapshots)
+	idx.Snapshots = kept
+	for id := range removed {
+		if _, ok := idx.Blobs[id]; ok {
+			delete(idx.Blobs, id)
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	if err := s.saveIndex(idx); err != nil {
+		return err
+	}
+	s.mirrorIndex(idx)
+	return nil
+}
+
+func snapshotReferences(snap Snapshot, hashes map[string]bool) bool {
+	for _, f := range snap.Files {
+		if f.Hash != "" && hashes[f.Hash] {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Service) relPath(path string) (string, error) {
+	rel, err := filepath.Rel(s.rootDir(), filepath.Clean(path))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("cannot snapshot %s: outside %s", path, s.rootDir())
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// absPath resolves a snapshot path, refusing anything that would escape the
+// root directory so a tampered index cannot redirect a restore.
+func (s *Service) absPath(rel string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(rel))
+	if rel == "" || filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid snapshot path %q", rel)
+	}
+	return filepath.Join(s.rootDir(), clean), nil
+}
+
+func uniqueSnapshotID(idx *index, now time.Time) string {
+	base := now.UTC().Format("20060102T150405Z")
+	taken := make(map[string]bool, len(idx.Snapshots))
+	for _, snap := range idx.Snapshots {
+		taken[snap.ID] = true
+	}
+	id := base
+	for n := 2; taken[id]; n++ {
+		id = fmt.Sprintf("%s-%d", base, n)
+	}
+	return id
+}
@@ -0,0 +1,220 @@
+package backup
+
+// Tests for operation snapshots recorded in meta/index.json.
+//
+// Focus: Snapshot (grouping, absent files, unique IDs), FindSnapshot (prefix
+// lookup), RestoreSnapshot (all-or-nothing restore), interaction with pruning.
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+func TestSnapshot_RestoreBringsBackContentAndAbsence(t *testing.T) {
+	svc, fs := newTestService(t)
+	if err := afero.WriteFile(fs, "/settings.json", []byte(`{"v":1}`), 0o644); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+
+	snap, err := svc.Snapshot("use work", "/settings.json", "/settings.json.active")
+	if err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+	if snap.ID == "" || len(snap.Files) != 2 {
+		t.Fatalf("unexpected snapshot: %+v", snap)
+	}
+	if snap.Files[0].Path != "settings.json" || snap.Files[1].Hash != "" {
+		t.Fatalf("unexpected snapshot files: %+v", snap.Files)
+	}
+
+	// Mutate both files, then restore
+	if err := afero.WriteFile(fs, "/settings.json", []byte(`{"v":2}`), 0o644); err != nil {
+		t.Fatalf("mutate: %v", err)
+	}
+	if err := afero.WriteFile(fs, "/settings.json.active", []byte("work"), 0o644); err != nil {
+		t.Fatalf("mutate: %v", err)
+	}
+	if err := svc.RestoreSnapshot(snap); err != nil {
+		t.Fatalf("RestoreSnapshot failed: %v", err)
+	}
+
+	content, err := afero.ReadFile(fs, "/settings.json")
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if string(content) != `{"v":1}` {
+		t.Errorf("expected original content, got %s", content)
+	}
+	if exists, _ := afero.Exists(fs, "/settings.json.active"); exists {
+		t.Error("file absent at snapshot time should be removed")
+	}
+}
+
+func TestSnapshot_SkipsWhenNothingExists(t *testing.T) {
+	svc, _ := newTestService(t)
+
+	snap, err := svc.Snapshot("use work", "/settings.json")
+	if err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+	if snap.ID != "" {
+		t.Errorf("expected no snapshot, got %+v", snap)
+	}
+	snaps, err := svc.Snapshots()
+	if err != nil {
+		t.Fatalf("Snapshots failed: %v", err)
+	}
+	if len(snaps) != 0 {
+		t.Errorf("expected empty index, got %d snapshots", len(snaps))
+	}
+}
+
+func TestSnapshot_RejectsPathOutsideRoot(t *testing.T) {
+	svc, _ := newTestService(t)
+	svc.backupDir = "/home/.claude/backups"
+	if _, err := svc.Snapshot("use work", "/etc/passwd"); err == nil {
+		t.Fatal("expected error for path outside root")
+	}
+	if _, err := svc.absPath("../etc/passwd"); err == nil {
+		t.Fatal("expected error for escaping snapshot path")
+	}
+}
+
+func TestFindSnapshot_PrefixAndAmbiguity(t *testing.T) {
+	svc, fs := newTestService(t)
+	svc.SetNow(func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) })
+	if err := afero.WriteFile(fs, "/settings.json", []byte("{}"), 0o644); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+
+	first, err := svc.Snapshot("first", "/settings.json")
+	if err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+	second, err := svc.Snapshot("second", "/settings.json")
+	if err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+	if first.ID != "20240102T030405Z" || second.ID != "20240102T030405Z-2" {
+		t.Fatalf("unexpected IDs %q, %q", first.ID, second.ID)
+	}
+
+	found, err := svc.FindSnapshot(first.ID)
+	if err != nil || found.Operation != "first" {
+		t.Fatalf("exact lookup: %+v, %v", found, err)
+	}
+	if _, err := svc.FindSnapshot("20240102"); err == nil {
+		t.Error("expected ambiguous prefix error")
+	}
+	if _, err := svc.FindSnapshot("1999"); !errors.Is(err, ErrSnapshotNotFound) {
+		t.Errorf("expected ErrSnapshotNotFound, got %v", err)
+	}
+
+	snaps, err := svc.Snapshots()
+	if err != nil {
+		t.Fatalf("Snapshots failed: %v", err)
+	}
+	if snaps[0].ID != second.ID {
+		t.Errorf("expected newest snapshot first, got %s", snaps[0].ID)
+	}
+}
+
+func TestHistory_ReturnsVersionsOfOneFileOldestFirst(t *testing.T) {
+	svc, fs := newTestService(t)
+	if _, err := svc.Snapshot("before", "/settings.json"); err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+	for i, content := range []string{`{"v":1}`, `{"v":2}`} {
+		if err := afero.WriteFile(fs, "/settings.json", []byte(content), 0o644); err != nil {
+			t.Fatalf("setup: %v", err)
+		}
+		if _, err := svc.Snapshot(fmt.Sprintf("op %d", i), "/settings.json", "/other.json"); err != nil {
+			t.Fatalf("Snapshot failed: %v", err)
+		}
+	}
+	if err := afero.WriteFile(fs, "/other.json", []byte("{}"), 0o644); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	if _, err := svc.Snapshot("unrelated", "/other.json"); err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+
+	versions, err := svc.History("/settings.json")
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	if len(versions) != 2 {
+		t.Fatalf("expected 2 versions, got %+v", versions)
+	}
+	if versions[0].Snapshot.Operation != "op 0" || versions[1].Snapshot.Operation != "op 1" {
+		t.Fatalf("unexpected order: %+v", versions)
+	}
+	data, err := svc.ReadBackup(versions[1].Hash)
+	if err != nil || string(data) != `{"v":2}` {
+		t.Fatalf("unexpected content %q, %v", data, err)
+	}
+}
+
+func TestRestoreSnapshot_MissingBlobLeavesFilesUntouched(t *testing.T) {
+	svc, fs := newTestService(t)
+	if err := afero.WriteFile(fs, "/a.json", []byte(`{"a":1}`), 0o644); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	if err := afero.WriteFile(fs, "/b.json", []byte(`{"b":1}`), 0o644); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	snap, err := svc.Snapshot("save", "/a.json", "/b.json")
+	if err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+	if err := afero.WriteFile(fs, "/a.json", []byte(`{"a":2}`), 0o644); err != nil {
+		t.Fatalf("mutate: %v", err)
+	}
+	if err := fs.Remove("/backups/" + snap.Files[1].Hash + ".json"); err != nil {
+		t.Fatalf("remove blob: %v", err)
+	}
+
+	if err := svc.RestoreSnapshot(snap); err == nil {
+		t.Fatal("expected error for missing blob")
+	}
+	content, _ := afero.ReadFile(fs, "/a.json")
+	if string(content) != `{"a":2}` {
+		t.Errorf("restore should be all-or-nothing, got %s", content)
+	}
+}
+
+func TestPruneBackups_DropsSnapshotsWithDeletedBlobs(t *testing.T) {
+	svc, fs := newTestService(t)
+	now := time.Now()
+	svc.SetNow(func() time.Time { return now })
+	if err := afero.WriteFile(fs, "/settings.json", []byte(`{"old":true}`), 0o644); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	snap, err := svc.Snapshot("use work", "/settings.json")
+	if err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+	old := now.Add(-48 * time.Hour)
+	if err := fs.Chtimes("/backups/"+snap.Files[0].Hash+".json", old, old); err != nil {
+		t.Fatalf("chtimes: %v", err)
+	}
+
+	deleted, err := svc.PruneBackups(24 * time.Hour)
+	if err != nil {
+		t.Fatalf("PruneBackups failed: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("expected 1 deleted blob, got %d", deleted)
+	}
+	snaps, err := svc.Snapshots()
+	if err != nil {
+		t.Fatalf("Snapshots failed: %v", err)
+	}
+	if len(snaps) != 0 {
+		t.Errorf("expected snapshot to be dropped, got %+v", snaps)
+	}
+}
@@ -0,0 +1,149 @@
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// Layout selects where blobs are placed inside the backup directory.
+//
+// Both layouts are always readable, so a store stays usable while
+// MigrateLayout is running or after it was interrupted.
+type Layout string
+
+const (
+	// LayoutFlat stores every blob directly in the backup directory (the
+	// legacy layout).
+	LayoutFlat Layout = "flat"
+	// LayoutSharded stores blobs in subdirectories named after the first two
+	// hex digits of their hash (ab/cdef....json), keeping directories small
+	// for stores with many thousands of blobs.
+	LayoutSharded Layout = "sharded"
+)
+
+// shardDirPattern matches shard directory names. The metadata directory can
+// never match.
+var shardDirPattern = regexp.MustCompile(`^[0-9a-f]{2}$`)
+
+// ParseLayout converts a configuration value into a Layout.
+// An empty value selects LayoutFlat.
+func ParseLayout(value string) (Layout, error) {
+	switch Layout(value) {
+	case "", LayoutFlat:
+		return LayoutFlat, nil
+	case LayoutSharded:
+		return LayoutSharded, nil
+	default:
+		return "", fmt.Errorf("unsupported backup layout %q (supported: flat, sharded)", value)
+	}
+}
+
+// SetLayout selects the layout used for newly written blobs.
+// Existing blobs stay where they are until MigrateLayout moves them.
+func (s *Service) SetLayout(l Layout) {
+	s.layout = l
+}
+
+// layoutPath returns the path of the blob named id in layout l. The "empty"
+// blob is not a hash and always stays in the top-level directory.
+func (s *Service) layoutPath(id string, l Layout) string {
+	if l == LayoutSharded && len(id) == 64 {
+		return filepath.Join(s.backupDir, id[:2], id[2:]+".json")
+	}
+	return filepath.Join(s.backupDir, id+".json")
+}
+
+// blobPath returns where the blob named id is written.
+func (s *Service) blobPath(id string) string {
+	return s.layoutPath(id, s.layout)
+}
+
+// otherBlobPath returns where the blob named id would live in the layout not
+// currently selected, or "" when both layouts use the same path.
+func (s *Service) otherBlobPath(id string) string {
+	other := LayoutSharded
+	if s.layout == LayoutSharded {
+		other = LayoutFlat
+	}
+	if p := s.layoutPath(id, other); p != s.blobPath(id) {
+		return p
+	}
+	return ""
+}
+
+// readBlob returns the stored bytes and path of the blob named id, looking in
+// the configured layout first and then in the other one. A missing blob
+// yields an error satisfying errors.Is(err, os.ErrNotExist).
+func (s *Service) readBlob(id string) ([]byte, string, error) {
+	candidates := []string{s.blobPath(id)}
+	if other := s.otherBlobPath(id); other != "" {
+		candidates = append(candidates, other)
+	}
+	var firstErr error
+	for _, p := range candidates {
+		if err := s.storage.ValidatePathSafety(p); err != nil {
+			return nil, "", fmt.Errorf("path validation failed: %w", err)
+		}
+		stored, err := s.storage.ReadFile(p)
+		if err == nil {
+			return stored, p, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, "", err
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return nil, "", firstErr
+}
+
+// MigrateLayout moves every blob into layout l and makes l the layout for new
+// writes. Each blob is moved with an atomic rename and keeps its modification
+// time. When a blob already exists at its destination (after an interrupted
+// migration), the destination is verified against the hash: an intact copy
+// makes the leftover source redundant and it is removed, while a corrupted
+// copy is replaced by the source. The migration can be re-run safely.
+//
+// Returns the number of blobs moved.
+func (s *Service) MigrateLayout(l Layout) (int, error) {
+	moved := 0
+	err := s.forEachBlob(func(id, path string, info os.FileInfo) error {
+		dest := s.layoutPath(id, l)
+		if dest == path {
+			return nil
+		}
+		if exists, err := s.storage.Exists(dest); err != nil {
+			return fmt.Errorf("failed to inspect backup %s: %w", id, err)
+		} else if exists {
+			stored, err := s.storage.ReadFile(dest)
+			if err != nil {
+				return fmt.Errorf("failed to read backup %s: %w", id, err)
+			}
+			if s.blobIntact(id, stored) {
+				if err := s.storage.Remove(path); err != nil {
+					return fmt.Errorf("failed to remove duplicate backup %s: %w", id, err)
+				}
+				return nil
+			}
+			s.logger.Warn("replacing corrupted backup copy", "hash", id, "path", dest)
+		}
+		if err := s.storage.MkdirAll(filepath.Dir(dest)); err != nil {
+			return fmt.Errorf("failed to create shard directory: %w", err)
+		}
+		if err := s.storage.Rename(path, dest); err != nil {
+			return fmt.Errorf("failed to move backup %s: %w", id, err)
+		}
+		moved++
+		return nil
+	})
+	if err != nil {
+		return moved, err
+	}
+	s.layout = l
+	s.logger.Info("backup layout migrated", "layout", string(l), "moved", moved)
+	return moved, nil
+}
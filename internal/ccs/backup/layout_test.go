@@ -0,0 +1,148 @@
+package backup
+
+// Tests for the flat and sharded backup layouts.
+//
+// Focus: ParseLayout, sharded writes, mixed-layout reads, MigrateLayout,
+// pruning across shards.
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+func shardedPath(hash string) string {
+	return "/backups/" + hash[:2] + "/" + hash[2:] + ".json"
+}
+
+func TestParseLayout(t *testing.T) {
+	if l, err := ParseLayout(""); err != nil || l != LayoutFlat {
+		t.Errorf("expected flat default, got %q, %v", l, err)
+	}
+	if l, err := ParseLayout("sharded"); err != nil || l != LayoutSharded {
+		t.Errorf("expected sharded, got %q, %v", l, err)
+	}
+	if _, err := ParseLayout("nested"); err == nil {
+		t.Error("expected error for unknown layout")
+	}
+}
+
+func TestShardedLayout_WritesIntoShardAndReadsBothLayouts(t *testing.T) {
+	svc, fs := newTestService(t)
+	flat := writeBlob(t, svc, fs, `{"flat":true}`)
+
+	svc.SetLayout(LayoutSharded)
+	sharded := writeBlob(t, svc, fs, `{"sharded":true}`)
+	if exists, _ := afero.Exists(fs, shardedPath(sharded)); !exists {
+		t.Fatalf("expected blob in shard directory")
+	}
+
+	// Legacy flat blobs stay readable and are deduplicated in place
+	if _, err := svc.ReadBackup(flat); err != nil {
+		t.Fatalf("ReadBackup flat blob: %v", err)
+	}
+	writeBlob(t, svc, fs, `{"flat":true}`)
+	if exists, _ := afero.Exists(fs, shardedPath(flat)); exists {
+		t.Error("existing flat blob should be reused, not copied into a shard")
+	}
+	if _, err := svc.ResolveHash(sharded[:6]); err != nil {
+		t.Errorf("sharded blob should be listed: %v", err)
+	}
+}
+
+func TestMigrateLayout_MovesBlobsAndIsRerunnable(t *testing.T) {
+	svc, fs := newTestService(t)
+	mtime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	first := writeBlob(t, svc, fs, `{"n":1}`)
+	second := writeBlob(t, svc, fs, `{"n":2}`)
+	backdateBlob(t, fs, first, mtime)
+	empty := writeBlob(t, svc, fs, ``)
+
+	// Simulate an interrupted migration: second already has a sharded copy
+	stored, err := afero.ReadFile(fs, "/backups/"+second+".json")
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if err := afero.WriteFile(fs, shardedPath(second), stored, 0o600); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+
+	moved, err := svc.MigrateLayout(LayoutSharded)
+	if err != nil {
+		t.Fatalf("MigrateLayout failed: %v", err)
+	}
+	if moved != 1 {
+		t.Errorf("expected 1 moved blob, got %d", moved)
+	}
+	for _, hash := range []string{first, second} {
+		if exists, _ := afero.Exists(fs, "/backups/"+hash+".json"); exists {
+			t.Errorf("flat copy of %s should be gone", hash)
+		}
+	}
+	info, err := fs.Stat(shardedPath(first))
+	if err != nil {
+		t.Fatalf("stat moved blob: %v", err)
+	}
+	if !info.ModTime().Equal(mtime) {
+		t.Errorf("expected preserved mtime, got %v", info.ModTime())
+	}
+	if exists, _ := afero.Exists(fs, "/backups/"+empty+".json"); !exists {
+		t.Error("empty marker blob should stay in the top-level directory")
+	}
+
+	if moved, err := svc.MigrateLayout(LayoutSharded); err != nil || moved != 0 {
+		t.Errorf("re-run should be a no-op, got %d, %v", moved, err)
+	}
+	if moved, err := svc.MigrateLayout(LayoutFlat); err != nil || moved != 2 {
+		t.Errorf("migrating back should move 2 blobs, got %d, %v", moved, err)
+	}
+}
+
+func TestMigrateLayout_ReplacesCorruptedDestination(t *testing.T) {
+	svc, fs := newTestService(t)
+	hash := writeBlob(t, svc, fs, `{"n":1}`)
+
+	// A truncated copy left behind by an interrupted migration
+	if err := afero.WriteFile(fs, shardedPath(hash), []byte(`{"n"`), 0o600); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+
+	moved, err := svc.MigrateLayout(LayoutSharded)
+	if err != nil {
+		t.Fatalf("MigrateLayout failed: %v", err)
+	}
+	if moved != 1 {
+		t.Errorf("expected the corrupted copy to be replaced, got %d moved", moved)
+	}
+	if exists, _ := afero.Exists(fs, "/backups/"+hash+".json"); exists {
+		t.Error("flat copy should be gone")
+	}
+	plain, err := svc.ReadBackup(hash)
+	if err != nil {
+		t.Fatalf("ReadBackup failed: %v", err)
+	}
+	if string(plain) != `{"n":1}` {
+		t.Errorf("unexpected content %q", plain)
+	}
+}
+
+func TestPruneBackups_CoversShardDirectories(t *testing.T) {
+	svc, fs := newTestService(t)
+	svc.SetLayout(LayoutSharded)
+	now := time.Now()
+	svc.SetNow(func() time.Time { return now })
+	hash := writeBlob(t, svc, fs, `{"old":true}`)
+	backdateBlob(t, fs, hash[:2]+"/"+hash[2:], now.Add(-48*time.Hour))
+
+	deleted, err := svc.PruneBackups(24 * time.Hour)
+	if err != nil {
+		t.Fatalf("PruneBackups failed: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("expected 1 deleted blob, got %d", deleted)
+	}
+	if exists, _ := afero.Exists(fs, shardedPath(hash)); exists {
+		t.Error("sharded blob should be pruned")
+	}
+}
@@ -0,0 +1,413 @@
+package backup
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// MirrorSyncResult reports what SyncMirror copied.
+type MirrorSyncResult struct {
+	// ToMirror counts blobs copied from the backup directory to the mirror.
+	ToMirror int
+	// FromMirror counts blobs copied from the mirror back into the backup directory.
+	FromMirror int
+	// Removed counts mirror copies of locally deleted blobs that were removed.
+	Removed int
+}
+
+// ErrNoMirror indicates that a mirror operation was requested without a
+// configured mirror directory.
+var ErrNoMirror = errors.New("no backup mirror configured")
+
+// SetMirror enables best-effort copying of every new blob, and of the index
+// after each snapshot or annotation, to dir. An empty dir disables mirroring.
+func (s *Service) SetMirror(dir string) {
+	s.mirrorDir = dir
+}
+
+func (s *Service) mirrorPath(id string) string {
+	return filepath.Join(s.mirrorDir, id+".json")
+}
+
+func (s *Service) mirrorIndexPath() string {
+	return filepath.Join(s.mirrorDir, metaDirName, "index.json")
+}
+
+// mirrorIndexEntry stands for the snapshot index in the mirror queue, next to
+// the hashes of queued blobs.
+const mirrorIndexEntry = "index"
+
+func (s *Service) mirrorQueuePath() string {
+	return filepath.Join(s.backupDir, metaDirName, "mirror-queue.json")
+}
+
+// mirrorBlob copies a freshly written blob to the mirror. Failures are logged
+// and the blob is queued so the next backup or SyncMirror retries it; the
+// local backup has already succeeded and must not be failed by the mirror.
+func (s *Service) mirrorBlob(id string, stored []byte, mtime time.Time) {
+	if s.mirrorDir == "" {
+		return
+	}
+	pending, err := s.loadMirrorQueue()
+	if err != nil {
+		s.logger.Warn("failed to read mirror queue", "error", err)
+	}
+	if err := s.copyBlob(s.mirrorPath(id), stored, mtime); err != nil {
+		s.logger.Warn("failed to mirror backup; queued for retry", "hash", id, "mirror", s.mirrorDir, "error", err)
+		pending = appendUnique(pending, id)
+	} else {
+		pending = s.retryMirrorQueue(pending)
+	}
+	if err := s.saveMirrorQueue(pending); err != nil {
+		s.logger.Warn("failed to write mirror queue", "error", err)
+	}
+}
+
+// mirrorIndex merges idx into the mirror's copy of the index after a
+// snapshot or annotation, so a mirror can be restored from with its history,
+// pins and notes. Failures are logged and queued like blobs.
+func (s *Service) mirrorIndex(idx *index) {
+	if s.mirrorDir == "" {
+		return
+	}
+	pending, err := s.loadMirrorQueue()
+	if err != nil {
+		s.logger.Warn("failed to read mirror queue", "error", err)
+	}
+	if err := s.writeMirrorIndex(idx); err != nil {
+		s.logger.Warn("failed to mirror backup index; queued for retry", "mirror", s.mirrorDir, "error", err)
+		pending = appendUnique(pending, mirrorIndexEntry)
+	} else {
+		pending = s.retryMirrorQueue(pending)
+	}
+	if err := s.saveMirrorQueue(pending); err != nil {
+		s.logger.Warn("failed to write mirror queue", "error", err)
+	}
+}
+
+// writeMirrorIndex writes idx merged with the mirror's copy of the index to
+// the mirror. Snapshots only the mirror knows are kept, so a fresh backup
+// directory cannot erase the mirrored history, unless they reference a blob
+// that was deleted. Annotations of blobs present locally follow idx; the
+// mirror's annotations are kept for the others.
+func (s *Service) writeMirrorIndex(idx *index) error {
+	merged := &index{Version: indexVersion, Snapshots: append([]Snapshot(nil), idx.Snapshots...)}
+	for id, meta := range idx.Blobs {
+		if merged.Blobs == nil {
+			merged.Blobs = make(map[string]BlobMeta)
+		}
+		merged.Blobs[id] = meta
+	}
+	data, err := s.storage.ReadFile(s.mirrorIndexPath())
+	switch {
+	case err == nil:
+		var mirrored index
+		if err := json.Unmarshal(data, &mirrored); err != nil {
+			s.logger.Warn("replacing unreadable mirror index", "error", err)
+			break
+		}
+		s.dropForgotten(&mirrored, s.hasLocalBlob, toSet(idx.Deleted))
+		mergeIndex(merged, &mirrored)
+	case !errors.Is(err, os.ErrNotExist):
+		return fmt.Errorf("failed to read mirror index: %w", err)
+	}
+	if data, err = json.MarshalIndent(merged, "", "  "); err != nil {
+		return fmt.Errorf("failed to encode backup index: %w", err)
+	}
+	if err := s.storage.WriteFileAtomic(s.mirrorIndexPath(), append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write mirror index: %w", err)
+	}
+	return nil
+}
+
+// retryMirrorQueue copies queued blobs, and the index when queued, to the
+// mirror and returns the entries that still could not be copied. Blobs that
+// no longer exist locally are dropped.
+func (s *Service) retryMirrorQueue(pending []string) []string {
+	var remaining []string
+	for _, id := range pending {
+		if id == mirrorIndexEntry {
+			idx, err := s.loadIndex()
+			if err == nil {
+				err = s.writeMirrorIndex(idx)
+			}
+			if err != nil {
+				s.logger.Warn("mirror index retry failed", "error", err)
+				remaining = append(remaining, id)
+			}
+			continue
+		}
+		stored, path, err := s.readBlob(id)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err == nil {
+			var info os.FileInfo
+			if info, err = s.storage.Stat(path); err == nil {
+				err = s.copyBlob(s.mirrorPath(id), stored, info.ModTime())
+			}
+		}
+		if err != nil {
+			s.logger.Warn("mirror retry failed", "hash", id, "error", err)
+			remaining = append(remaining, id)
+		}
+	}
+	return remaining
+}
+
+// SyncMirror reconciles the backup directory and the mirror by hash. Local
+// blobs are authoritative: a mirror copy that is missing or differs from the
+// local bytes (after a rekey or recompression) is replaced. Mirror-only blobs
+// are copied back, except those deleted locally, whose mirror copies are
+// removed. The snapshot indexes of both sides are merged. Corrupted blobs are
+// logged and never copied. The retry queue and the deletion records are
+// cleared on success.
+func (s *Service) SyncMirror() (MirrorSyncResult, error) {
+	var result MirrorSyncResult
+	if s.mirrorDir == "" {
+		return result, ErrNoMirror
+	}
+	if err := s.storage.MkdirAll(s.mirrorDir); err != nil {
+		return result, fmt.Errorf("failed to create mirror directory: %w", err)
+	}
+	idx, err := s.loadIndex()
+	if err != nil {
+		return result, err
+	}
+	deleted := toSet(idx.Deleted)
+
+	local := make(map[string]bool)
+	err = s.forEachBlob(func(id, path string, info os.FileInfo) error {
+		local[id] = true
+		stored, err := s.storage.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read backup %s: %w", id, err)
+		}
+		mirrored, err := s.storage.ReadFile(s.mirrorPath(id))
+		if err == nil && bytes.Equal(mirrored, stored) {
+			return nil
+		} else if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to read mirrored backup %s: %w", id, err)
+		}
+		if !s.blobIntact(id, stored) {
+			s.logger.Warn("not mirroring corrupted backup", "hash", id)
+			return nil
+		}
+		if err := s.copyBlob(s.mirrorPath(id), stored, info.ModTime()); err != nil {
+			return fmt.Errorf("failed to mirror backup %s: %w", id, err)
+		}
+		result.ToMirror++
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	entries, err := s.storage.ReadDir(s.mirrorDir)
+	if err != nil {
+		return result, fmt.Errorf("failed to read mirror directory: %w", err)
+	}
+	for _, entry := range entries {
+		id := fileBlobID("", entry.Name())
+		if entry.IsDir() || !blobIDPattern.MatchString(id) || local[id] {
+			continue
+		}
+		if deleted[id] {
+			if err := s.storage.Remove(s.mirrorPath(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return result, fmt.Errorf("failed to remove mirrored backup %s: %w", id, err)
+			}
+			result.Removed++
+			continue
+		}
+		stored, err := s.storage.ReadFile(filepath.Join(s.mirrorDir, entry.Name()))
+		if err != nil {
+			return result, fmt.Errorf("failed to read mirrored backup %s: %w", id, err)
+		}
+		if !s.blobIntact(id, stored) {
+			s.logger.Warn("not restoring corrupted mirrored backup", "hash", id)
+			continue
+		}
+		if err := s.copyBlob(s.blobPath(id), stored, entry.ModTime()); err != nil {
+			return result, fmt.Errorf("failed to restore mirrored backup %s: %w", id, err)
+		}
+		result.FromMirror++
+	}
+
+	if err := s.syncMirrorIndex(local, deleted); err != nil {
+		return result, err
+	}
+	if err := s.saveMirrorQueue(nil); err != nil {
+		return result, err
+	}
+	s.logger.Info("backup mirror synchronized",
+		"mirror", s.mirrorDir,
+		"to_mirror", result.ToMirror,
+		"from_mirror", result.FromMirror,
+		"removed", result.Removed)
+	return result, nil
+}
+
+// syncMirrorIndex merges the mirror's copy of the index into the local one
+// and writes the result to both sides. Annotations of the blobs in local,
+// which were present before the sync, come from the local index only, so
+// pins and notes removed locally are not brought back from the mirror.
+// Mirrored snapshots of deleted blobs are dropped, and the deletion records
+// are cleared since the mirror copies are gone.
+func (s *Service) syncMirrorIndex(local, deleted map[string]bool) error {
+	idx, err := s.loadIndex()
+	if err != nil {
+		return err
+	}
+	if data, err := s.storage.ReadFile(s.mirrorIndexPath()); err == nil {
+		var mirrored index
+		if err := json.Unmarshal(data, &mirrored); err != nil {
+			s.logger.Warn("ignoring unreadable mirror index", "error", err)
+		} else {
+			s.dropForgotten(&mirrored, func(id string) bool { return local[id] }, deleted)
+			mergeIndex(idx, &mirrored)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read mirror index: %w", err)
+	}
+	idx.Deleted = nil
+	if err := s.saveIndex(idx); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(idx, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode backup index: %w", err)
+	}
+	if err := s.storage.WriteFileAtomic(s.mirrorIndexPath(), append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write mirror index: %w", err)
+	}
+	return nil
+}
+
+// dropForgotten prepares the mirror's index for a merge. Annotations of blobs
+// for which isLocal reports true are removed, so the local annotations of
+// those blobs win, including removals. Snapshots and annotations of blobs that
+// are gone, because they are not local and were deleted or have no mirror
+// copy, are removed so a merge cannot bring back what pruning forgot.
+func (s *Service) dropForgotten(mirrored *index, isLocal func(id string) bool, deleted map[string]bool) {
+	gone := make(map[string]bool)
+	isGone := func(id string) bool {
+		if g, ok := gone[id]; ok {
+			return g
+		}
+		g := false
+		if !isLocal(id) {
+			if deleted[id] {
+				g = true
+			} else {
+				exists, err := s.storage.Exists(s.mirrorPath(id))
+				g = err == nil && !exists
+			}
+		}
+		gone[id] = g
+		return g
+	}
+	for id := range mirrored.Blobs {
+		if isLocal(id) || isGone(id) {
+			delete(mirrored.Blobs, id)
+		}
+	}
+	kept := mirrored.Snapshots[:0]
+	for _, snap := range mirrored.Snapshots {
+		forgotten := false
+		for _, f := range snap.Files {
+			if f.Hash != "" && isGone(f.Hash) {
+				forgotten = true
+				break
+			}
+		}
+		if !forgotten {
+			kept = append(kept, snap)
+		}
+	}
+	mirrored.Snapshots = kept
+}
+
+// removeMirrorCopy deletes the mirror copy of a locally deleted blob and
+// reports whether the mirror no longer holds it.
+func (s *Service) removeMirrorCopy(id string) bool {
+	err := s.storage.Remove(s.mirrorPath(id))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		s.logger.Warn("failed to remove mirrored backup; recorded for sync-mirror", "hash", id, "mirror", s.mirrorDir, "error", err)
+		return false
+	}
+	return true
+}
+
+// hasLocalBlob reports whether the backup directory holds the blob id.
+func (s *Service) hasLocalBlob(id string) bool {
+	_, _, err := s.readBlob(id)
+	return err == nil
+}
+
+func toSet(items []string) map[string]bool {
+	set := make(map[string]bool, len(items))
+	for _, item := range items {
+		set[item] = true
+	}
+	return set
+}
+
+// copyBlob writes stored bytes verbatim to dst, verifying the written copy,
+// and sets its modification time.
+func (s *Service) copyBlob(dst string, stored []byte, mtime time.Time) error {
+	err := s.storage.WriteFileVerified(dst, stored, func(written []byte) error {
+		if !bytes.Equal(written, stored) {
+			return errors.New("written copy differs from source")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return s.storage.Chtimes(dst, mtime, mtime)
+}
+
+func (s *Service) loadMirrorQueue() ([]string, error) {
+	data, err := s.storage.ReadFile(s.mirrorQueuePath())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var pending []string
+	if err := json.Unmarshal(data, &pending); err != nil {
+		return nil, fmt.Errorf("failed to parse mirror queue: %w", err)
+	}
+	return pending, nil
+}
+
+func (s *Service) saveMirrorQueue(pending []string) error {
+	if len(pending) == 0 {
+		if err := s.storage.Remove(s.mirrorQueuePath()); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to clear mirror queue: %w", err)
+		}
+		return nil
+	}
+	sort.Strings(pending)
+	data, err := json.Marshal(pending)
+	if err != nil {
+		return fmt.Errorf("failed to encode mirror queue: %w", err)
+	}
+	return s.storage.WriteFileAtomic(s.mirrorQueuePath(), append(data, '\n'))
+}
+
+func appendUnique(items []string, item string) []string {
+	for _, existing := range items {
+		if existing == item {
+			return items
+		}
+	}
+	return append(items, item)
+}
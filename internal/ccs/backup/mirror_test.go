@@ -0,0 +1,368 @@
+package backup
+
+// Tests for mirroring backups to a secondary directory.
+//
+// Focus: best-effort mirroring on backup, retry queue, SyncMirror reconciliation.
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OpenGG/claude-code-switch-settings/internal/ccs/storage"
+	"github.com/spf13/afero"
+)
+
+func TestBackupFile_MirrorsNewBlobs(t *testing.T) {
+	svc, fs := newTestService(t)
+	svc.SetMirror("/mirror")
+
+	hash := writeBlob(t, svc, fs, `{"a":1}`)
+
+	mirrored, err := afero.ReadFile(fs, "/mirror/"+hash+".json")
+	if err != nil {
+		t.Fatalf("expected mirrored blob: %v", err)
+	}
+	if string(mirrored) != `{"a":1}` {
+		t.Errorf("unexpected mirrored content %q", mirrored)
+	}
+}
+
+// unavailableFs fails every file open below prefix while down is set.
+type unavailableFs struct {
+	afero.Fs
+	prefix string
+	down   bool
+}
+
+func (f *unavailableFs) OpenFile(name string, flag int, perm os.FileMode) (afero.File, error) {
+	if f.down && strings.HasPrefix(name, f.prefix) {
+		return nil, errors.New("device not available")
+	}
+	return f.Fs.OpenFile(name, flag, perm)
+}
+
+func TestBackupFile_QueuesFailedMirrorAndRetries(t *testing.T) {
+	fs := &unavailableFs{Fs: afero.NewMemMapFs(), prefix: "/mirror", down: true}
+	svc := New(storage.New(fs), "/backups", nil)
+	svc.SetMirror("/mirror")
+
+	first := writeBlob(t, svc, fs, `{"a":1}`)
+	if exists, _ := afero.Exists(fs, "/backups/"+first+".json"); !exists {
+		t.Fatal("local backup must succeed even if the mirror fails")
+	}
+	pending, err := svc.loadMirrorQueue()
+	if err != nil {
+		t.Fatalf("loadMirrorQueue failed: %v", err)
+	}
+	if len(pending) != 1 || pending[0] != first {
+		t.Fatalf("expected queued blob, got %v", pending)
+	}
+
+	// Once the mirror is reachable, the next backup drains the queue
+	fs.down = false
+	writeBlob(t, svc, fs, `{"b":2}`)
+	if exists, _ := afero.Exists(fs, "/mirror/"+first+".json"); !exists {
+		t.Error("queued blob should be mirrored on retry")
+	}
+	if pending, _ := svc.loadMirrorQueue(); len(pending) != 0 {
+		t.Errorf("expected empty queue, got %v", pending)
+	}
+}
+
+func TestSyncMirror_ReconcilesBothDirections(t *testing.T) {
+	svc, fs := newTestService(t)
+	if _, err := svc.SyncMirror(); !errors.Is(err, ErrNoMirror) {
+		t.Fatalf("expected ErrNoMirror, got %v", err)
+	}
+
+	localOnly := writeBlob(t, svc, fs, `{"local":true}`)
+	svc.SetMirror("/mirror")
+	if err := afero.WriteFile(fs, "/mirror/"+contentID([]byte(`{"mirror":true}`))+".json", []byte(`{"mirror":true}`), 0o600); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	corrupted := contentID([]byte("expected"))
+	if err := afero.WriteFile(fs, "/mirror/"+corrupted+".json", []byte("tampered"), 0o600); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+
+	result, err := svc.SyncMirror()
+	if err != nil {
+		t.Fatalf("SyncMirror failed: %v", err)
+	}
+	if result.ToMirror != 1 || result.FromMirror != 1 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	if exists, _ := afero.Exists(fs, "/mirror/"+localOnly+".json"); !exists {
+		t.Error("local blob should be copied to the mirror")
+	}
+	if _, err := svc.ReadBackup(contentID([]byte(`{"mirror":true}`))); err != nil {
+		t.Errorf("mirrored blob should be restored locally: %v", err)
+	}
+	if exists, _ := afero.Exists(fs, "/backups/"+corrupted+".json"); exists {
+		t.Error("corrupted mirrored blob must not be restored")
+	}
+	if exists, _ := afero.Exists(fs, "/mirror/meta/index.json"); !exists {
+		t.Error("index should be written to the mirror")
+	}
+}
+
+func TestSnapshot_MirrorsIndexForRestoreFromMirrorAlone(t *testing.T) {
+	svc, fs := newTestService(t)
+	svc.SetMirror("/mirror")
+
+	if err := afero.WriteFile(fs, "/settings.json", []byte(`{"v":1}`), 0o644); err != nil {
+		t.Fatalf("write settings: %v", err)
+	}
+	first, err := svc.Snapshot("use work", "/settings.json")
+	if err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+	if _, err := svc.SetNote(first.Files[0].Hash, "known good"); err != nil {
+		t.Fatalf("SetNote failed: %v", err)
+	}
+	if err := afero.WriteFile(fs, "/settings.json", []byte(`{"v":2}`), 0o644); err != nil {
+		t.Fatalf("write settings: %v", err)
+	}
+	if _, err := svc.Snapshot("use home", "/settings.json"); err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+
+	// Wipe the local backups; only the mirror is left
+	if err := fs.RemoveAll("/backups"); err != nil {
+		t.Fatalf("remove backups: %v", err)
+	}
+	if err := fs.MkdirAll("/backups", 0o700); err != nil {
+		t.Fatalf("recreate backups: %v", err)
+	}
+	if err := afero.WriteFile(fs, "/settings.json", []byte(`{"broken`), 0o644); err != nil {
+		t.Fatalf("write settings: %v", err)
+	}
+	if _, err := svc.SyncMirror(); err != nil {
+		t.Fatalf("SyncMirror failed: %v", err)
+	}
+
+	snaps, err := svc.Snapshots()
+	if err != nil {
+		t.Fatalf("Snapshots failed: %v", err)
+	}
+	if len(snaps) != 2 || snaps[1].ID != first.ID {
+		t.Fatalf("expected both snapshots from the mirror, got %+v", snaps)
+	}
+	annotations, err := svc.Annotations()
+	if err != nil {
+		t.Fatalf("Annotations failed: %v", err)
+	}
+	if annotations[first.Files[0].Hash].Note != "known good" {
+		t.Errorf("expected the note to be mirrored, got %+v", annotations)
+	}
+	if err := svc.RestoreSnapshot(snaps[1]); err != nil {
+		t.Fatalf("RestoreSnapshot failed: %v", err)
+	}
+	if data, _ := afero.ReadFile(fs, "/settings.json"); string(data) != `{"v":1}` {
+		t.Errorf("unexpected restored content %q", data)
+	}
+}
+
+func TestSnapshot_QueuesFailedIndexMirror(t *testing.T) {
+	fs := &unavailableFs{Fs: afero.NewMemMapFs(), prefix: "/mirror", down: true}
+	svc := New(storage.New(fs), "/backups", nil)
+	svc.SetMirror("/mirror")
+	if err := afero.WriteFile(fs, "/settings.json", []byte(`{"v":1}`), 0o644); err != nil {
+		t.Fatalf("write settings: %v", err)
+	}
+	if _, err := svc.Snapshot("use work", "/settings.json"); err != nil {
+		t.Fatalf("Snapshot must succeed even if the mirror fails: %v", err)
+	}
+	pending, err := svc.loadMirrorQueue()
+	if err != nil {
+		t.Fatalf("loadMirrorQueue failed: %v", err)
+	}
+	if len(pending) != 2 || pending[1] != mirrorIndexEntry {
+		t.Fatalf("expected the index to be queued, got %v", pending)
+	}
+
+	fs.down = false
+	writeBlob(t, svc, fs, `{"b":2}`)
+	if exists, _ := afero.Exists(fs, "/mirror/meta/index.json"); !exists {
+		t.Error("queued index should be mirrored on retry")
+	}
+	if pending, _ := svc.loadMirrorQueue(); len(pending) != 0 {
+		t.Errorf("expected empty queue, got %v", pending)
+	}
+}
+
+func TestSyncMirror_KeepsLocalUnpin(t *testing.T) {
+	fs := &unavailableFs{Fs: afero.NewMemMapFs(), prefix: "/mirror"}
+	svc := New(storage.New(fs), "/backups", nil)
+	svc.SetMirror("/mirror")
+	if err := afero.WriteFile(fs, "/settings.json", []byte(`{"v":1}`), 0o644); err != nil {
+		t.Fatalf("write settings: %v", err)
+	}
+	snap, err := svc.Snapshot("use work", "/settings.json")
+	if err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+	hash := snap.Files[0].Hash
+	if _, err := svc.SetPinned(hash, true); err != nil {
+		t.Fatalf("SetPinned failed: %v", err)
+	}
+
+	// Unpin while the mirror is unreachable, leaving its index stale
+	fs.down = true
+	if _, err := svc.SetPinned(hash, false); err != nil {
+		t.Fatalf("SetPinned failed: %v", err)
+	}
+	fs.down = false
+	if _, err := svc.SyncMirror(); err != nil {
+		t.Fatalf("SyncMirror failed: %v", err)
+	}
+
+	annotations, err := svc.Annotations()
+	if err != nil {
+		t.Fatalf("Annotations failed: %v", err)
+	}
+	if annotations[hash].Pinned {
+		t.Error("a local unpin must not be undone by the mirror index")
+	}
+	data, err := afero.ReadFile(fs, "/mirror/meta/index.json")
+	if err != nil {
+		t.Fatalf("read mirror index: %v", err)
+	}
+	if strings.Contains(string(data), `"pinned"`) {
+		t.Errorf("expected the unpin to reach the mirror index, got %s", data)
+	}
+}
+
+func (f *unavailableFs) Remove(name string) error {
+	if f.down && strings.HasPrefix(name, f.prefix) {
+		return errors.New("device not available")
+	}
+	return f.Fs.Remove(name)
+}
+
+// snapshotVersions records two snapshots of /settings.json, backdates the
+// first one's blob by two days and returns both snapshots.
+func snapshotVersions(t *testing.T, svc *Service, fs afero.Fs) (old, current Snapshot) {
+	t.Helper()
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	svc.SetNow(func() time.Time { return now })
+	for i, content := range []string{`{"v":1}`, `{"v":2}`} {
+		if err := afero.WriteFile(fs, "/settings.json", []byte(content), 0o644); err != nil {
+			t.Fatalf("write settings: %v", err)
+		}
+		snap, err := svc.Snapshot("use work", "/settings.json")
+		if err != nil {
+			t.Fatalf("Snapshot failed: %v", err)
+		}
+		if i == 0 {
+			old = snap
+		} else {
+			current = snap
+		}
+		now = now.Add(time.Second)
+	}
+	backdateBlob(t, fs, old.Files[0].Hash, now.Add(-48*time.Hour))
+	return old, current
+}
+
+func TestPruneBackups_RemovesMirrorCopiesAndSnapshots(t *testing.T) {
+	svc, fs := newTestService(t)
+	svc.SetMirror("/mirror")
+	old, current := snapshotVersions(t, svc, fs)
+
+	if deleted, err := svc.PruneBackups(24 * time.Hour); err != nil || deleted != 1 {
+		t.Fatalf("PruneBackups = %d, %v", deleted, err)
+	}
+	if exists, _ := afero.Exists(fs, "/mirror/"+old.Files[0].Hash+".json"); exists {
+		t.Error("the mirror copy of a pruned backup should be removed")
+	}
+	data, err := afero.ReadFile(fs, "/mirror/meta/index.json")
+	if err != nil {
+		t.Fatalf("read mirror index: %v", err)
+	}
+	if strings.Contains(string(data), old.ID) || !strings.Contains(string(data), current.ID) {
+		t.Errorf("mirror index should only hold the remaining snapshot, got %s", data)
+	}
+}
+
+func TestSyncMirror_DoesNotRestorePrunedBackups(t *testing.T) {
+	fs := &unavailableFs{Fs: afero.NewMemMapFs(), prefix: "/mirror"}
+	svc := New(storage.New(fs), "/backups", nil)
+	svc.SetMirror("/mirror")
+	old, current := snapshotVersions(t, svc, fs)
+
+	// Prune while the mirror is unreachable, leaving the old copy behind
+	fs.down = true
+	if deleted, err := svc.PruneBackups(24 * time.Hour); err != nil || deleted != 1 {
+		t.Fatalf("PruneBackups = %d, %v", deleted, err)
+	}
+	fs.down = false
+	result, err := svc.SyncMirror()
+	if err != nil {
+		t.Fatalf("SyncMirror failed: %v", err)
+	}
+	if result.FromMirror != 0 || result.Removed != 1 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	for _, path := range []string{"/backups/", "/mirror/"} {
+		if exists, _ := afero.Exists(fs, path+old.Files[0].Hash+".json"); exists {
+			t.Errorf("pruned backup should be gone from %s", path)
+		}
+	}
+	snaps, err := svc.Snapshots()
+	if err != nil {
+		t.Fatalf("Snapshots failed: %v", err)
+	}
+	if len(snaps) != 1 || snaps[0].ID != current.ID {
+		t.Fatalf("pruned snapshot should not come back, got %+v", snaps)
+	}
+	idx, err := svc.loadIndex()
+	if err != nil {
+		t.Fatalf("loadIndex failed: %v", err)
+	}
+	if len(idx.Deleted) != 0 {
+		t.Errorf("deletion records should be cleared after a sync, got %v", idx.Deleted)
+	}
+}
+
+func TestSyncMirror_ReplacesStaleMirrorCopies(t *testing.T) {
+	svc, fs := newTestService(t)
+	svc.SetMirror("/mirror")
+	hash := writeBlob(t, svc, fs, `{"secret":"value"}`)
+
+	// Rekeying rewrites the mirror copy along with the local one
+	key, err := NewPassphraseKeyring("passphrase")
+	if err != nil {
+		t.Fatalf("keyring: %v", err)
+	}
+	if _, _, err := svc.Rekey(key); err != nil {
+		t.Fatalf("Rekey failed: %v", err)
+	}
+	mirrored, err := afero.ReadFile(fs, "/mirror/"+hash+".json")
+	if err != nil {
+		t.Fatalf("read mirror copy: %v", err)
+	}
+	if strings.Contains(string(mirrored), "secret") {
+		t.Fatal("the mirror copy should be encrypted after rekey")
+	}
+
+	// A copy that differs from the local bytes is replaced by a sync
+	if err := afero.WriteFile(fs, "/mirror/"+hash+".json", []byte(`{"secret":"value"}`), 0o600); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	result, err := svc.SyncMirror()
+	if err != nil {
+		t.Fatalf("SyncMirror failed: %v", err)
+	}
+	if result.ToMirror != 1 {
+		t.Fatalf("expected the stale copy to be replaced, got %+v", result)
+	}
+	local, _ := afero.ReadFile(fs, "/backups/"+hash+".json")
+	mirrored, _ = afero.ReadFile(fs, "/mirror/"+hash+".json")
+	if string(local) != string(mirrored) {
+		t.Error("mirror copy should match the local blob after a sync")
+	}
+}
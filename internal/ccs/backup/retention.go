@@ -0,0 +1,112 @@
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RetentionPolicy describes which blobs ApplyRetention may delete.
+// Pinned blobs are always kept.
+type RetentionPolicy struct {
+	// MaxAge deletes blobs whose modification time is older than this.
+	// Zero disables the age limit.
+	MaxAge time.Duration
+	// KeepLast always keeps this many of the most recently refreshed blobs.
+	// With MaxAge unset, every other blob is deleted.
+	KeepLast int
+}
+
+func (p RetentionPolicy) isZero() bool {
+	return p.MaxAge <= 0 && p.KeepLast <= 0
+}
+
+type blobAge struct {
+	id      string
+	path    string
+	modTime time.Time
+}
+
+// ApplyRetention deletes the blobs the policy allows and records the run time,
+// so callers can throttle automatic runs with LastRetentionRun.
+//
+// Snapshots and annotations of deleted blobs are forgotten, as with
+// PruneBackups. Each deletion is logged.
+//
+// Returns the hashes of the deleted blobs.
+func (s *Service) ApplyRetention(policy RetentionPolicy) ([]string, error) {
+	if policy.isZero() {
+		return nil, nil
+	}
+	idx, err := s.loadIndex()
+	if err != nil {
+		return nil, err
+	}
+	var blobs []blobAge
+	err = s.forEachBlob(func(id, path string, info os.FileInfo) error {
+		if !idx.Blobs[id].Pinned {
+			blobs = append(blobs, blobAge{id: id, path: path, modTime: info.ModTime()})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(blobs, func(i, j int) bool {
+		return blobs[i].modTime.After(blobs[j].modTime)
+	})
+
+	now := s.now()
+	cutoff := now.Add(-policy.MaxAge)
+	var deleted []string
+	removed := make(map[string]bool)
+	for i, blob := range blobs {
+		if i < policy.KeepLast {
+			continue
+		}
+		if policy.MaxAge > 0 && !blob.modTime.Before(cutoff) {
+			continue
+		}
+		if err := s.storage.Remove(blob.path); err != nil {
+			return deleted, fmt.Errorf("failed to delete backup: %w", err)
+		}
+		s.logger.Info("backup removed by retention policy",
+			"hash", blob.id,
+			"age", now.Sub(blob.modTime).Round(time.Second).String())
+		removed[blob.id] = true
+		deleted = append(deleted, blob.id)
+	}
+	if err := s.forgetBlobs(removed); err != nil {
+		return deleted, err
+	}
+	if err := s.storage.WriteFileAtomic(s.retentionStampPath(), []byte(now.UTC().Format(time.RFC3339)+"\n")); err != nil {
+		return deleted, fmt.Errorf("failed to record retention run: %w", err)
+	}
+	return deleted, nil
+}
+
+// LastRetentionRun returns when ApplyRetention last completed, or the zero
+// time if it never ran or the record is unreadable.
+func (s *Service) LastRetentionRun() time.Time {
+	data, err := s.storage.ReadFile(s.retentionStampPath())
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			s.logger.Warn("failed to read retention stamp", "error", err)
+		}
+		return time.Time{}
+	}
+	t, err := time.Parse(time.RFC3339, strings.TrimSpace(string(data)))
+	if err != nil {
+		s.logger.Warn("ignoring malformed retention stamp", "error", err)
+		return time.Time{}
+	}
+	return t
+}
+
+func (s *Service) retentionStampPath() string {
+	return filepath.Join(s.backupDir, metaDirName, "retention-stamp")
+}
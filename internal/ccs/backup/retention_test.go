@@ -0,0 +1,84 @@
+package backup
+
+// Tests for policy-based retention.
+//
+// Focus: ApplyRetention (maxAge, keepLast, pins), LastRetentionRun stamp.
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+func backdateBlob(t *testing.T, fs afero.Fs, hash string, mod time.Time) {
+	t.Helper()
+	if err := fs.Chtimes("/backups/"+hash+".json", mod, mod); err != nil {
+		t.Fatalf("chtimes: %v", err)
+	}
+}
+
+func TestApplyRetention_MaxAgeKeepLastAndPins(t *testing.T) {
+	svc, fs := newTestService(t)
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	svc.SetNow(func() time.Time { return now })
+
+	newest := writeBlob(t, svc, fs, `{"n":1}`)
+	older := writeBlob(t, svc, fs, `{"n":2}`)
+	oldest := writeBlob(t, svc, fs, `{"n":3}`)
+	pinned := writeBlob(t, svc, fs, `{"n":4}`)
+	backdateBlob(t, fs, newest, now.Add(-40*24*time.Hour))
+	backdateBlob(t, fs, older, now.Add(-50*24*time.Hour))
+	backdateBlob(t, fs, oldest, now.Add(-60*24*time.Hour))
+	backdateBlob(t, fs, pinned, now.Add(-70*24*time.Hour))
+	if _, err := svc.SetPinned(pinned, true); err != nil {
+		t.Fatalf("SetPinned failed: %v", err)
+	}
+
+	// All four are past maxAge; keepLast protects the newest unpinned one
+	deleted, err := svc.ApplyRetention(RetentionPolicy{MaxAge: 30 * 24 * time.Hour, KeepLast: 1})
+	if err != nil {
+		t.Fatalf("ApplyRetention failed: %v", err)
+	}
+	if len(deleted) != 2 {
+		t.Fatalf("expected 2 deletions, got %v", deleted)
+	}
+	for _, hash := range []string{newest, pinned} {
+		if exists, _ := afero.Exists(fs, "/backups/"+hash+".json"); !exists {
+			t.Errorf("blob %s should be kept", hash)
+		}
+	}
+	if got := svc.LastRetentionRun(); !got.Equal(now) {
+		t.Errorf("expected retention stamp %v, got %v", now, got)
+	}
+}
+
+func TestApplyRetention_KeepLastOnly(t *testing.T) {
+	svc, fs := newTestService(t)
+	now := time.Now()
+	first := writeBlob(t, svc, fs, `{"n":1}`)
+	second := writeBlob(t, svc, fs, `{"n":2}`)
+	backdateBlob(t, fs, first, now.Add(-time.Hour))
+	backdateBlob(t, fs, second, now)
+
+	deleted, err := svc.ApplyRetention(RetentionPolicy{KeepLast: 1})
+	if err != nil {
+		t.Fatalf("ApplyRetention failed: %v", err)
+	}
+	if len(deleted) != 1 || deleted[0] != first {
+		t.Fatalf("expected only the older blob deleted, got %v", deleted)
+	}
+}
+
+func TestLastRetentionRun_MissingOrMalformed(t *testing.T) {
+	svc, fs := newTestService(t)
+	if got := svc.LastRetentionRun(); !got.IsZero() {
+		t.Errorf("expected zero time without stamp, got %v", got)
+	}
+	if err := afero.WriteFile(fs, svc.retentionStampPath(), []byte("garbage"), 0o600); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	if got := svc.LastRetentionRun(); !got.IsZero() {
+		t.Errorf("expected zero time for malformed stamp, got %v", got)
+	}
+}
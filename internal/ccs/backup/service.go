@@ -2,6 +2,7 @@ package backup
 
 import (
 	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -9,17 +10,23 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
+	"github.com/OpenGG/claude-code-switch-settings/internal/ccs/jsondoc"
 	"github.com/OpenGG/claude-code-switch-settings/internal/ccs/storage"
 )
 
 // Service handles backup operations with content-addressed storage.
 type Service struct {
-	storage   *storage.Storage
-	backupDir string
-	now       func() time.Time
-	logger    *slog.Logger
+	storage     *storage.Storage
+	backupDir   string
+	now         func() time.Time
+	logger      *slog.Logger
+	compression Compression
+	keyring     *Keyring
+	layout      Layout
+	mirrorDir   string
 }
 
 // New creates a new backup Service.
@@ -28,10 +35,12 @@ func New(storage *storage.Storage, backupDir string, logger *slog.Logger) *Servi
 		logger = slog.New(slog.NewTextHandler(io.Discard, nil))
 	}
 	return &Service{
-		storage:   storage,
-		backupDir: backupDir,
-		now:       time.Now,
-		logger:    logger,
+		storage:     storage,
+		backupDir:   backupDir,
+		now:         time.Now,
+		logger:      logger,
+		compression: CompressionNone,
+		layout:      LayoutFlat,
 	}
 }
 
@@ -44,6 +53,18 @@ func (s *Service) SetNow(now func() time.Time) {
 	s.now = now
 }
 
+// SetCompression selects the encoding used for newly written backups.
+// Existing blobs keep their encoding until Recompress rewrites them.
+func (s *Service) SetCompression(c Compression) {
+	s.compression = c
+}
+
+// SetKeyring enables encryption of new backups with the given keyring and
+// allows encrypted blobs to be read. A nil keyring writes unencrypted blobs.
+func (s *Service) SetKeyring(k *Keyring) {
+	s.keyring = k
+}
+
 // CalculateHash returns the SHA-256 hash of the given file.
 // Empty files return a special "empty" marker and log a warning.
 // Missing files return an empty string without error.
@@ -80,6 +101,65 @@ func (s *Service) CalculateHash(path string) (string, error) {
 	return hex.EncodeToString(h.Sum(nil)), nil
 }
 
+// Fingerprint returns a SHA-256 fingerprint of the meaning of a JSON file,
+// for detecting drift between settings files. It hashes the canonical form
+// (sorted keys, normalized whitespace and numbers), so reformatting or
+// reordering keys does not change it. Files that are not valid JSON fall back
+// to the byte hash of CalculateHash, as do empty and missing files.
+//
+// Values at the ignore paths are removed before hashing, together with parent
+// objects left empty, so two files that differ only there share a fingerprint.
+//
+// Fingerprints are for comparison only; blobs stay addressed by byte hash.
+func (s *Service) Fingerprint(path string, ignore ...jsondoc.Path) (string, error) {
+	hash, err := s.CalculateHash(path)
+	if err != nil || hash == "" || hash == "empty" {
+		return hash, err
+	}
+	data, err := s.storage.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file for fingerprint: %w", err)
+	}
+	doc, err := jsondoc.Parse(data)
+	if err != nil {
+		return hash, nil
+	}
+	for _, p := range ignore {
+		if doc, err = deleteIgnored(doc, p); err != nil {
+			return "", err
+		}
+	}
+	canonical, err := jsondoc.Canonical(doc)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(canonical)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// deleteIgnored removes the value at path from doc, along with any parent
+// objects the removal leaves empty, so a local-only key such as env.LOCAL does
+// not leave an "env" object behind that the other file lacks.
+func deleteIgnored(doc any, path jsondoc.Path) (any, error) {
+	if len(path) == 0 {
+		return doc, nil
+	}
+	doc, removed, err := jsondoc.Delete(doc, path)
+	if err != nil || !removed {
+		return doc, err
+	}
+	for i := len(path) - 1; i > 0; i-- {
+		parent, _ := jsondoc.Get(doc, path[:i])
+		if obj, ok := parent.(*jsondoc.Object); !ok || obj.Len() > 0 {
+			break
+		}
+		if doc, _, err = jsondoc.Delete(doc, path[:i]); err != nil {
+			return nil, err
+		}
+	}
+	return doc, nil
+}
+
 // BackupFile creates a content-addressed backup of the file at path.
 //
 // The backup uses SHA-256 hash as filename, enabling deduplication:
@@ -92,76 +172,443 @@ func (s *Service) CalculateHash(path string) (string, error) {
 //
 //	<sha256-hash>.json or empty.json
 //
+// The hash always covers the uncompressed content; when compression is
+// enabled the file holds a gzip stream that ReadBackup decodes transparently.
+//
+// New blobs are written to a temp file, synced, read back and verified against
+// their name before being renamed into place. An existing blob is only reused
+// for deduplication after its content has been checked; a corrupted one is
+// replaced.
+//
 // This approach ensures:
 //   - Multiple backups of identical content don't waste space
 //   - The prune command can use mtime to determine backup age
 //   - Each unique settings version is preserved exactly once
-func (s *Service) BackupFile(path string) (err error) {
-	// Note: CalculateHash already validates path safety via ValidatePathSafety
-	hash, err := s.CalculateHash(path)
-	if err != nil {
-		return err
-	}
-	if hash == "" {
-		// File doesn't exist - nothing to backup
-		return nil
-	}
+func (s *Service) BackupFile(path string) error {
+	_, err := s.backupFile(path)
+	return err
+}
 
-	source, err := s.storage.FileSystem().Open(path)
+// backupFile implements BackupFile and returns the blob hash, or an empty
+// string when the file does not exist.
+//
+// The hash is computed from the same bytes that get stored, so the blob name
+// always matches its content even if the source changes concurrently.
+func (s *Service) backupFile(path string) (string, error) {
+	if err := s.storage.ValidatePathSafety(path); err != nil {
+		return "", fmt.Errorf("path validation failed: %w", err)
+	}
+	plain, err := s.storage.ReadFile(path)
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
-			return nil
+			// File doesn't exist - nothing to backup
+			return "", nil
 		}
-		return fmt.Errorf("failed to open file for backup: %w", err)
+		return "", fmt.Errorf("failed to read file for backup: %w", err)
 	}
-	defer func() {
-		if cerr := source.Close(); cerr != nil && err == nil {
-			err = fmt.Errorf("failed to close source: %w", cerr)
-		}
-	}()
+	if len(plain) == 0 {
+		s.logger.Warn("empty file detected during hash calculation",
+			"path", path,
+			"operation", "hash")
+	}
+	hash := contentID(plain)
 
-	backupPath := filepath.Join(s.backupDir, hash+".json")
 	now := s.now()
-	if _, err := s.storage.Stat(backupPath); err == nil {
+	stored, backupPath, err := s.readBlob(hash)
+	switch {
+	case err == nil && s.blobIntact(hash, stored):
 		// Backup already exists - just update timestamp for deduplication
 		if err := s.storage.Chtimes(backupPath, now, now); err != nil {
-			return fmt.Errorf("failed to update backup timestamp: %w", err)
+			return "", fmt.Errorf("failed to update backup timestamp: %w", err)
 		}
 		s.logger.Debug("backup already exists, updated timestamp",
 			"path", path,
 			"hash", hash,
 			"backup_path", backupPath)
+		return hash, nil
+	case err == nil:
+		s.logger.Warn("replacing corrupted backup", "hash", hash, "backup_path", backupPath)
+	case !errors.Is(err, os.ErrNotExist):
+		return "", fmt.Errorf("failed to read existing backup: %w", err)
+	}
+
+	encoded, err := s.sealBlob(hash, plain)
+	if err != nil {
+		return "", err
+	}
+	if err := s.writeBlob(hash, encoded, now, s.keyring); err != nil {
+		return "", err
+	}
+	s.mirrorBlob(hash, encoded, now)
+
+	s.logger.Info("backup created",
+		"path", path,
+		"hash", hash,
+		"backup_path", backupPath,
+		"compression", string(s.compression),
+		"encrypted", s.keyring != nil)
+
+	return hash, nil
+}
+
+// ReadBackup returns the plain content of the backup with the given hash.
+//
+// Compressed, encrypted and legacy plain blobs are all accepted; callers never
+// need to know how a blob was encoded on disk.
+func (s *Service) ReadBackup(hash string) ([]byte, error) {
+	if !blobIDPattern.MatchString(hash) {
+		return nil, fmt.Errorf("invalid backup hash %q", hash)
+	}
+	stored, _, err := s.readBlob(hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup %s: %w", hash, err)
+	}
+	return s.openBlob(hash, stored)
+}
+
+// Recompress rewrites every stored blob using the given compression.
+//
+// Each blob is decoded, checked against the hash in its name, re-encoded and
+// atomically replaced via a temp file and rename, so an interrupted migration
+// leaves every blob either in its old or its new encoding. Modification times
+// are preserved because pruning relies on them. Blobs whose content does not
+// match their name are skipped and logged rather than rewritten.
+//
+// Rewritten blobs are encrypted when a keyring is configured, and copied to
+// the mirror when one is set.
+//
+// Returns the number of blobs rewritten.
+func (s *Service) Recompress(c Compression) (int, error) {
+	rewritten := 0
+	err := s.forEachBlob(func(id, path string, info os.FileInfo) error {
+		stored, err := s.storage.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read backup %s: %w", id, err)
+		}
+		inner, plain, ok := s.verifiedContent(id, stored)
+		if !ok || compressionOf(inner) == c {
+			return nil
+		}
+		encoded, err := encode(plain, c)
+		if err != nil {
+			return err
+		}
+		if s.keyring != nil {
+			if encoded, err = s.encrypt(id, encoded, s.keyring); err != nil {
+				return err
+			}
+		}
+		if err := s.writeBlob(id, encoded, info.ModTime(), s.keyring); err != nil {
+			return err
+		}
+		s.mirrorBlob(id, encoded, info.ModTime())
+		rewritten++
 		return nil
-	} else if !errors.Is(err, os.ErrNotExist) {
-		return fmt.Errorf("failed to stat backup: %w", err)
+	})
+	return rewritten, err
+}
+
+// Rekey re-encrypts every stored blob with newKey, keeping each blob's
+// compression. A nil newKey decrypts the store back to unencrypted blobs.
+//
+// Every blob is first read with the current keyring and verified against its
+// hash. If any blob cannot be opened, nothing is rewritten and an error
+// wrapping ErrUnreadableBackups is returned, so the store is never split
+// across two keys. Otherwise each blob is atomically replaced with its
+// modification time preserved and copied to the mirror, and newKey becomes
+// the service keyring.
+//
+// Returns the number of blobs rewritten and the number that could not be
+// opened.
+func (s *Service) Rekey(newKey *Keyring) (rewritten, unreadable int, err error) {
+	err = s.forEachBlob(func(id, path string, info os.FileInfo) error {
+		stored, err := s.storage.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read backup %s: %w", id, err)
+		}
+		if !isEncrypted(stored) && newKey == nil {
+			return nil
+		}
+		if _, _, ok := s.verifiedContent(id, stored); !ok {
+			unreadable++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, unreadable, err
+	}
+	if unreadable > 0 {
+		return 0, unreadable, fmt.Errorf("%w: %d backup(s) cannot be opened with the current key; nothing was rewritten", ErrUnreadableBackups, unreadable)
 	}
 
-	dst, err := s.storage.FileSystem().OpenFile(backupPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if newKey != nil && newKey.mode == keyModePassphrase {
+		salt, err := newSalt()
+		if err != nil {
+			return 0, 0, err
+		}
+		newKey.salt = salt
+	}
+	err = s.forEachBlob(func(id, path string, info os.FileInfo) error {
+		stored, err := s.storage.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read backup %s: %w", id, err)
+		}
+		if !isEncrypted(stored) && newKey == nil {
+			return nil
+		}
+		inner, _, ok := s.verifiedContent(id, stored)
+		if !ok {
+			return fmt.Errorf("%w: backup %s changed during rekey", ErrUnreadableBackups, id)
+		}
+		encoded := inner
+		if newKey != nil {
+			if encoded, err = s.encrypt(id, inner, newKey); err != nil {
+				return err
+			}
+		}
+		if err := s.writeBlob(id, encoded, info.ModTime(), newKey); err != nil {
+			return err
+		}
+		s.mirrorBlob(id, encoded, info.ModTime())
+		rewritten++
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("failed to create backup: %w", err)
+		return rewritten, 0, err
 	}
 
-	_, copyErr := io.Copy(dst, source)
-	closeErr := dst.Close()
+	if newKey != nil && newKey.mode == keyModePassphrase {
+		if err := s.writeSalt(newKey.salt); err != nil {
+			return rewritten, 0, err
+		}
+	}
+	s.keyring = newKey
+	return rewritten, 0, nil
+}
 
-	if copyErr != nil {
-		s.storage.Remove(backupPath)
-		return fmt.Errorf("failed to copy backup: %w", copyErr)
+// Contents calls fn with the decoded content of every readable blob. Blobs
+// that cannot be read or decoded are logged and skipped.
+func (s *Service) Contents(fn func(hash string, content []byte) error) error {
+	return s.forEachBlob(func(id, path string, info os.FileInfo) error {
+		stored, err := s.storage.ReadFile(path)
+		if err != nil {
+			s.logger.Warn("skipping unreadable backup", "hash", id, "error", err)
+			return nil
+		}
+		if _, plain, ok := s.verifiedContent(id, stored); ok {
+			return fn(id, plain)
+		}
+		return nil
+	})
+}
+
+// forEachBlob calls fn for every blob file in the backup directory, in
+// either layout.
+func (s *Service) forEachBlob(fn func(id, path string, info os.FileInfo) error) error {
+	return s.walkBackupFiles(func(id, path string, info os.FileInfo) error {
+		if !blobIDPattern.MatchString(id) {
+			return nil
+		}
+		return fn(id, path, info)
+	})
+}
+
+// walkBackupFiles calls fn for every file in the backup directory and its
+// shard directories, using the file info from ReadDir so no extra stat is
+// needed. id is the blob name the file stands for, or "" when the file name
+// does not end in .json. The metadata directory is never visited.
+func (s *Service) walkBackupFiles(fn func(id, path string, info os.FileInfo) error) error {
+	entries, err := s.storage.ReadDir(s.backupDir)
+	if err != nil {
+		return fmt.Errorf("failed to read backup directory: %w", err)
 	}
-	if closeErr != nil {
-		s.storage.Remove(backupPath)
-		return fmt.Errorf("failed to close backup: %w", closeErr)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			if err := fn(fileBlobID("", entry.Name()), filepath.Join(s.backupDir, entry.Name()), entry); err != nil {
+				return err
+			}
+			continue
+		}
+		if !shardDirPattern.MatchString(entry.Name()) {
+			continue
+		}
+		shardDir := filepath.Join(s.backupDir, entry.Name())
+		files, err := s.storage.ReadDir(shardDir)
+		if err != nil {
+			return fmt.Errorf("failed to read backup shard %s: %w", entry.Name(), err)
+		}
+		for _, file := range files {
+			if file.IsDir() {
+				continue
+			}
+			if err := fn(fileBlobID(entry.Name(), file.Name()), filepath.Join(shardDir, file.Name()), file); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// fileBlobID derives the blob name of a file from its shard and file name.
+func fileBlobID(shard, name string) string {
+	if !strings.HasSuffix(name, ".json") {
+		return ""
+	}
+	return shard + strings.TrimSuffix(name, ".json")
+}
+
+// verifiedContent decrypts and decodes a stored blob, returning both the
+// decrypted (possibly compressed) bytes and the plain content. Blobs that
+// cannot be read or whose content does not match id are logged and reported
+// as not ok, so migrations never propagate corruption.
+func (s *Service) verifiedContent(id string, stored []byte) (inner, plain []byte, ok bool) {
+	inner, err := s.decrypt(id, stored)
+	if err == nil {
+		plain, err = decode(inner)
+	}
+	if err != nil {
+		s.logger.Warn("skipping unreadable backup", "hash", id, "error", err)
+		return nil, nil, false
+	}
+	if contentID(plain) != id {
+		s.logger.Warn("skipping backup whose content does not match its hash", "hash", id)
+		return nil, nil, false
 	}
+	return inner, plain, true
+}
 
-	if err := s.storage.Chtimes(backupPath, now, now); err != nil {
+// writeBlob atomically writes the stored form of the blob named id and sets
+// its modification time. The bytes that reached the disk are opened with k and
+// checked against id before the rename, so a blob can never be stored under a
+// name its content does not match.
+func (s *Service) writeBlob(id string, stored []byte, mtime time.Time, k *Keyring) error {
+	blobPath := s.blobPath(id)
+	err := s.storage.WriteFileVerified(blobPath, stored, func(written []byte) error {
+		plain, err := s.openWith(id, written, k)
+		if err != nil {
+			return err
+		}
+		if contentID(plain) != id {
+			return fmt.Errorf("written backup does not match hash %s", id)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write backup %s: %w", id, err)
+	}
+	if err := s.storage.Chtimes(blobPath, mtime, mtime); err != nil {
 		return fmt.Errorf("failed to update backup timestamp: %w", err)
 	}
+	// Drop a copy left in the other layout so each blob exists once
+	if other := s.otherBlobPath(id); other != "" {
+		if err := s.storage.Remove(other); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove old backup copy: %w", err)
+		}
+	}
+	return nil
+}
 
-	s.logger.Info("backup created",
-		"path", path,
-		"hash", hash,
-		"backup_path", backupPath)
+// blobIntact reports whether an existing stored blob really holds the content
+// named by id. Encrypted blobs that cannot be opened for lack of a usable key
+// are trusted, since they cannot be checked and rewriting them would either
+// fail or downgrade them to plaintext.
+func (s *Service) blobIntact(id string, stored []byte) bool {
+	plain, err := s.openWith(id, stored, s.keyring)
+	if err != nil {
+		return isEncrypted(stored) && (s.keyring == nil || s.keyring.err != nil)
+	}
+	return contentID(plain) == id
+}
+
+// sealBlob converts plain content into the stored form: compressed with the
+// configured compression, then encrypted when a keyring is set.
+func (s *Service) sealBlob(id string, plain []byte) ([]byte, error) {
+	encoded, err := encode(plain, s.compression)
+	if err != nil {
+		return nil, err
+	}
+	if s.keyring == nil {
+		return encoded, nil
+	}
+	return s.encrypt(id, encoded, s.keyring)
+}
+
+// openBlob reverses sealBlob for any supported stored form.
+func (s *Service) openBlob(id string, stored []byte) ([]byte, error) {
+	return s.openWith(id, stored, s.keyring)
+}
+
+// openWith is openBlob using keyring k for encrypted blobs.
+func (s *Service) openWith(id string, stored []byte, k *Keyring) ([]byte, error) {
+	inner := stored
+	if isEncrypted(stored) {
+		var err error
+		if inner, err = k.open(id, stored); err != nil {
+			return nil, fmt.Errorf("backup %s: %w", id, err)
+		}
+	}
+	return decode(inner)
+}
+
+func (s *Service) encrypt(id string, data []byte, k *Keyring) ([]byte, error) {
+	if k.err == nil && k.mode == keyModePassphrase && k.salt == nil {
+		salt, err := s.readOrCreateSalt()
+		if err != nil {
+			return nil, err
+		}
+		k.salt = salt
+	}
+	sealed, err := k.seal(id, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt backup: %w", err)
+	}
+	return sealed, nil
+}
+
+func (s *Service) decrypt(id string, stored []byte) ([]byte, error) {
+	if !isEncrypted(stored) {
+		return stored, nil
+	}
+	plain, err := s.keyring.open(id, stored)
+	if err != nil {
+		return nil, fmt.Errorf("backup %s: %w", id, err)
+	}
+	return plain, nil
+}
+
+// saltPath holds the scrypt salt shared by passphrase-encrypted blobs. It is
+// only consulted when writing; every blob header carries its own salt.
+func (s *Service) saltPath() string {
+	return filepath.Join(s.backupDir, metaDirName, "encryption-salt")
+}
+
+func (s *Service) readOrCreateSalt() ([]byte, error) {
+	content, err := s.storage.ReadFile(s.saltPath())
+	if err == nil {
+		salt, decErr := base64.StdEncoding.DecodeString(strings.TrimSpace(string(content)))
+		if decErr == nil && len(salt) == saltSize {
+			return salt, nil
+		}
+		return nil, fmt.Errorf("invalid encryption salt file %s", s.saltPath())
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read encryption salt: %w", err)
+	}
+	salt, err := newSalt()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.writeSalt(salt); err != nil {
+		return nil, err
+	}
+	return salt, nil
+}
 
+func (s *Service) writeSalt(salt []byte) error {
+	data := []byte(base64.StdEncoding.EncodeToString(salt) + "\n")
+	if err := s.storage.WriteFileAtomic(s.saltPath(), data); err != nil {
+		return fmt.Errorf("failed to write encryption salt: %w", err)
+	}
 	return nil
 }
 
@@ -169,31 +616,37 @@ func (s *Service) BackupFile(path string) (err error) {
 //
 // The function uses modification time (mtime) to determine backup age. Since
 // content-addressed backups update mtime on each backup event, this effectively
-// prunes backups that haven't been referenced recently.
+// prunes backups that haven't been referenced recently. Snapshots that referenced
+// a deleted backup are dropped from the index, since they can no longer be
+// restored completely. Pinned backups are never deleted.
 //
 // Returns the number of backups deleted and any error encountered.
 func (s *Service) PruneBackups(olderThan time.Duration) (int, error) {
-	entries, err := s.storage.ReadDir(s.backupDir)
+	idx, err := s.loadIndex()
 	if err != nil {
-		return 0, fmt.Errorf("failed to read backup directory: %w", err)
+		return 0, err
 	}
 	cutoff := s.now().Add(-olderThan)
 	deleted := 0
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
+	removed := make(map[string]bool)
+	err = s.walkBackupFiles(func(id, path string, info os.FileInfo) error {
+		if idx.Blobs[id].Pinned || !info.ModTime().Before(cutoff) {
+			return nil
 		}
-		path := filepath.Join(s.backupDir, entry.Name())
-		info, err := s.storage.Stat(path)
-		if err != nil {
-			return deleted, fmt.Errorf("failed to stat backup: %w", err)
+		if err := s.storage.Remove(path); err != nil {
+			return fmt.Errorf("failed to delete backup: %w", err)
 		}
-		if info.ModTime().Before(cutoff) {
-			if err := s.storage.Remove(path); err != nil {
-				return deleted, fmt.Errorf("failed to delete backup: %w", err)
-			}
-			deleted++
+		if id != "" {
+			removed[id] = true
 		}
+		deleted++
+		return nil
+	})
+	if err != nil {
+		return deleted, err
+	}
+	if err := s.forgetBlobs(removed); err != nil {
+		return deleted, err
 	}
 	return deleted, nil
 }
@@ -3,7 +3,8 @@ package backup
 // Tests for content-addressed backup with SHA-256 deduplication.
 //
 // Focus: CalculateHash (SHA-256, empty file handling), BackupFile (deduplication),
-// PruneBackups (time-based cleanup).
+// PruneBackups (time-based cleanup), ReadBackup/Recompress (transparent compression),
+// verified blob writes and repair of corrupted blobs.
 
 import (
 	"errors"
@@ -14,6 +15,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/OpenGG/claude-code-switch-settings/internal/ccs/jsondoc"
 	"github.com/OpenGG/claude-code-switch-settings/internal/ccs/storage"
 	"github.com/spf13/afero"
 )
@@ -80,6 +82,54 @@ func TestCalculateHash_MissingFile(t *testing.T) {
 	}
 }
 
+func TestFingerprint_IgnoresFormattingButNotMeaning(t *testing.T) {
+	svc, fs := newTestService(t)
+	files := map[string]string{
+		"/a.json":   `{"model":"opus","env":{"A":"1"}}`,
+		"/b.json":   "{\n  \"env\": {\"A\": \"1\"},\n  \"model\": \"opus\"\n}\n",
+		"/c.json":   `{"model":"sonnet","env":{"A":"1"}}`,
+		"/bad.json": `{"model":`,
+	}
+	for path, content := range files {
+		if err := afero.WriteFile(fs, path, []byte(content), 0o644); err != nil {
+			t.Fatalf("setup: %v", err)
+		}
+	}
+	fingerprints := make(map[string]string)
+	for path := range files {
+		fp, err := svc.Fingerprint(path)
+		if err != nil {
+			t.Fatalf("Fingerprint(%s): %v", path, err)
+		}
+		fingerprints[path] = fp
+	}
+	if fingerprints["/a.json"] != fingerprints["/b.json"] {
+		t.Error("expected reformatted file to share a fingerprint")
+	}
+	if fingerprints["/a.json"] == fingerprints["/c.json"] {
+		t.Error("expected changed value to change the fingerprint")
+	}
+	byteHash, _ := svc.CalculateHash("/bad.json")
+	if fingerprints["/bad.json"] != byteHash {
+		t.Error("expected invalid JSON to fall back to the byte hash")
+	}
+	envPath, _ := jsondoc.ParsePath("env")
+	if fp, _ := svc.Fingerprint("/a.json", envPath); fp == fingerprints["/a.json"] {
+		t.Error("expected ignored key to change the fingerprint")
+	}
+	if err := afero.WriteFile(fs, "/d.json", []byte(`{"model":"opus","env":{"B":"2"}}`), 0o644); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	fpA, _ := svc.Fingerprint("/a.json", envPath)
+	fpD, _ := svc.Fingerprint("/d.json", envPath)
+	if fpA != fpD {
+		t.Error("expected files differing only in ignored keys to match")
+	}
+	if fp, err := svc.Fingerprint("/missing.json"); err != nil || fp != "" {
+		t.Errorf("expected empty fingerprint for missing file, got %q, %v", fp, err)
+	}
+}
+
 func TestCalculateHash_Deterministic(t *testing.T) {
 	svc, fs := newTestService(t)
 
@@ -387,3 +437,207 @@ func TestPruneBackups_ErrorOnNonExistentDirectory(t *testing.T) {
 		t.Errorf("expected ErrNotExist in chain, got: %v", err)
 	}
 }
+
+func TestBackupFile_GzipCompression(t *testing.T) {
+	svc, fs := newTestService(t)
+	svc.SetCompression(CompressionGzip)
+
+	original := []byte(`{"permissions": {"allow": ["Read", "Read", "Read"]}}`)
+	path := "/test/file.json"
+	if err := afero.WriteFile(fs, path, original, 0o644); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+
+	if err := svc.BackupFile(path); err != nil {
+		t.Fatalf("BackupFile failed: %v", err)
+	}
+
+	// Name is still the hash of the uncompressed content
+	hash, err := svc.CalculateHash(path)
+	if err != nil {
+		t.Fatalf("calculate hash: %v", err)
+	}
+	stored, err := afero.ReadFile(fs, filepath.Join(svc.BackupDir(), hash+".json"))
+	if err != nil {
+		t.Fatalf("read backup: %v", err)
+	}
+	if compressionOf(stored) != CompressionGzip {
+		t.Fatalf("expected gzip-encoded blob, got %q", string(stored))
+	}
+
+	plain, err := svc.ReadBackup(hash)
+	if err != nil {
+		t.Fatalf("ReadBackup failed: %v", err)
+	}
+	if string(plain) != string(original) {
+		t.Errorf("decoded content mismatch: got %q", string(plain))
+	}
+}
+
+func TestReadBackup_LegacyPlainBlob(t *testing.T) {
+	svc, fs := newTestService(t)
+
+	content := []byte(`{"model": "opus"}`)
+	hash := contentID(content)
+	if err := afero.WriteFile(fs, filepath.Join(svc.BackupDir(), hash+".json"), content, 0o600); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+
+	plain, err := svc.ReadBackup(hash)
+	if err != nil {
+		t.Fatalf("ReadBackup failed: %v", err)
+	}
+	if string(plain) != string(content) {
+		t.Errorf("content mismatch: got %q", string(plain))
+	}
+}
+
+func TestReadBackup_RejectsInvalidHash(t *testing.T) {
+	svc, _ := newTestService(t)
+
+	for _, hash := range []string{"", "../settings", "ABC", "index"} {
+		if _, err := svc.ReadBackup(hash); err == nil {
+			t.Errorf("expected error for hash %q", hash)
+		}
+	}
+}
+
+func TestRecompress_MigratesInPlaceAndPreservesMtime(t *testing.T) {
+	svc, fs := newTestService(t)
+
+	content := []byte(`{"env": {"A": "1"}}`)
+	hash := contentID(content)
+	blobPath := filepath.Join(svc.BackupDir(), hash+".json")
+	mtime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := afero.WriteFile(fs, blobPath, content, 0o600); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	if err := fs.Chtimes(blobPath, mtime, mtime); err != nil {
+		t.Fatalf("set time: %v", err)
+	}
+
+	rewritten, err := svc.Recompress(CompressionGzip)
+	if err != nil {
+		t.Fatalf("Recompress failed: %v", err)
+	}
+	if rewritten != 1 {
+		t.Errorf("expected 1 rewritten, got %d", rewritten)
+	}
+
+	stored, err := afero.ReadFile(fs, blobPath)
+	if err != nil {
+		t.Fatalf("read blob: %v", err)
+	}
+	if compressionOf(stored) != CompressionGzip {
+		t.Error("blob should be gzip-encoded after migration")
+	}
+	info, err := fs.Stat(blobPath)
+	if err != nil {
+		t.Fatalf("stat blob: %v", err)
+	}
+	if !info.ModTime().Equal(mtime) {
+		t.Errorf("mtime should be preserved, got %v", info.ModTime())
+	}
+
+	// Running again is a no-op
+	rewritten, err = svc.Recompress(CompressionGzip)
+	if err != nil {
+		t.Fatalf("second Recompress failed: %v", err)
+	}
+	if rewritten != 0 {
+		t.Errorf("expected 0 rewritten on second run, got %d", rewritten)
+	}
+}
+
+func TestRecompress_SkipsCorruptedBlob(t *testing.T) {
+	svc, fs := newTestService(t)
+
+	// Name claims a hash the content does not have
+	blobPath := filepath.Join(svc.BackupDir(), contentID([]byte("original"))+".json")
+	if err := afero.WriteFile(fs, blobPath, []byte("truncated"), 0o600); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+
+	rewritten, err := svc.Recompress(CompressionGzip)
+	if err != nil {
+		t.Fatalf("Recompress failed: %v", err)
+	}
+	if rewritten != 0 {
+		t.Errorf("corrupted blob should not be rewritten, got %d", rewritten)
+	}
+	stored, err := afero.ReadFile(fs, blobPath)
+	if err != nil {
+		t.Fatalf("read blob: %v", err)
+	}
+	if string(stored) != "truncated" {
+		t.Errorf("corrupted blob should be left untouched, got %q", string(stored))
+	}
+}
+
+func TestBackupFile_ReplacesCorruptedExistingBlob(t *testing.T) {
+	svc, fs := newTestService(t)
+
+	content := []byte(`{"model": "opus"}`)
+	path := "/test/file.json"
+	if err := afero.WriteFile(fs, path, content, 0o644); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	hash, err := svc.CalculateHash(path)
+	if err != nil {
+		t.Fatalf("calculate hash: %v", err)
+	}
+	// Simulate a crash that left a truncated blob under the right name
+	blobPath := filepath.Join(svc.BackupDir(), hash+".json")
+	if err := afero.WriteFile(fs, blobPath, content[:5], 0o600); err != nil {
+		t.Fatalf("setup corrupted blob: %v", err)
+	}
+
+	if err := svc.BackupFile(path); err != nil {
+		t.Fatalf("BackupFile failed: %v", err)
+	}
+	plain, err := svc.ReadBackup(hash)
+	if err != nil {
+		t.Fatalf("ReadBackup failed: %v", err)
+	}
+	if string(plain) != string(content) {
+		t.Errorf("corrupted blob should be repaired, got %q", string(plain))
+	}
+	if exists, _ := afero.Exists(fs, blobPath+".tmp"); exists {
+		t.Error("temp file should not remain after backup")
+	}
+}
+
+func TestBackupFile_TrustsEncryptedBlobWithoutKey(t *testing.T) {
+	svc, fs := newTestService(t)
+	keyFile, err := GenerateKeyFile()
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	keyring, err := NewKeyFileKeyring(keyFile)
+	if err != nil {
+		t.Fatalf("keyring: %v", err)
+	}
+	svc.SetKeyring(keyring)
+
+	path := "/test/file.json"
+	if err := afero.WriteFile(fs, path, []byte(`{"a":1}`), 0o644); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	if err := svc.BackupFile(path); err != nil {
+		t.Fatalf("BackupFile failed: %v", err)
+	}
+
+	// Without the key the blob cannot be verified, but must not be downgraded
+	svc.SetKeyring(nil)
+	if err := svc.BackupFile(path); err != nil {
+		t.Fatalf("BackupFile without key failed: %v", err)
+	}
+	hash, _ := svc.CalculateHash(path)
+	stored, err := afero.ReadFile(fs, filepath.Join(svc.BackupDir(), hash+".json"))
+	if err != nil {
+		t.Fatalf("read blob: %v", err)
+	}
+	if !isEncrypted(stored) {
+		t.Error("encrypted blob should be kept as is")
+	}
+}
@@ -0,0 +1,121 @@
+package ccs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/OpenGG/claude-code-switch-settings/internal/ccs/jsondoc"
+)
+
+// BlameResult describes when the current value of a settings key appeared.
+type BlameResult struct {
+	// File is the settings file that was examined.
+	File string
+	// Key is the key path in dotted form.
+	Key string
+	// Value is the current value; Present is false when the key is absent.
+	Value   any
+	Present bool
+	// Since is the oldest snapshot of the unbroken run of backups that already
+	// held the current value. It is nil when only the live file holds it.
+	Since *Snapshot
+	// ChangedAfter is the newest snapshot holding a different value. The change
+	// was made by its operation or by an edit after it. It is nil when every
+	// readable backup holds the current value.
+	ChangedAfter *Snapshot
+	// Previous is the value the current one replaced; PreviousPresent is false
+	// when the key was absent before.
+	Previous        any
+	PreviousPresent bool
+	// Versions counts the backups examined; Skipped counts those that could
+	// not be read or parsed.
+	Versions int
+	Skipped  int
+}
+
+// Blame walks the backup history of settings.json, or of the named profile
+// when profile is non-empty, and reports when the current value of the key at
+// keyPath first appeared and what it replaced.
+//
+// keyPath is a dotted path such as "permissions.allow[0]" or a JSON pointer
+// such as "/permissions/allow/0". A missing key is a value too, so blaming a
+// removed key reports when it disappeared.
+func (m *Manager) Blame(keyPath, profile string) (BlameResult, error) {
+	if err := m.InitInfra(); err != nil {
+		return BlameResult{}, err
+	}
+	path, err := jsondoc.ParsePath(keyPath)
+	if err != nil {
+		return BlameResult{}, err
+	}
+	file := m.paths.ActiveSettingsPath()
+	if profile != "" {
+		normalized, err := m.normalizeSettingsName(profile)
+		if err != nil {
+			return BlameResult{}, err
+		}
+		file = m.paths.StoredSettingsPath(normalized)
+	}
+
+	result := BlameResult{File: file, Key: path.String()}
+	data, err := m.storage.ReadFile(file)
+	switch {
+	case err == nil:
+		doc, err := jsondoc.Parse(data)
+		if err != nil {
+			return BlameResult{}, fmt.Errorf("%s: %w", file, err)
+		}
+		result.Value, result.Present = jsondoc.Get(doc, path)
+	case errors.Is(err, os.ErrNotExist):
+		if profile != "" {
+			return BlameResult{}, fmt.Errorf("settings '%s' not found", profile)
+		}
+	default:
+		return BlameResult{}, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+
+	versions, err := m.backup.History(file)
+	if err != nil {
+		return BlameResult{}, err
+	}
+	for i := len(versions) - 1; i >= 0; i-- {
+		v := versions[i]
+		value, present, ok := m.versionValue(v.Hash, path)
+		result.Versions++
+		if !ok {
+			result.Skipped++
+			continue
+		}
+		snap := v.Snapshot
+		if present == result.Present && (!present || jsondoc.Equal(value, result.Value)) {
+			result.Since = &snap
+			continue
+		}
+		result.ChangedAfter = &snap
+		result.Previous, result.PreviousPresent = value, present
+		break
+	}
+	return result, nil
+}
+
+// versionValue looks up path in the backup blob hash. An empty hash is a file
+// that did not exist, so the key is absent. ok is false when the blob cannot
+// be read or parsed.
+func (m *Manager) versionValue(hash string, path jsondoc.Path) (value any, present, ok bool) {
+	if hash == "" {
+		return nil, false, true
+	}
+	data, err := m.backup.ReadBackup(hash)
+	if err != nil {
+		m.logger.Warn("skipping unreadable backup", "hash", hash, "error", err)
+		return nil, false, false
+	}
+	doc, err := jsondoc.Parse(data)
+	if err != nil {
+		m.logger.Warn("skipping backup that is not valid JSON", "hash", hash, "error", err)
+		return nil, false, false
+	}
+	value, present = jsondoc.Get(doc, path)
+	return value, present, true
+}
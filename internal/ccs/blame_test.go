@@ -0,0 +1,79 @@
+package ccs
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+func TestBlameReportsWhenValueFirstAppeared(t *testing.T) {
+	mgr := newTestManager(t)
+	fs := mgr.FileSystem()
+	store := mgr.SettingsStoreDir()
+	for name, content := range map[string]string{"work": `{"model": "opus"}`, "home": `{"model": "sonnet", "env": {}}`} {
+		if err := afero.WriteFile(fs, filepath.Join(store, name+".json"), []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	if err := afero.WriteFile(fs, mgr.ActiveSettingsPath(), []byte(`{"model": "haiku"}`), 0o644); err != nil {
+		t.Fatalf("write active: %v", err)
+	}
+
+	clock := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	mgr.SetNow(func() time.Time { return clock })
+	for _, name := range []string{"work", "home", "home"} {
+		clock = clock.Add(time.Hour)
+		if err := mgr.Use(name); err != nil {
+			t.Fatalf("use %s: %v", name, err)
+		}
+	}
+
+	result, err := mgr.Blame("model", "")
+	if err != nil {
+		t.Fatalf("blame: %v", err)
+	}
+	if !result.Present || result.Value != "sonnet" {
+		t.Fatalf("unexpected current value: %+v", result)
+	}
+	if result.Since == nil || !result.Since.Time.Equal(time.Date(2024, 1, 1, 15, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected first-seen snapshot: %+v", result.Since)
+	}
+	if result.ChangedAfter == nil || result.ChangedAfter.Operation != "use home" {
+		t.Fatalf("unexpected change snapshot: %+v", result.ChangedAfter)
+	}
+	if !result.PreviousPresent || result.Previous != "opus" {
+		t.Fatalf("unexpected previous value: %+v", result)
+	}
+
+	// A key that did not exist before is reported as newly added
+	result, err = mgr.Blame("/env", "")
+	if err != nil {
+		t.Fatalf("blame env: %v", err)
+	}
+	if !result.Present || result.PreviousPresent || result.ChangedAfter == nil {
+		t.Fatalf("expected env to replace an absent key: %+v", result)
+	}
+}
+
+func TestBlameProfileWithoutHistory(t *testing.T) {
+	mgr := newTestManager(t)
+	if err := afero.WriteFile(mgr.FileSystem(), filepath.Join(mgr.SettingsStoreDir(), "work.json"), []byte(`{"model": "opus"}`), 0o644); err != nil {
+		t.Fatalf("write profile: %v", err)
+	}
+
+	result, err := mgr.Blame("model", "work")
+	if err != nil {
+		t.Fatalf("blame: %v", err)
+	}
+	if result.Since != nil || result.ChangedAfter != nil || result.Versions != 0 {
+		t.Fatalf("expected no history: %+v", result)
+	}
+	if _, err := mgr.Blame("model", "missing"); err == nil {
+		t.Fatal("expected error for unknown profile")
+	}
+	if _, err := mgr.Blame("model..x", "work"); err == nil {
+		t.Fatal("expected error for invalid path")
+	}
+}
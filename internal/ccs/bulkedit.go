@@ -0,0 +1,178 @@
+package ccs
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/OpenGG/claude-code-switch-settings/internal/ccs/jsondoc"
+)
+
+// BulkEditOptions selects the stored profiles changed by BulkSetKey and
+// BulkUnsetKey. Exactly one of All, Profiles and Tag must be set.
+type BulkEditOptions struct {
+	// All selects every stored profile.
+	All bool
+	// Profiles selects profiles by name.
+	Profiles []string
+	// Tag selects the profiles listed under the tag in the config file.
+	Tag string
+	// NoValidate skips the settings schema check.
+	NoValidate bool
+}
+
+// parseTags validates the tags configuration and normalizes profile names.
+func (m *Manager) parseTags(tags map[string][]string) (map[string][]string, error) {
+	parsed := make(map[string][]string, len(tags))
+	for tag, names := range tags {
+		if strings.TrimSpace(tag) == "" {
+			return nil, errors.New("tag name cannot be empty")
+		}
+		for _, name := range names {
+			normalized, err := m.normalizeSettingsName(name)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", tag, err)
+			}
+			parsed[tag] = append(parsed[tag], normalized)
+		}
+	}
+	return parsed, nil
+}
+
+// selectProfiles resolves opts to a sorted list of distinct profile names.
+func (m *Manager) selectProfiles(opts BulkEditOptions) ([]string, error) {
+	selectors := 0
+	for _, set := range []bool{opts.All, len(opts.Profiles) > 0, opts.Tag != ""} {
+		if set {
+			selectors++
+		}
+	}
+	if selectors != 1 {
+		return nil, errors.New("select profiles with exactly one of all, a profile list or a tag")
+	}
+
+	var names []string
+	switch {
+	case opts.All:
+		stored, err := m.settings.ListStored()
+		if err != nil {
+			return nil, err
+		}
+		names = stored
+	case opts.Tag != "":
+		tagged, ok := m.tags[opts.Tag]
+		if !ok {
+			return nil, fmt.Errorf("unknown tag %q", opts.Tag)
+		}
+		names = tagged
+	default:
+		names = opts.Profiles
+	}
+
+	seen := make(map[string]bool, len(names))
+	selected := make([]string, 0, len(names))
+	for _, name := range names {
+		normalized, err := m.normalizeSettingsName(strings.TrimSpace(name))
+		if err != nil {
+			return nil, err
+		}
+		if !seen[normalized] {
+			seen[normalized] = true
+			selected = append(selected, normalized)
+		}
+	}
+	sort.Strings(selected)
+	if len(selected) == 0 {
+		return nil, errors.New("no stored profiles selected")
+	}
+	return selected, nil
+}
+
+// BulkSetKey stores value at keyPath in every selected profile as one
+// transaction. Every profile is read, edited and validated before anything is
+// written, then the changed profiles are captured in a single snapshot and
+// replaced atomically. If a write fails, the profiles already written are
+// restored to their previous content.
+//
+// The result holds one KeyEdit per selected profile, in name order; Changed
+// is false for profiles that already held the value.
+func (m *Manager) BulkSetKey(keyPath string, value any, opts BulkEditOptions) ([]KeyEdit, error) {
+	return m.bulkEditKey("set", keyPath, opts, func(doc any, path jsondoc.Path) (any, error) {
+		return jsondoc.Set(doc, path, jsondoc.Clone(value))
+	})
+}
+
+// BulkUnsetKey removes the value at keyPath from every selected profile like
+// BulkSetKey stores one.
+func (m *Manager) BulkUnsetKey(keyPath string, opts BulkEditOptions) ([]KeyEdit, error) {
+	return m.bulkEditKey("unset", keyPath, opts, func(doc any, path jsondoc.Path) (any, error) {
+		doc, _, err := jsondoc.Delete(doc, path)
+		return doc, err
+	})
+}
+
+func (m *Manager) bulkEditKey(op, keyPath string, opts BulkEditOptions, edit editFunc) ([]KeyEdit, error) {
+	if err := m.InitInfra(); err != nil {
+		return nil, err
+	}
+	path, err := jsondoc.ParsePath(keyPath)
+	if err != nil {
+		return nil, err
+	}
+	names, err := m.selectProfiles(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]KeyEdit, 0, len(names))
+	var pending []*keyEdit
+	var files []string
+	for _, name := range names {
+		sd, err := m.loadSettingsDoc(name)
+		if err != nil {
+			return nil, err
+		}
+		planned, err := m.planKeyEdit(sd, path, opts.NoValidate, edit)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, planned.result)
+		if planned.result.Changed {
+			pending = append(pending, planned)
+			files = append(files, sd.file)
+		}
+	}
+	if len(pending) == 0 {
+		return results, nil
+	}
+
+	label := fmt.Sprintf("%s %s in %d profiles", op, path.String(), len(pending))
+	if _, err := m.backup.Snapshot(label, files...); err != nil {
+		return nil, err
+	}
+	for i, planned := range pending {
+		if err := m.storage.WriteFileAtomic(planned.doc.file, planned.content); err != nil {
+			err = fmt.Errorf("failed to write %s: %w", planned.doc.file, err)
+			return nil, m.rollbackKeyEdits(pending[:i], err)
+		}
+	}
+	m.applyRetention()
+	return results, nil
+}
+
+// rollbackKeyEdits restores the original content of files written before
+// cause, and reports cause together with any file that could not be restored.
+func (m *Manager) rollbackKeyEdits(written []*keyEdit, cause error) error {
+	var failed []string
+	for _, planned := range written {
+		if err := m.storage.WriteFileAtomic(planned.doc.file, planned.doc.original); err != nil {
+			m.logger.Error("rollback failed", "file", planned.doc.file, "error", err)
+			failed = append(failed, planned.doc.file)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%w; rollback failed for %s, restore them from the latest snapshot", cause, strings.Join(failed, ", "))
+	}
+	return fmt.Errorf("%w; rolled back %d profile(s)", cause, len(written))
+}
@@ -0,0 +1,126 @@
+package ccs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+// failRenameFs fails renames onto target, so atomic writes of that file fail.
+type failRenameFs struct {
+	afero.Fs
+	target string
+}
+
+func (f *failRenameFs) Rename(oldname, newname string) error {
+	if newname == f.target {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: errors.New("disk full")}
+	}
+	return f.Fs.Rename(oldname, newname)
+}
+
+func writeProfiles(t *testing.T, mgr *Manager, profiles map[string]string) {
+	t.Helper()
+	for name, content := range profiles {
+		if err := afero.WriteFile(mgr.FileSystem(), filepath.Join(mgr.SettingsStoreDir(), name+".json"), []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+}
+
+func TestBulkSetKeyUpdatesTaggedProfiles(t *testing.T) {
+	mgr := newTestManager(t)
+	writeProfiles(t, mgr, map[string]string{
+		"corp":     `{"env": {"HTTPS_PROXY": "http://old"}}`,
+		"corp-eu":  `{"env": {"HTTPS_PROXY": "http://new"}}`,
+		"personal": `{"model": "opus"}`,
+	})
+	config := `{"tags": {"proxy": ["corp", "corp-eu", "lab"]}}`
+	if err := afero.WriteFile(mgr.FileSystem(), mgr.paths.ConfigPath(), []byte(config), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if err := mgr.LoadConfig(); err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+
+	// A tagged profile that does not exist aborts before anything is written.
+	if _, err := mgr.BulkSetKey("env.HTTPS_PROXY", "http://new", BulkEditOptions{Tag: "proxy"}); err == nil || !strings.Contains(err.Error(), "'lab' not found") {
+		t.Fatalf("expected missing profile error, got %v", err)
+	}
+	writeProfiles(t, mgr, map[string]string{"lab": `{}`})
+
+	edits, err := mgr.BulkSetKey("env.HTTPS_PROXY", "http://new", BulkEditOptions{Tag: "proxy"})
+	if err != nil {
+		t.Fatalf("BulkSetKey: %v", err)
+	}
+	var changed []string
+	for _, e := range edits {
+		if e.Changed {
+			changed = append(changed, e.Profile)
+		}
+	}
+	if len(edits) != 3 || strings.Join(changed, ",") != "corp,lab" {
+		t.Fatalf("unexpected edits: %+v", edits)
+	}
+	for _, name := range []string{"corp", "corp-eu", "lab"} {
+		if value, _, _ := mgr.GetKey("env.HTTPS_PROXY", name); value != "http://new" {
+			t.Errorf("%s HTTPS_PROXY = %v", name, value)
+		}
+	}
+	snaps, err := mgr.Snapshots()
+	if err != nil {
+		t.Fatalf("Snapshots: %v", err)
+	}
+	if len(snaps) != 1 || len(snaps[0].Files) != 2 || snaps[0].Operation != "set env.HTTPS_PROXY in 2 profiles" {
+		t.Fatalf("expected one snapshot of both profiles, got %+v", snaps)
+	}
+
+	edits, err = mgr.BulkUnsetKey("model", BulkEditOptions{All: true})
+	if err != nil || len(edits) != 4 {
+		t.Fatalf("BulkUnsetKey --all: %+v, %v", edits, err)
+	}
+	if _, present, _ := mgr.GetKey("model", "personal"); present {
+		t.Fatal("model should be removed from personal")
+	}
+
+	for _, opts := range []BulkEditOptions{{}, {All: true, Tag: "proxy"}, {Tag: "unknown"}} {
+		if _, err := mgr.BulkSetKey("model", "opus", opts); err == nil {
+			t.Errorf("expected an error for %+v", opts)
+		}
+	}
+}
+
+func TestBulkSetKeyRollsBackOnWriteFailure(t *testing.T) {
+	fs := &failRenameFs{Fs: afero.NewMemMapFs()}
+	mgr := NewManager(fs, "/home/test", nil)
+	if err := mgr.InitInfra(); err != nil {
+		t.Fatalf("InitInfra: %v", err)
+	}
+	profiles := map[string]string{"a": `{"model": "haiku"}`, "b": `{"model": "haiku"}`, "c": `{"model": "haiku"}`}
+	writeProfiles(t, mgr, profiles)
+	fs.target = filepath.Join(mgr.SettingsStoreDir(), "b.json")
+
+	_, err := mgr.BulkSetKey("model", "opus", BulkEditOptions{Profiles: []string{"c", "a", "b"}})
+	if err == nil || !strings.Contains(err.Error(), "rolled back 1 profile(s)") {
+		t.Fatalf("expected a rolled back write failure, got %v", err)
+	}
+	for name, want := range profiles {
+		if data, _ := afero.ReadFile(fs, filepath.Join(mgr.SettingsStoreDir(), name+".json")); string(data) != want {
+			t.Errorf("%s = %q, want %q", name, data, want)
+		}
+	}
+}
+
+func TestLoadConfigRejectsInvalidTags(t *testing.T) {
+	mgr := newTestManager(t)
+	if err := afero.WriteFile(mgr.FileSystem(), mgr.paths.ConfigPath(), []byte(`{"tags": {"x": ["../evil"]}}`), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if err := mgr.LoadConfig(); err == nil || !strings.Contains(err.Error(), "invalid tags configuration") {
+		t.Fatalf("expected tags error, got %v", err)
+	}
+}
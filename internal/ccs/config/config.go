@@ -0,0 +1,150 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/OpenGG/claude-code-switch-settings/internal/ccs/storage"
+)
+
+// Config holds optional user preferences read from switch-settings-config.json.
+//
+// Every field is optional; the zero value reproduces the default behavior, so a
+// missing configuration file is equivalent to an empty one.
+type Config struct {
+	Backup  Backup  `json:"backup"`
+	Compare Compare `json:"compare"`
+	Use     Use     `json:"use"`
+	Save    Save    `json:"save"`
+	// Schema replaces the bundled settings schema when set.
+	Schema  *Schema `json:"schema,omitempty"`
+	Lint    Lint    `json:"lint"`
+	Secrets Secrets `json:"secrets"`
+	// Tags groups stored profiles for bulk edits, keyed by tag name.
+	Tags map[string][]string `json:"tags,omitempty"`
+}
+
+// Secrets configures detection of credentials in saved settings and backup
+// archives.
+type Secrets struct {
+	// Mode is "warn" (default), "strict" to refuse to store detected secrets,
+	// or "off".
+	Mode string `json:"mode,omitempty"`
+}
+
+// Lint configures ccs lint.
+type Lint struct {
+	// RulesFile is a JSON file of user rules; a leading "~/" is expanded.
+	RulesFile string `json:"rulesFile,omitempty"`
+	// Disable lists rule IDs to skip, built-in or user-defined.
+	Disable []string `json:"disable,omitempty"`
+}
+
+// Schema points at a JSON Schema for settings.json, such as a newer version
+// of the bundled one.
+type Schema struct {
+	// File is the schema path; a leading "~/" is expanded.
+	File string `json:"file"`
+}
+
+// Use configures switching profiles with ccs use.
+type Use struct {
+	// Sticky lists JSON paths whose values in the current settings.json are
+	// carried into the newly activated profile, such as a machine-specific
+	// "statusLine" command.
+	Sticky []string `json:"sticky,omitempty"`
+}
+
+// Save configures storing profiles with ccs save.
+type Save struct {
+	// Format rewrites settings.json in the canonical style of ccs fmt before
+	// it is stored.
+	Format bool `json:"format,omitempty"`
+}
+
+// Compare configures how live settings are compared with stored profiles.
+type Compare struct {
+	// Ignore lists JSON paths left out of every comparison, such as keys
+	// Claude Code writes itself ("feedbackSurveyState", "env.LOCAL_ONLY").
+	Ignore []string `json:"ignore,omitempty"`
+	// Profiles adds paths to ignore when comparing a specific profile, keyed
+	// by profile name.
+	Profiles map[string]CompareProfile `json:"profiles,omitempty"`
+}
+
+// CompareProfile holds comparison settings for one profile.
+type CompareProfile struct {
+	Ignore []string `json:"ignore,omitempty"`
+}
+
+// Backup configures how backups are written.
+type Backup struct {
+	// Compression selects the encoding for new backup blobs ("none" or "gzip").
+	Compression string `json:"compression,omitempty"`
+	// Layout places new backup blobs flat or in hash-prefix shards
+	// ("flat" or "sharded").
+	Layout string `json:"layout,omitempty"`
+	// Encryption enables authenticated encryption of backup blobs when set.
+	Encryption *Encryption `json:"encryption,omitempty"`
+	// Mirror copies every new backup to a second directory when set.
+	Mirror *Mirror `json:"mirror,omitempty"`
+	// Retention enables automatic pruning after mutating commands when set.
+	Retention *Retention `json:"retention,omitempty"`
+}
+
+// Mirror configures the secondary backup directory.
+type Mirror struct {
+	// Dir is the mirror directory, such as a synced folder or another disk.
+	// It must live outside ~/.claude; a leading "~/" is expanded.
+	Dir string `json:"dir"`
+}
+
+// Retention describes which backups automatic pruning may delete.
+// Pinned backups are always kept.
+type Retention struct {
+	// MaxAge deletes backups not refreshed within this duration (e.g. "90d").
+	MaxAge string `json:"maxAge,omitempty"`
+	// KeepLast always keeps this many of the most recently refreshed backups.
+	// Without MaxAge, every older backup is deleted.
+	KeepLast int `json:"keepLast,omitempty"`
+	// Interval is the minimum time between automatic runs (default "24h").
+	Interval string `json:"interval,omitempty"`
+}
+
+// Encryption selects where the backup encryption key comes from.
+// Exactly one of KeyFile and PassphraseEnv must be set.
+type Encryption struct {
+	// KeyFile is the path of a file holding a base64 encoded 256-bit key.
+	// It must live outside ~/.claude; a leading "~/" is expanded.
+	KeyFile string `json:"keyFile,omitempty"`
+	// PassphraseEnv names the environment variable holding a passphrase
+	// from which the key is derived.
+	PassphraseEnv string `json:"passphraseEnv,omitempty"`
+}
+
+// Load reads the configuration file at path.
+//
+// A missing file yields the zero Config without error. Unknown fields are
+// rejected so that typos surface instead of being silently ignored.
+func Load(stor *storage.Storage, path string) (Config, error) {
+	var cfg Config
+	if err := stor.ValidatePathSafety(path); err != nil {
+		return cfg, fmt.Errorf("path validation failed: %w", err)
+	}
+	data, err := stor.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return cfg, nil
+		}
+		return cfg, fmt.Errorf("failed to read config: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return Config{}, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	return cfg, nil
+}
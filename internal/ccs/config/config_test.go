@@ -0,0 +1,72 @@
+package config
+
+// Tests for configuration loading.
+//
+// Focus: missing file defaults, strict decoding of unknown fields.
+
+import (
+	"testing"
+
+	"github.com/OpenGG/claude-code-switch-settings/internal/ccs/storage"
+	"github.com/spf13/afero"
+)
+
+func TestLoad_MissingFileReturnsDefaults(t *testing.T) {
+	stor := storage.New(afero.NewMemMapFs())
+
+	cfg, err := Load(stor, "/missing/config.json")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Backup.Compression != "" {
+		t.Errorf("expected default compression, got %q", cfg.Backup.Compression)
+	}
+}
+
+func TestLoad_ParsesBackupSection(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	stor := storage.New(fs)
+	if err := afero.WriteFile(fs, "/config.json", []byte(`{"backup": {"compression": "gzip"}}`), 0o600); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+
+	cfg, err := Load(stor, "/config.json")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Backup.Compression != "gzip" {
+		t.Errorf("expected gzip compression, got %q", cfg.Backup.Compression)
+	}
+}
+
+func TestLoad_RejectsUnknownFields(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	stor := storage.New(fs)
+	if err := afero.WriteFile(fs, "/config.json", []byte(`{"backup": {"compresion": "gzip"}}`), 0o600); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+
+	if _, err := Load(stor, "/config.json"); err == nil {
+		t.Fatal("expected error for misspelled field")
+	}
+}
+
+func TestLoad_ParsesCompareAndUseSections(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	stor := storage.New(fs)
+	content := `{"compare": {"ignore": ["feedbackSurveyState"], "profiles": {"work": {"ignore": ["env.LOCAL"]}}}, "use": {"sticky": ["statusLine"]}}`
+	if err := afero.WriteFile(fs, "/config.json", []byte(content), 0o600); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+
+	cfg, err := Load(stor, "/config.json")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(cfg.Compare.Ignore) != 1 || cfg.Compare.Profiles["work"].Ignore[0] != "env.LOCAL" {
+		t.Errorf("unexpected compare config: %+v", cfg.Compare)
+	}
+	if len(cfg.Use.Sticky) != 1 || cfg.Use.Sticky[0] != "statusLine" {
+		t.Errorf("unexpected use config: %+v", cfg.Use)
+	}
+}
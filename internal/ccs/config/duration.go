@@ -0,0 +1,49 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseDuration parses a human-friendly duration such as "30d", "12h" or
+// "90m". Day values are whole numbers; other units follow time.ParseDuration.
+// Negative durations are rejected.
+func ParseDuration(value string) (time.Duration, error) {
+	value = strings.TrimSpace(strings.ToLower(value))
+	if value == "" {
+		return 0, errors.New("duration cannot be empty")
+	}
+	if strings.HasSuffix(value, "d") {
+		days := strings.TrimSuffix(value, "d")
+		v, err := parseDays(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid day duration: %w", err)
+		}
+		return v, nil
+	}
+	if strings.HasSuffix(value, "h") || strings.HasSuffix(value, "m") || strings.HasSuffix(value, "s") {
+		dur, err := time.ParseDuration(value)
+		if err != nil {
+			return 0, err
+		}
+		if dur < 0 {
+			return 0, fmt.Errorf("duration cannot be negative")
+		}
+		return dur, nil
+	}
+	return 0, fmt.Errorf("unsupported duration format: %s", value)
+}
+
+func parseDays(value string) (time.Duration, error) {
+	d, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid day duration: %w", err)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("invalid day duration: %d", d)
+	}
+	return time.Duration(d) * 24 * time.Hour, nil
+}
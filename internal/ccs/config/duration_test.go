@@ -0,0 +1,34 @@
+package config
+
+// Tests for human-friendly duration parsing.
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDuration_DaysAndUnits(t *testing.T) {
+	cases := map[string]time.Duration{
+		"30d":  30 * 24 * time.Hour,
+		" 12H": 12 * time.Hour,
+		"90m":  90 * time.Minute,
+	}
+	for input, want := range cases {
+		got, err := ParseDuration(input)
+		if err != nil {
+			t.Fatalf("ParseDuration(%q) failed: %v", input, err)
+		}
+		if got != want {
+			t.Errorf("ParseDuration(%q) = %v, want %v", input, got, want)
+		}
+	}
+}
+
+func TestParseDays_Invalid(t *testing.T) {
+	if _, err := parseDays("abc"); err == nil {
+		t.Fatalf("expected parse error")
+	}
+	if _, err := parseDays("-1"); err == nil {
+		t.Fatalf("expected negative error")
+	}
+}
@@ -0,0 +1,70 @@
+package ccs
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/OpenGG/claude-code-switch-settings/internal/ccs/format"
+)
+
+// FormatResult reports whether one settings file is in canonical style.
+type FormatResult struct {
+	// Name is the profile name, or empty for the live settings.json.
+	Name string
+	File string
+	// Changed is true when the file was not in canonical style. Without
+	// check, it has been rewritten.
+	Changed bool
+}
+
+// FormatSettings rewrites settings files in canonical style (see
+// format.Settings): the named profiles, every stored profile with all, or
+// settings.json when neither is given. With check, nothing is written and the
+// results only report which files would change.
+//
+// Every file is formatted before anything is written, so a file that is not
+// valid JSON fails the command without changes. Rewritten files are captured
+// in a single snapshot first and replaced atomically.
+func (m *Manager) FormatSettings(names []string, all, check bool) ([]FormatResult, error) {
+	if err := m.InitInfra(); err != nil {
+		return nil, err
+	}
+	targets, err := m.selectFiles(names, all)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]FormatResult, 0, len(targets))
+	formatted := make(map[string][]byte)
+	var changed []string
+	for _, t := range targets {
+		data, err := m.storage.ReadFile(t.file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", t.file, err)
+		}
+		canonical, err := format.Settings(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", t.file, err)
+		}
+		result := FormatResult{Name: t.profile, File: t.file, Changed: !bytes.Equal(data, canonical)}
+		if result.Changed {
+			formatted[t.file] = canonical
+			changed = append(changed, t.file)
+		}
+		results = append(results, result)
+	}
+	if check || len(changed) == 0 {
+		return results, nil
+	}
+
+	if _, err := m.backup.Snapshot(fmt.Sprintf("fmt %d files", len(changed)), changed...); err != nil {
+		return nil, err
+	}
+	for _, file := range changed {
+		if err := m.storage.WriteFileAtomic(file, formatted[file]); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", file, err)
+		}
+	}
+	m.applyRetention()
+	return results, nil
+}
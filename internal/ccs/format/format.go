@@ -0,0 +1,101 @@
+// Package format rewrites settings files in a canonical style, so profiles
+// edited by different people and tools diff and deduplicate cleanly.
+package format
+
+import (
+	"sort"
+
+	"github.com/OpenGG/claude-code-switch-settings/internal/ccs/jsondoc"
+)
+
+// Indent is the indentation of canonical settings files.
+const Indent = "  "
+
+// ruleArrays are the permissions keys holding permission rules. Their order
+// does not matter to Claude Code, so they are sorted and deduplicated.
+var ruleArrays = []string{"allow", "ask", "deny"}
+
+// Settings returns data in canonical style: object keys sorted, permission
+// rule arrays sorted with duplicates removed, two-space indentation and a
+// trailing newline. Other arrays keep their order, since hooks run in order.
+// Data that is not valid JSON is an error.
+func Settings(data []byte) ([]byte, error) {
+	doc, err := jsondoc.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	doc = sortKeys(doc)
+	if root, ok := doc.(*jsondoc.Object); ok {
+		if permissions, ok := root.Get("permissions"); ok {
+			normalizeRules(permissions)
+		}
+	}
+	out, err := jsondoc.Marshal(doc, Indent)
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+// sortKeys returns a copy of v with the keys of every object sorted.
+func sortKeys(v any) any {
+	switch v := v.(type) {
+	case *jsondoc.Object:
+		keys := v.Keys()
+		sort.Strings(keys)
+		sorted := jsondoc.NewObject()
+		for _, key := range keys {
+			child, _ := v.Get(key)
+			sorted.Set(key, sortKeys(child))
+		}
+		return sorted
+	case []any:
+		out := make([]any, len(v))
+		for i, child := range v {
+			out[i] = sortKeys(child)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// normalizeRules sorts and deduplicates the rule arrays of a permissions
+// object. Arrays holding anything but strings are left alone.
+func normalizeRules(permissions any) {
+	obj, ok := permissions.(*jsondoc.Object)
+	if !ok {
+		return
+	}
+	for _, key := range ruleArrays {
+		value, ok := obj.Get(key)
+		if !ok {
+			continue
+		}
+		rules, ok := value.([]any)
+		if !ok {
+			continue
+		}
+		strs := make([]string, 0, len(rules))
+		for _, rule := range rules {
+			s, ok := rule.(string)
+			if !ok {
+				strs = nil
+				break
+			}
+			strs = append(strs, s)
+		}
+		if strs == nil && len(rules) > 0 {
+			continue
+		}
+		sort.Strings(strs)
+		normalized := make([]any, 0, len(strs))
+		for i, s := range strs {
+			if i > 0 && s == strs[i-1] {
+				continue
+			}
+			normalized = append(normalized, s)
+		}
+		obj.Set(key, normalized)
+	}
+}
@@ -0,0 +1,72 @@
+package format
+
+// Tests for canonical settings formatting.
+//
+// Focus: key sorting, permission rule normalization, stable output.
+
+import (
+	"testing"
+)
+
+func TestSettings_CanonicalStyle(t *testing.T) {
+	input := `{"permissions":{"deny":["Read(.env)"],"allow":["Read","Bash(ls:*)","Read"]},
+	    "model":"opus","hooks":{"Stop":[{"hooks":[{"type":"command","command":"b"}]},{"hooks":[{"command":"a","type":"command"}]}]}}`
+	want := `{
+  "hooks": {
+    "Stop": [
+      {
+        "hooks": [
+          {
+            "command": "b",
+            "type": "command"
+          }
+        ]
+      },
+      {
+        "hooks": [
+          {
+            "command": "a",
+            "type": "command"
+          }
+        ]
+      }
+    ]
+  },
+  "model": "opus",
+  "permissions": {
+    "allow": [
+      "Bash(ls:*)",
+      "Read"
+    ],
+    "deny": [
+      "Read(.env)"
+    ]
+  }
+}
+`
+	got, err := Settings([]byte(input))
+	if err != nil {
+		t.Fatalf("Settings: %v", err)
+	}
+	if string(got) != want {
+		t.Fatalf("Settings =\n%s\nwant\n%s", got, want)
+	}
+	again, err := Settings(got)
+	if err != nil || string(again) != want {
+		t.Fatalf("formatting is not stable: %s, %v", again, err)
+	}
+}
+
+func TestSettings_LeavesMixedRuleArraysAndRejectsInvalidJSON(t *testing.T) {
+	got, err := Settings([]byte(`{"permissions": {"allow": ["b", 1, "a"], "deny": []}}`))
+	if err != nil {
+		t.Fatalf("Settings: %v", err)
+	}
+	want := "{\n  \"permissions\": {\n    \"allow\": [\n      \"b\",\n      1,\n      \"a\"\n    ],\n    \"deny\": []\n  }\n}\n"
+	if string(got) != want {
+		t.Fatalf("Settings = %q, want %q", got, want)
+	}
+	if _, err := Settings([]byte(`{`)); err == nil {
+		t.Fatal("expected an error for invalid JSON")
+	}
+}
@@ -0,0 +1,84 @@
+package ccs
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+func TestFormatSettingsCheckAndRewrite(t *testing.T) {
+	mgr := newTestManager(t)
+	fs := mgr.FileSystem()
+	canonical := "{\n  \"model\": \"opus\"\n}\n"
+	writeProfiles(t, mgr, map[string]string{
+		"messy": `{"permissions": {"allow": ["Read", "Edit", "Read"]}, "model": "opus"}`,
+		"tidy":  canonical,
+	})
+
+	results, err := mgr.FormatSettings(nil, true, true)
+	if err != nil {
+		t.Fatalf("FormatSettings --check: %v", err)
+	}
+	if len(results) != 2 || !results[0].Changed || results[0].Name != "messy" || results[1].Changed {
+		t.Fatalf("unexpected check results: %+v", results)
+	}
+	messy := filepath.Join(mgr.SettingsStoreDir(), "messy.json")
+	if data, _ := afero.ReadFile(fs, messy); !strings.HasPrefix(string(data), `{"permissions"`) {
+		t.Fatalf("--check must not write: %s", data)
+	}
+
+	if _, err := mgr.FormatSettings([]string{"messy", "tidy"}, false, false); err != nil {
+		t.Fatalf("FormatSettings: %v", err)
+	}
+	want := "{\n  \"model\": \"opus\",\n  \"permissions\": {\n    \"allow\": [\n      \"Edit\",\n      \"Read\"\n    ]\n  }\n}\n"
+	if data, _ := afero.ReadFile(fs, messy); string(data) != want {
+		t.Fatalf("messy.json = %q, want %q", data, want)
+	}
+	snaps, err := mgr.Snapshots()
+	if err != nil {
+		t.Fatalf("Snapshots: %v", err)
+	}
+	if len(snaps) != 1 || snaps[0].Operation != "fmt 1 files" {
+		t.Fatalf("expected one fmt snapshot, got %+v", snaps)
+	}
+
+	writeProfiles(t, mgr, map[string]string{"broken": `{`})
+	if _, err := mgr.FormatSettings(nil, true, false); err == nil || !strings.Contains(err.Error(), "broken.json") {
+		t.Fatalf("expected an invalid JSON error, got %v", err)
+	}
+}
+
+func TestSaveFormatsProfileWhenConfigured(t *testing.T) {
+	mgr := newTestManager(t)
+	fs := mgr.FileSystem()
+	active := `{"permissions":{"allow":["Read","Read"]},"model":"opus"}`
+	if err := afero.WriteFile(fs, mgr.ActiveSettingsPath(), []byte(active), 0o644); err != nil {
+		t.Fatalf("write active: %v", err)
+	}
+	if err := afero.WriteFile(fs, mgr.paths.ConfigPath(), []byte(`{"save": {"format": true}}`), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if err := mgr.LoadConfig(); err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+
+	if err := mgr.Save("work"); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	stored, _ := afero.ReadFile(fs, filepath.Join(mgr.SettingsStoreDir(), "work.json"))
+	if want := "{\n  \"model\": \"opus\",\n  \"permissions\": {\n    \"allow\": [\n      \"Read\"\n    ]\n  }\n}\n"; string(stored) != want {
+		t.Fatalf("stored profile = %q, want %q", stored, want)
+	}
+	if data, _ := afero.ReadFile(fs, mgr.ActiveSettingsPath()); string(data) != string(stored) {
+		t.Fatalf("settings.json should be formatted too: %q", data)
+	}
+	entries, err := mgr.ListSettings()
+	if err != nil {
+		t.Fatalf("ListSettings: %v", err)
+	}
+	if len(entries) != 1 || contains(entries[0].Qualifiers, "modified") {
+		t.Fatalf("the saved profile should match settings.json: %+v", entries)
+	}
+}
@@ -0,0 +1,171 @@
+package ccs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/OpenGG/claude-code-switch-settings/internal/ccs/jsondoc"
+)
+
+// GrepOptions controls what Grep matches and where it looks.
+type GrepOptions struct {
+	// Regexp treats the pattern as a regular expression instead of literal text.
+	Regexp     bool
+	IgnoreCase bool
+	// KeysOnly and ValuesOnly restrict matching to object keys or to scalar
+	// values. Setting neither matches both.
+	KeysOnly   bool
+	ValuesOnly bool
+	// Active also searches settings.json; Backups also searches every backup.
+	Active  bool
+	Backups bool
+}
+
+// GrepMatch is one key or value matching a Grep pattern.
+type GrepMatch struct {
+	// Source names the searched file or backup: a profile name,
+	// "settings.json" or "backup 1a2b3c4d5e6f".
+	Source string
+	// Path locates the match in dotted form.
+	Path string
+	// Value is the value at Path. For key matches it may be an object or
+	// an array.
+	Value any
+	// Key reports whether the key at Path matched rather than its value.
+	Key bool
+}
+
+// GrepResult holds the matches of a Grep and the sources that could not be
+// searched.
+type GrepResult struct {
+	Matches  []GrepMatch
+	Warnings []string
+}
+
+// Grep searches the keys and values of every stored profile, and optionally
+// settings.json and the backups, for pattern. Matches are reported in
+// document order: settings.json first, then profiles by name, then backups.
+// Sources that are not valid JSON are skipped with a warning.
+func (m *Manager) Grep(pattern string, opts GrepOptions) (GrepResult, error) {
+	var result GrepResult
+	if err := m.InitInfra(); err != nil {
+		return result, err
+	}
+	if opts.KeysOnly && opts.ValuesOnly {
+		return result, errors.New("keys-only and values-only cannot be combined")
+	}
+	re, err := compileGrepPattern(pattern, opts)
+	if err != nil {
+		return result, err
+	}
+
+	search := func(source string, content []byte) {
+		doc, err := jsondoc.Parse(content)
+		if err != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("%s: not searched: invalid JSON: %v", source, err))
+			return
+		}
+		grepValue(doc, nil, func(path jsondoc.Path, value any, key bool) {
+			result.Matches = append(result.Matches, GrepMatch{Source: source, Path: path.String(), Value: value, Key: key})
+		}, re, opts)
+	}
+
+	if opts.Active {
+		data, err := m.storage.ReadFile(m.paths.ActiveSettingsPath())
+		switch {
+		case err == nil:
+			search("settings.json", data)
+		case !errors.Is(err, os.ErrNotExist):
+			return result, fmt.Errorf("failed to read settings.json: %w", err)
+		}
+	}
+	names, err := m.settings.ListStored()
+	if err != nil {
+		return result, err
+	}
+	for _, name := range names {
+		data, err := m.storage.ReadFile(m.paths.StoredSettingsPath(name))
+		if err != nil {
+			return result, fmt.Errorf("failed to read settings '%s': %w", name, err)
+		}
+		search(name, data)
+	}
+	if opts.Backups {
+		if err := m.backup.Contents(func(hash string, content []byte) error {
+			search("backup "+ShortHash(hash), content)
+			return nil
+		}); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+func compileGrepPattern(pattern string, opts GrepOptions) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, errors.New("pattern cannot be empty")
+	}
+	if !opts.Regexp {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if opts.IgnoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+	return re, nil
+}
+
+// grepValue walks v in document order and calls match for every object key
+// and scalar value that re matches. A member whose key and value both match
+// is reported once, as a key match.
+func grepValue(v any, path jsondoc.Path, match func(jsondoc.Path, any, bool), re *regexp.Regexp, opts GrepOptions) {
+	switch v := v.(type) {
+	case *jsondoc.Object:
+		for _, key := range v.Keys() {
+			child, _ := v.Get(key)
+			childPath := append(path[:len(path):len(path)], jsondoc.Segment{Key: key})
+			if !opts.ValuesOnly && re.MatchString(key) {
+				match(childPath, child, true)
+				if isScalar(child) {
+					continue
+				}
+			}
+			grepValue(child, childPath, match, re, opts)
+		}
+	case []any:
+		for i, child := range v {
+			grepValue(child, append(path[:len(path):len(path)], jsondoc.Segment{Index: i, IsIndex: true}), match, re, opts)
+		}
+	default:
+		if !opts.KeysOnly && re.MatchString(scalarText(v)) {
+			match(path, v, false)
+		}
+	}
+}
+
+func isScalar(v any) bool {
+	switch v.(type) {
+	case *jsondoc.Object, []any:
+		return false
+	}
+	return true
+}
+
+// scalarText renders a scalar for matching: strings as-is, everything else
+// as JSON.
+func scalarText(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
@@ -0,0 +1,92 @@
+package ccs
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+func TestGrepFindsKeysAndValuesAcrossProfiles(t *testing.T) {
+	mgr := newTestManager(t)
+	writeProfiles(t, mgr, map[string]string{
+		"work":   `{"permissions": {"allow": ["Read", "Bash(rm:*)"]}, "env": {"RM_SAFE": "1"}}`,
+		"home":   `{"permissions": {"allow": ["Bash(git:*)"]}}`,
+		"broken": `{"permissions":`,
+	})
+
+	result, err := mgr.Grep("Bash(rm:*)", GrepOptions{})
+	if err != nil {
+		t.Fatalf("Grep: %v", err)
+	}
+	if len(result.Matches) != 1 {
+		t.Fatalf("expected one match, got %+v", result.Matches)
+	}
+	if got := result.Matches[0]; got.Source != "work" || got.Path != "permissions.allow[1]" || got.Value != "Bash(rm:*)" || got.Key {
+		t.Fatalf("unexpected match: %+v", got)
+	}
+	if len(result.Warnings) != 1 || !strings.HasPrefix(result.Warnings[0], "broken: ") {
+		t.Fatalf("expected a warning for the broken profile, got %v", result.Warnings)
+	}
+
+	result, err = mgr.Grep("^rm", GrepOptions{Regexp: true, IgnoreCase: true, KeysOnly: true})
+	if err != nil {
+		t.Fatalf("Grep keys: %v", err)
+	}
+	if len(result.Matches) != 1 || result.Matches[0].Path != "env.RM_SAFE" || !result.Matches[0].Key {
+		t.Fatalf("expected the RM_SAFE key, got %+v", result.Matches)
+	}
+
+	result, err = mgr.Grep("permissions", GrepOptions{ValuesOnly: true})
+	if err != nil {
+		t.Fatalf("Grep values: %v", err)
+	}
+	if len(result.Matches) != 0 {
+		t.Fatalf("expected keys to be ignored, got %+v", result.Matches)
+	}
+}
+
+func TestGrepSearchesActiveAndBackups(t *testing.T) {
+	mgr := newTestManager(t)
+	fs := mgr.FileSystem()
+	writeProfiles(t, mgr, map[string]string{"work": `{"model": "opus"}`})
+	if err := afero.WriteFile(fs, mgr.ActiveSettingsPath(), []byte(`{"model": "haiku"}`), 0o644); err != nil {
+		t.Fatalf("write active: %v", err)
+	}
+	if err := mgr.Use("work"); err != nil {
+		t.Fatalf("use: %v", err)
+	}
+
+	result, err := mgr.Grep("haiku", GrepOptions{})
+	if err != nil {
+		t.Fatalf("Grep: %v", err)
+	}
+	if len(result.Matches) != 0 {
+		t.Fatalf("expected no matches in profiles, got %+v", result.Matches)
+	}
+
+	result, err = mgr.Grep("o", GrepOptions{Active: true, Backups: true})
+	if err != nil {
+		t.Fatalf("Grep: %v", err)
+	}
+	var sources []string
+	for _, m := range result.Matches {
+		sources = append(sources, m.Source)
+	}
+	if len(sources) != 3 || sources[0] != "settings.json" || sources[1] != "work" || !strings.HasPrefix(sources[2], "backup ") {
+		t.Fatalf("unexpected sources: %v", sources)
+	}
+}
+
+func TestGrepRejectsInvalidOptions(t *testing.T) {
+	mgr := newTestManager(t)
+	if _, err := mgr.Grep("x", GrepOptions{KeysOnly: true, ValuesOnly: true}); err == nil {
+		t.Fatalf("expected error for keys-only and values-only")
+	}
+	if _, err := mgr.Grep("(", GrepOptions{Regexp: true}); err == nil || !strings.Contains(err.Error(), "invalid pattern") {
+		t.Fatalf("expected invalid pattern error, got %v", err)
+	}
+	if _, err := mgr.Grep("", GrepOptions{}); err == nil {
+		t.Fatalf("expected empty pattern error")
+	}
+}
@@ -0,0 +1,392 @@
+// Package jsondoc provides an order-preserving JSON document model.
+//
+// Settings files are edited by hand, so tools that read and rewrite them must
+// keep keys in their original order. Decoded values are:
+//
+//	nil, bool, string, json.Number, []any and *Object
+//
+// Numbers are kept as json.Number so they round-trip exactly.
+package jsondoc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Object is a JSON object that remembers the order of its keys.
+type Object struct {
+	keys   []string
+	values map[string]any
+}
+
+// NewObject returns an empty Object.
+func NewObject() *Object {
+	return &Object{values: make(map[string]any)}
+}
+
+// Keys returns the keys in document order.
+func (o *Object) Keys() []string {
+	return append([]string(nil), o.keys...)
+}
+
+// Len returns the number of keys.
+func (o *Object) Len() int {
+	return len(o.keys)
+}
+
+// Get returns the value stored under key.
+func (o *Object) Get(key string) (any, bool) {
+	v, ok := o.values[key]
+	return v, ok
+}
+
+// Set stores value under key. New keys are appended; existing keys keep
+// their position.
+func (o *Object) Set(key string, value any) {
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = value
+}
+
+// Delete removes key and reports whether it was present.
+func (o *Object) Delete(key string) bool {
+	if _, ok := o.values[key]; !ok {
+		return false
+	}
+	delete(o.values, key)
+	for i, k := range o.keys {
+		if k == key {
+			o.keys = append(o.keys[:i], o.keys[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+// MarshalJSON encodes the object with its keys in document order, so values
+// from Parse can be embedded in encoding/json output.
+func (o *Object) MarshalJSON() ([]byte, error) {
+	return Marshal(o, "")
+}
+
+// Parse decodes a single JSON value, preserving object key order.
+// Trailing data after the value is an error.
+func Parse(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	v, err := parseValue(dec)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return nil, errors.New("invalid JSON: unexpected data after top-level value")
+	}
+	return v, nil
+}
+
+func parseValue(dec *json.Decoder) (any, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("invalid JSON: unexpected end of input")
+		}
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	switch t := tok.(type) {
+	case json.Delim:
+		switch t {
+		case '{':
+			obj := NewObject()
+			for dec.More() {
+				keyTok, err := dec.Token()
+				if err != nil {
+					return nil, fmt.Errorf("invalid JSON: %w", err)
+				}
+				key, ok := keyTok.(string)
+				if !ok {
+					return nil, errors.New("invalid JSON: object key must be a string")
+				}
+				value, err := parseValue(dec)
+				if err != nil {
+					return nil, err
+				}
+				obj.Set(key, value)
+			}
+			if _, err := dec.Token(); err != nil {
+				return nil, fmt.Errorf("invalid JSON: %w", err)
+			}
+			return obj, nil
+		case '[':
+			arr := []any{}
+			for dec.More() {
+				value, err := parseValue(dec)
+				if err != nil {
+					return nil, err
+				}
+				arr = append(arr, value)
+			}
+			if _, err := dec.Token(); err != nil {
+				return nil, fmt.Errorf("invalid JSON: %w", err)
+			}
+			return arr, nil
+		default:
+			return nil, fmt.Errorf("invalid JSON: unexpected %q", t)
+		}
+	default:
+		return t, nil
+	}
+}
+
+// Marshal encodes v, preserving object key order. With a non-empty indent the
+// output is pretty-printed with one entry per line; otherwise it is compact.
+// HTML characters are not escaped.
+func Marshal(v any, indent string) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := writeValue(&buf, v, indent, ""); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeValue(buf *bytes.Buffer, v any, indent, prefix string) error {
+	switch t := v.(type) {
+	case *Object:
+		if t.Len() == 0 {
+			buf.WriteString("{}")
+			return nil
+		}
+		buf.WriteByte('{')
+		inner := prefix + indent
+		for i, key := range t.keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if indent != "" {
+				buf.WriteByte('\n')
+				buf.WriteString(inner)
+			}
+			writeString(buf, key)
+			buf.WriteByte(':')
+			if indent != "" {
+				buf.WriteByte(' ')
+			}
+			if err := writeValue(buf, t.values[key], indent, inner); err != nil {
+				return err
+			}
+		}
+		if indent != "" {
+			buf.WriteByte('\n')
+			buf.WriteString(prefix)
+		}
+		buf.WriteByte('}')
+	case []any:
+		if len(t) == 0 {
+			buf.WriteString("[]")
+			return nil
+		}
+		buf.WriteByte('[')
+		inner := prefix + indent
+		for i, item := range t {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if indent != "" {
+				buf.WriteByte('\n')
+				buf.WriteString(inner)
+			}
+			if err := writeValue(buf, item, indent, inner); err != nil {
+				return err
+			}
+		}
+		if indent != "" {
+			buf.WriteByte('\n')
+			buf.WriteString(prefix)
+		}
+		buf.WriteByte(']')
+	case string:
+		writeString(buf, t)
+	case json.Number:
+		buf.WriteString(t.String())
+	case bool:
+		if t {
+			buf.WriteString("true")
+		} else {
+			buf.WriteString("false")
+		}
+	case nil:
+		buf.WriteString("null")
+	default:
+		return fmt.Errorf("jsondoc: unsupported value of type %T", v)
+	}
+	return nil
+}
+
+func writeString(buf *bytes.Buffer, s string) {
+	var tmp bytes.Buffer
+	enc := json.NewEncoder(&tmp)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s) // encoding a string cannot fail
+	buf.Write(bytes.TrimSuffix(tmp.Bytes(), []byte("\n")))
+}
+
+// Equal reports whether a and b are the same JSON value. Object key order is
+// ignored and numbers are compared by value, so 1, 1.0 and 1e0 are equal.
+func Equal(a, b any) bool {
+	switch x := a.(type) {
+	case *Object:
+		y, ok := b.(*Object)
+		if !ok || x.Len() != y.Len() {
+			return false
+		}
+		for _, key := range x.keys {
+			yv, ok := y.values[key]
+			if !ok || !Equal(x.values[key], yv) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		y, ok := b.([]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !Equal(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	case json.Number:
+		y, ok := b.(json.Number)
+		return ok && numbersEqual(x, y)
+	default:
+		return a == b
+	}
+}
+
+func numbersEqual(a, b json.Number) bool {
+	if a == b {
+		return true
+	}
+	x, okA := new(big.Float).SetString(a.String())
+	y, okB := new(big.Float).SetString(b.String())
+	return okA && okB && x.Cmp(y) == 0
+}
+
+// Clone returns a deep copy of v.
+func Clone(v any) any {
+	switch t := v.(type) {
+	case *Object:
+		c := NewObject()
+		for _, key := range t.keys {
+			c.Set(key, Clone(t.values[key]))
+		}
+		return c
+	case []any:
+		c := make([]any, len(t))
+		for i, item := range t {
+			c[i] = Clone(item)
+		}
+		return c
+	default:
+		return v
+	}
+}
+
+// TypeName returns the JSON type name of v ("object", "array", "string",
+// "number", "boolean" or "null").
+func TypeName(v any) string {
+	switch v.(type) {
+	case *Object:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case json.Number:
+		return "number"
+	case bool:
+		return "boolean"
+	case nil:
+		return "null"
+	default:
+		return strings.TrimPrefix(fmt.Sprintf("%T", v), "*")
+	}
+}
+
+// MarshalLike encodes v pretty-printed with the indentation of like, the
+// original text of a document being rewritten. The result ends with a newline
+// when like does, or when like is empty.
+func MarshalLike(v any, like []byte) ([]byte, error) {
+	data, err := Marshal(v, DetectIndent(like))
+	if err != nil {
+		return nil, err
+	}
+	if len(like) == 0 || bytes.HasSuffix(like, []byte("\n")) {
+		data = append(data, '\n')
+	}
+	return data, nil
+}
+
+// DetectIndent returns the indentation unit used by a pretty-printed JSON
+// document, or two spaces when data is compact or empty.
+func DetectIndent(data []byte) string {
+	for _, line := range bytes.Split(data, []byte("\n"))[1:] {
+		trimmed := bytes.TrimLeft(line, " \t")
+		if len(trimmed) == 0 || len(trimmed) == len(line) {
+			continue
+		}
+		return string(line[:len(line)-len(trimmed)])
+	}
+	return "  "
+}
+
+// Canonical encodes v in a canonical compact form: object keys sorted,
+// no insignificant whitespace, and numbers normalized so that 1, 1.0 and 1e0
+// encode identically. Documents that are Equal have the same canonical form.
+func Canonical(v any) ([]byte, error) {
+	return Marshal(canonicalize(v), "")
+}
+
+func canonicalize(v any) any {
+	switch t := v.(type) {
+	case *Object:
+		keys := t.Keys()
+		sort.Strings(keys)
+		c := NewObject()
+		for _, key := range keys {
+			c.Set(key, canonicalize(t.values[key]))
+		}
+		return c
+	case []any:
+		c := make([]any, len(t))
+		for i, item := range t {
+			c[i] = canonicalize(item)
+		}
+		return c
+	case json.Number:
+		return canonicalNumber(t)
+	default:
+		return v
+	}
+}
+
+func canonicalNumber(n json.Number) json.Number {
+	r, ok := new(big.Rat).SetString(n.String())
+	if !ok {
+		return n
+	}
+	if r.IsInt() {
+		return json.Number(r.Num().String())
+	}
+	f, _ := r.Float64()
+	return json.Number(strconv.FormatFloat(f, 'g', -1, 64))
+}
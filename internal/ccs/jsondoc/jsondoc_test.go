@@ -0,0 +1,213 @@
+package jsondoc
+
+// Tests for the order-preserving JSON document model.
+//
+// Focus: Parse/Marshal round trips, Equal semantics, dotted and JSON pointer
+// path lookups.
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseMarshal_PreservesKeyOrder(t *testing.T) {
+	in := `{"zeta":1,"alpha":{"b":true,"a":null},"list":[1.50,"x<y"]}`
+	doc, err := Parse([]byte(in))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	out, err := Marshal(doc, "")
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if string(out) != in {
+		t.Fatalf("round trip mismatch:\n got %s\nwant %s", out, in)
+	}
+}
+
+func TestMarshal_Indented(t *testing.T) {
+	doc, err := Parse([]byte(`{"a":[1,{}],"b":[]}`))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	out, err := Marshal(doc, "  ")
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	want := "{\n  \"a\": [\n    1,\n    {}\n  ],\n  \"b\": []\n}"
+	if string(out) != want {
+		t.Fatalf("unexpected output:\n%s", out)
+	}
+}
+
+func TestParse_RejectsInvalid(t *testing.T) {
+	for _, in := range []string{"", "{", `{"a":1} x`, `[1,]`} {
+		if _, err := Parse([]byte(in)); err == nil {
+			t.Errorf("expected error for %q", in)
+		}
+	}
+}
+
+func TestEqual_IgnoresKeyOrderAndNumberForm(t *testing.T) {
+	a, _ := Parse([]byte(`{"x":1,"y":[true,"s"]}`))
+	b, _ := Parse([]byte(`{"y":[true,"s"],"x":1.0}`))
+	c, _ := Parse([]byte(`{"y":[true,"s"],"x":2}`))
+	if !Equal(a, b) {
+		t.Fatal("expected documents to be equal")
+	}
+	if Equal(a, c) {
+		t.Fatal("expected documents to differ")
+	}
+}
+
+func TestObject_SetDeleteKeepOrder(t *testing.T) {
+	obj := NewObject()
+	obj.Set("b", "1")
+	obj.Set("a", "2")
+	obj.Set("b", "3")
+	if got := obj.Keys(); len(got) != 2 || got[0] != "b" || got[1] != "a" {
+		t.Fatalf("unexpected keys %v", got)
+	}
+	if !obj.Delete("b") || obj.Delete("b") {
+		t.Fatal("unexpected Delete result")
+	}
+	if got := obj.Keys(); len(got) != 1 || got[0] != "a" {
+		t.Fatalf("unexpected keys after delete %v", got)
+	}
+}
+
+func TestParsePath_DottedAndPointer(t *testing.T) {
+	doc, _ := Parse([]byte(`{"permissions":{"allow":["Read","Bash(ls)"]},"env":{"A.B":"v","x/y":"w"}}`))
+	cases := map[string]string{
+		"permissions.allow[1]": "Bash(ls)",
+		"/permissions/allow/0": "Read",
+		`env["A.B"]`:           "v",
+		"/env/x~1y":            "w",
+	}
+	for raw, want := range cases {
+		path, err := ParsePath(raw)
+		if err != nil {
+			t.Fatalf("ParsePath(%q): %v", raw, err)
+		}
+		got, ok := Get(doc, path)
+		if !ok || got != want {
+			t.Errorf("Get(%q) = %v, %v; want %q", raw, got, ok, want)
+		}
+	}
+	path, _ := ParsePath("permissions.deny")
+	if _, ok := Get(doc, path); ok {
+		t.Error("expected missing key to report absent")
+	}
+}
+
+func TestParsePath_Invalid(t *testing.T) {
+	for _, raw := range []string{"", "a..b", "a.", "a[", "a[x]", "a[0]b"} {
+		if _, err := ParsePath(raw); err == nil {
+			t.Errorf("expected error for %q", raw)
+		}
+	}
+}
+
+func TestPath_String(t *testing.T) {
+	path, _ := ParsePath(`env["A.B"].list[2]`)
+	if got := path.String(); got != `env["A.B"].list[2]` {
+		t.Fatalf("unexpected String %q", got)
+	}
+}
+
+func TestSet_CreatesParentsAndAppends(t *testing.T) {
+	doc, _ := Parse([]byte(`{"model":"opus","permissions":{"allow":["Read"]}}`))
+	for raw, value := range map[string]any{
+		"env.ANTHROPIC_BASE_URL": "https://example.test",
+		"permissions.allow[1]":   "Bash(ls)",
+		"model":                  "sonnet",
+	} {
+		path, _ := ParsePath(raw)
+		var err error
+		if doc, err = Set(doc, path, value); err != nil {
+			t.Fatalf("Set(%q): %v", raw, err)
+		}
+	}
+	out, _ := Marshal(doc, "")
+	want := `{"model":"sonnet","permissions":{"allow":["Read","Bash(ls)"]},"env":{"ANTHROPIC_BASE_URL":"https://example.test"}}`
+	if string(out) != want {
+		t.Fatalf("unexpected document:\n got %s\nwant %s", out, want)
+	}
+
+	for _, raw := range []string{"permissions.allow[5]", "model.name"} {
+		path, _ := ParsePath(raw)
+		if _, err := Set(doc, path, "x"); err == nil {
+			t.Errorf("expected error for %q", raw)
+		}
+	}
+}
+
+func TestDelete_RemovesKeysAndElements(t *testing.T) {
+	doc, _ := Parse([]byte(`{"a":{"b":1,"c":2},"list":[1,2,3]}`))
+	for _, raw := range []string{"a.b", "list[0]", "missing.key"} {
+		path, _ := ParsePath(raw)
+		var err error
+		if doc, _, err = Delete(doc, path); err != nil {
+			t.Fatalf("Delete(%q): %v", raw, err)
+		}
+	}
+	out, _ := Marshal(doc, "")
+	if string(out) != `{"a":{"c":2},"list":[2,3]}` {
+		t.Fatalf("unexpected document %s", out)
+	}
+	if _, _, err := Delete(doc, nil); err == nil {
+		t.Fatal("expected error deleting the root")
+	}
+}
+
+func TestDetectIndent(t *testing.T) {
+	cases := map[string]string{
+		"{\n    \"a\": 1\n}": "    ",
+		"{\n\t\"a\": 1\n}":   "\t",
+		`{"a":1}`:            "  ",
+	}
+	for in, want := range cases {
+		if got := DetectIndent([]byte(in)); got != want {
+			t.Errorf("DetectIndent(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestCanonical_IgnoresFormattingOrderAndNumberForm(t *testing.T) {
+	a, _ := Parse([]byte("{\n  \"b\": [1.0, 2.50],\n  \"a\": {\"y\": 1e2, \"x\": \"\\u0041\"}\n}"))
+	b, _ := Parse([]byte(`{"a":{"x":"A","y":100},"b":[1,2.5]}`))
+	ca, err := Canonical(a)
+	if err != nil {
+		t.Fatalf("Canonical: %v", err)
+	}
+	cb, _ := Canonical(b)
+	if string(ca) != string(cb) || string(ca) != `{"a":{"x":"A","y":100},"b":[1,2.5]}` {
+		t.Fatalf("canonical forms differ:\n%s\n%s", ca, cb)
+	}
+}
+
+func TestMarshalLike_KeepsIndentAndTrailingNewline(t *testing.T) {
+	doc, _ := Parse([]byte(`{"a":1}`))
+	out, err := MarshalLike(doc, []byte("{\n\t\"x\": 0\n}\n"))
+	if err != nil || string(out) != "{\n\t\"a\": 1\n}\n" {
+		t.Fatalf("unexpected output %q, %v", out, err)
+	}
+	out, _ = MarshalLike(doc, []byte(`{"x":0}`))
+	if string(out) != "{\n  \"a\": 1\n}" {
+		t.Fatalf("unexpected output without newline %q", out)
+	}
+}
+
+func TestObject_MarshalJSONKeepsOrder(t *testing.T) {
+	v, err := Parse([]byte(`{"b": [1, {"d": true, "c": null}], "a": "x"}`))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	data, err := json.Marshal(map[string]any{"doc": v})
+	if err != nil {
+		t.Fatalf("json.Marshal: %v", err)
+	}
+	if want := `{"doc":{"b":[1,{"d":true,"c":null}],"a":"x"}}`; string(data) != want {
+		t.Fatalf("json.Marshal = %s, want %s", data, want)
+	}
+}
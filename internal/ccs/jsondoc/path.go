@@ -0,0 +1,233 @@
+package jsondoc
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Segment is one step of a Path: an object key or an array index.
+type Segment struct {
+	Key     string
+	Index   int
+	IsIndex bool
+}
+
+// Path addresses a value inside a document.
+type Path []Segment
+
+// ParsePath parses either a dotted path such as "permissions.allow[0]" or a
+// JSON pointer such as "/permissions/allow/0". Keys containing dots or
+// brackets can be quoted in dotted form: `env["A.B"]`.
+func ParsePath(s string) (Path, error) {
+	if s == "" {
+		return nil, errors.New("path cannot be empty")
+	}
+	if strings.HasPrefix(s, "/") {
+		return parsePointer(s)
+	}
+	return parseDotted(s)
+}
+
+func parsePointer(s string) (Path, error) {
+	var path Path
+	for _, raw := range strings.Split(s[1:], "/") {
+		token := strings.ReplaceAll(strings.ReplaceAll(raw, "~1", "/"), "~0", "~")
+		seg := Segment{Key: token}
+		if n, err := strconv.Atoi(token); err == nil && n >= 0 && strconv.Itoa(n) == token {
+			seg.Index = n
+			seg.IsIndex = true
+		}
+		path = append(path, seg)
+	}
+	return path, nil
+}
+
+func parseDotted(s string) (Path, error) {
+	var path Path
+	i := 0
+	expectKey := true
+	for i < len(s) {
+		switch s[i] {
+		case '.':
+			if expectKey {
+				return nil, fmt.Errorf("invalid path %q: empty key", s)
+			}
+			expectKey = true
+			i++
+		case '[':
+			end := strings.IndexByte(s[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid path %q: unclosed '['", s)
+			}
+			inner := s[i+1 : i+end]
+			if strings.HasPrefix(inner, `"`) {
+				key, err := strconv.Unquote(inner)
+				if err != nil {
+					return nil, fmt.Errorf("invalid path %q: bad quoted key %s", s, inner)
+				}
+				path = append(path, Segment{Key: key})
+			} else {
+				n, err := strconv.Atoi(inner)
+				if err != nil || n < 0 {
+					return nil, fmt.Errorf("invalid path %q: bad index %q", s, inner)
+				}
+				path = append(path, Segment{Key: inner, Index: n, IsIndex: true})
+			}
+			i += end + 1
+			expectKey = false
+		default:
+			if !expectKey {
+				return nil, fmt.Errorf("invalid path %q: expected '.' or '[' at offset %d", s, i)
+			}
+			end := strings.IndexAny(s[i:], ".[")
+			if end < 0 {
+				end = len(s) - i
+			}
+			path = append(path, Segment{Key: s[i : i+end]})
+			i += end
+			expectKey = false
+		}
+	}
+	if expectKey {
+		return nil, fmt.Errorf("invalid path %q: trailing '.'", s)
+	}
+	return path, nil
+}
+
+// String renders the path in dotted form.
+func (p Path) String() string {
+	var b strings.Builder
+	for i, seg := range p {
+		switch {
+		case seg.IsIndex:
+			fmt.Fprintf(&b, "[%d]", seg.Index)
+		case seg.Key == "" || strings.ContainsAny(seg.Key, ".[]\""):
+			fmt.Fprintf(&b, "[%s]", strconv.Quote(seg.Key))
+		default:
+			if i > 0 {
+				b.WriteByte('.')
+			}
+			b.WriteString(seg.Key)
+		}
+	}
+	return b.String()
+}
+
+// Get returns the value at path inside doc and whether it exists. Index
+// segments parsed from a JSON pointer also match object keys.
+func Get(doc any, path Path) (any, bool) {
+	cur := doc
+	for _, seg := range path {
+		switch node := cur.(type) {
+		case *Object:
+			v, ok := node.Get(seg.Key)
+			if !ok {
+				return nil, false
+			}
+			cur = v
+		case []any:
+			if !seg.IsIndex || seg.Index >= len(node) {
+				return nil, false
+			}
+			cur = node[seg.Index]
+		default:
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// Set stores value at path inside doc and returns the updated document.
+// Missing intermediate objects are created. An array index may address an
+// existing element or the position just past the end, which appends. An
+// empty path replaces the whole document. doc is modified in place.
+func Set(doc any, path Path, value any) (any, error) {
+	return setIn(doc, path, value, path)
+}
+
+func setIn(node any, rest Path, value any, full Path) (any, error) {
+	if len(rest) == 0 {
+		return value, nil
+	}
+	seg := rest[0]
+	switch n := node.(type) {
+	case *Object:
+		child, _ := n.Get(seg.Key)
+		updated, err := setIn(child, rest[1:], value, full)
+		if err != nil {
+			return nil, err
+		}
+		n.Set(seg.Key, updated)
+		return n, nil
+	case []any:
+		if !seg.IsIndex || seg.Index > len(n) {
+			return nil, fmt.Errorf("cannot set %s: no element %s in array of length %d", full, seg.Key, len(n))
+		}
+		if seg.Index == len(n) {
+			updated, err := setIn(nil, rest[1:], value, full)
+			if err != nil {
+				return nil, err
+			}
+			return append(n, updated), nil
+		}
+		updated, err := setIn(n[seg.Index], rest[1:], value, full)
+		if err != nil {
+			return nil, err
+		}
+		n[seg.Index] = updated
+		return n, nil
+	case nil:
+		obj := NewObject()
+		return setIn(obj, rest, value, full)
+	default:
+		return nil, fmt.Errorf("cannot set %s: parent is a %s", full, TypeName(node))
+	}
+}
+
+// Delete removes the value at path from doc and returns the updated document
+// and whether anything was removed. Removing an array element shifts the
+// elements after it. A missing path is not an error. doc is modified in
+// place.
+func Delete(doc any, path Path) (any, bool, error) {
+	if len(path) == 0 {
+		return nil, false, errors.New("cannot delete the document root")
+	}
+	return deleteIn(doc, path)
+}
+
+func deleteIn(node any, rest Path) (any, bool, error) {
+	seg := rest[0]
+	switch n := node.(type) {
+	case *Object:
+		if len(rest) == 1 {
+			return n, n.Delete(seg.Key), nil
+		}
+		child, ok := n.Get(seg.Key)
+		if !ok {
+			return n, false, nil
+		}
+		updated, removed, err := deleteIn(child, rest[1:])
+		if err != nil {
+			return nil, false, err
+		}
+		n.Set(seg.Key, updated)
+		return n, removed, nil
+	case []any:
+		if !seg.IsIndex || seg.Index >= len(n) {
+			return n, false, nil
+		}
+		if len(rest) == 1 {
+			return append(n[:seg.Index], n[seg.Index+1:]...), true, nil
+		}
+		updated, removed, err := deleteIn(n[seg.Index], rest[1:])
+		if err != nil {
+			return nil, false, err
+		}
+		n[seg.Index] = updated
+		return n, removed, nil
+	default:
+		return node, false, nil
+	}
+}
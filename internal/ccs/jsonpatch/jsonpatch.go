@@ -0,0 +1,361 @@
+// Package jsonpatch applies RFC 6902 JSON Patch and RFC 7386 JSON Merge Patch
+// documents to values decoded by jsondoc.Parse.
+//
+// A patch file holding a JSON array is a JSON Patch; one holding an object is
+// a merge patch. Apply works on a copy, so a failing operation, such as a
+// "test" that does not hold, leaves the input untouched.
+package jsonpatch
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/OpenGG/claude-code-switch-settings/internal/ccs/jsondoc"
+)
+
+// Kind is the format of a patch.
+type Kind int
+
+const (
+	// JSONPatch is an RFC 6902 list of operations.
+	JSONPatch Kind = iota
+	// MergePatch is an RFC 7386 merge patch.
+	MergePatch
+)
+
+func (k Kind) String() string {
+	if k == MergePatch {
+		return "merge patch"
+	}
+	return "JSON patch"
+}
+
+// Operation is one RFC 6902 operation.
+type Operation struct {
+	Op    string
+	Path  string
+	From  string
+	Value any
+}
+
+// Patch is a parsed patch document.
+type Patch struct {
+	Kind Kind
+	// Operations holds the operations of a JSONPatch.
+	Operations []Operation
+	merge      any
+}
+
+// Parse reads a patch document, choosing the format from its top-level type.
+func Parse(data []byte) (*Patch, error) {
+	doc, err := jsondoc.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	switch v := doc.(type) {
+	case *jsondoc.Object:
+		return &Patch{Kind: MergePatch, merge: v}, nil
+	case []any:
+		p := &Patch{Kind: JSONPatch}
+		for i, item := range v {
+			op, err := parseOperation(item)
+			if err != nil {
+				return nil, fmt.Errorf("operation %d: %w", i, err)
+			}
+			p.Operations = append(p.Operations, op)
+		}
+		return p, nil
+	default:
+		return nil, fmt.Errorf("patch must be an array of operations or an object, got %s", jsondoc.TypeName(doc))
+	}
+}
+
+func parseOperation(item any) (Operation, error) {
+	obj, ok := item.(*jsondoc.Object)
+	if !ok {
+		return Operation{}, fmt.Errorf("expected an object, got %s", jsondoc.TypeName(item))
+	}
+	var op Operation
+	member := func(name string, dst *string) error {
+		v, ok := obj.Get(name)
+		if !ok {
+			return fmt.Errorf("missing %q", name)
+		}
+		s, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("%q must be a string, got %s", name, jsondoc.TypeName(v))
+		}
+		*dst = s
+		return nil
+	}
+	if err := member("op", &op.Op); err != nil {
+		return op, err
+	}
+	if err := member("path", &op.Path); err != nil {
+		return op, err
+	}
+	switch op.Op {
+	case "add", "replace", "test":
+		v, ok := obj.Get("value")
+		if !ok {
+			return op, fmt.Errorf("%s: missing \"value\"", op.Op)
+		}
+		op.Value = v
+	case "move", "copy":
+		if err := member("from", &op.From); err != nil {
+			return op, fmt.Errorf("%s: %w", op.Op, err)
+		}
+	case "remove":
+	default:
+		return op, fmt.Errorf("unknown op %q", op.Op)
+	}
+	return op, nil
+}
+
+// Apply returns doc with the patch applied. doc is not modified.
+func (p *Patch) Apply(doc any) (any, error) {
+	if p.Kind == MergePatch {
+		return merge(jsondoc.Clone(doc), p.merge), nil
+	}
+	doc = jsondoc.Clone(doc)
+	for i, op := range p.Operations {
+		var err error
+		if doc, err = applyOperation(doc, op); err != nil {
+			return nil, fmt.Errorf("operation %d (%s %s): %w", i, op.Op, op.Path, err)
+		}
+	}
+	return doc, nil
+}
+
+// merge implements the MergePatch algorithm of RFC 7386. Existing keys keep
+// their position; new keys are appended.
+func merge(target, patch any) any {
+	p, ok := patch.(*jsondoc.Object)
+	if !ok {
+		return jsondoc.Clone(patch)
+	}
+	t, ok := target.(*jsondoc.Object)
+	if !ok {
+		t = jsondoc.NewObject()
+	}
+	for _, key := range p.Keys() {
+		value, _ := p.Get(key)
+		if value == nil {
+			t.Delete(key)
+			continue
+		}
+		current, _ := t.Get(key)
+		t.Set(key, merge(current, value))
+	}
+	return t
+}
+
+func applyOperation(doc any, op Operation) (any, error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+	switch op.Op {
+	case "add":
+		return add(doc, path, jsondoc.Clone(op.Value))
+	case "remove":
+		if len(path) == 0 {
+			return nil, errors.New("cannot remove the document root")
+		}
+		return edit(doc, path, removeChild)
+	case "replace":
+		if _, err := get(doc, path); err != nil {
+			return nil, err
+		}
+		if len(path) == 0 {
+			return jsondoc.Clone(op.Value), nil
+		}
+		value := jsondoc.Clone(op.Value)
+		return edit(doc, path, func(parent any, token string) (any, error) {
+			if obj, ok := parent.(*jsondoc.Object); ok {
+				obj.Set(token, value)
+				return obj, nil
+			}
+			arr := parent.([]any)
+			i, err := arrayIndex(token, len(arr))
+			if err != nil {
+				return nil, err
+			}
+			arr[i] = value
+			return arr, nil
+		})
+	case "test":
+		value, err := get(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !jsondoc.Equal(value, op.Value) {
+			return nil, fmt.Errorf("test failed: value is %s, want %s", render(value), render(op.Value))
+		}
+		return doc, nil
+	}
+
+	from, err := parsePointer(op.From)
+	if err != nil {
+		return nil, err
+	}
+	value, err := get(doc, from)
+	if err != nil {
+		return nil, fmt.Errorf("from %s: %w", op.From, err)
+	}
+	if op.Op == "copy" {
+		return add(doc, path, jsondoc.Clone(value))
+	}
+	if op.From == op.Path {
+		return doc, nil
+	}
+	if strings.HasPrefix(op.Path, op.From+"/") {
+		return nil, errors.New("cannot move a value into itself")
+	}
+	if doc, err = edit(doc, from, removeChild); err != nil {
+		return nil, err
+	}
+	return add(doc, path, value)
+}
+
+// parsePointer splits an RFC 6901 JSON pointer into unescaped tokens. The
+// empty pointer addresses the whole document.
+func parsePointer(s string) ([]string, error) {
+	if s == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(s, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q: must start with '/'", s)
+	}
+	tokens := strings.Split(s[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// arrayIndex parses an array index token; valid indexes are below limit.
+func arrayIndex(token string, limit int) (int, error) {
+	n, err := strconv.Atoi(token)
+	if err != nil || n < 0 || strconv.Itoa(n) != token {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	if n >= limit {
+		return 0, fmt.Errorf("array index %d out of range", n)
+	}
+	return n, nil
+}
+
+func get(doc any, path []string) (any, error) {
+	cur := doc
+	for _, token := range path {
+		switch n := cur.(type) {
+		case *jsondoc.Object:
+			v, ok := n.Get(token)
+			if !ok {
+				return nil, fmt.Errorf("no member %q", token)
+			}
+			cur = v
+		case []any:
+			i, err := arrayIndex(token, len(n))
+			if err != nil {
+				return nil, err
+			}
+			cur = n[i]
+		default:
+			return nil, fmt.Errorf("cannot look up %q in a %s", token, jsondoc.TypeName(cur))
+		}
+	}
+	return cur, nil
+}
+
+func add(doc any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return edit(doc, path, func(parent any, token string) (any, error) {
+		if obj, ok := parent.(*jsondoc.Object); ok {
+			obj.Set(token, value)
+			return obj, nil
+		}
+		arr := parent.([]any)
+		if token == "-" {
+			return append(arr, value), nil
+		}
+		i, err := arrayIndex(token, len(arr)+1)
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, nil)
+		copy(arr[i+1:], arr[i:])
+		arr[i] = value
+		return arr, nil
+	})
+}
+
+func removeChild(parent any, token string) (any, error) {
+	if obj, ok := parent.(*jsondoc.Object); ok {
+		if !obj.Delete(token) {
+			return nil, fmt.Errorf("no member %q", token)
+		}
+		return obj, nil
+	}
+	arr := parent.([]any)
+	i, err := arrayIndex(token, len(arr))
+	if err != nil {
+		return nil, err
+	}
+	return append(arr[:i], arr[i+1:]...), nil
+}
+
+// render encodes v as compact JSON for error messages.
+func render(v any) string {
+	data, err := jsondoc.Marshal(v, "")
+	if err != nil {
+		return jsondoc.TypeName(v)
+	}
+	return string(data)
+}
+
+// edit walks to the container holding the last token of path and replaces
+// it with the result of fn, which receives an object or an array. Arrays are
+// stored back into their parent, since fn may grow or shrink them.
+func edit(node any, path []string, fn func(parent any, token string) (any, error)) (any, error) {
+	token := path[0]
+	if len(path) == 1 {
+		switch node.(type) {
+		case *jsondoc.Object, []any:
+			return fn(node, token)
+		default:
+			return nil, fmt.Errorf("cannot change %q in a %s", token, jsondoc.TypeName(node))
+		}
+	}
+	switch n := node.(type) {
+	case *jsondoc.Object:
+		child, ok := n.Get(token)
+		if !ok {
+			return nil, fmt.Errorf("no member %q", token)
+		}
+		updated, err := edit(child, path[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		n.Set(token, updated)
+		return n, nil
+	case []any:
+		i, err := arrayIndex(token, len(n))
+		if err != nil {
+			return nil, err
+		}
+		updated, err := edit(n[i], path[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		n[i] = updated
+		return n, nil
+	default:
+		return nil, fmt.Errorf("cannot look up %q in a %s", token, jsondoc.TypeName(node))
+	}
+}
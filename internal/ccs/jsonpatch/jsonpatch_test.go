@@ -0,0 +1,143 @@
+package jsonpatch
+
+// Tests for JSON Patch and merge patch application.
+//
+// Focus: RFC 6902 operations, RFC 7386 merging, atomic failure, key order.
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/OpenGG/claude-code-switch-settings/internal/ccs/jsondoc"
+)
+
+func apply(t *testing.T, doc, patch string) (string, error) {
+	t.Helper()
+	p, err := Parse([]byte(patch))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	v, err := jsondoc.Parse([]byte(doc))
+	if err != nil {
+		t.Fatalf("parse doc: %v", err)
+	}
+	out, err := p.Apply(v)
+	if err != nil {
+		return "", err
+	}
+	data, err := jsondoc.Marshal(out, "")
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	return string(data), nil
+}
+
+func TestApply_JSONPatchOperations(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+	}{
+		{"add member", `{"a":1}`, `[{"op":"add","path":"/b","value":2}]`, `{"a":1,"b":2}`},
+		{"add inserts into array", `{"l":["x","z"]}`, `[{"op":"add","path":"/l/1","value":"y"}]`, `{"l":["x","y","z"]}`},
+		{"add appends with dash", `{"l":["x"]}`, `[{"op":"add","path":"/l/-","value":"y"}]`, `{"l":["x","y"]}`},
+		{"add replaces root", `{"a":1}`, `[{"op":"add","path":"","value":{"b":2}}]`, `{"b":2}`},
+		{"remove member", `{"a":1,"b":2}`, `[{"op":"remove","path":"/a"}]`, `{"b":2}`},
+		{"remove array element", `{"l":[1,2,3]}`, `[{"op":"remove","path":"/l/1"}]`, `{"l":[1,3]}`},
+		{"replace keeps position", `{"a":1,"b":2}`, `[{"op":"replace","path":"/a","value":3}]`, `{"a":3,"b":2}`},
+		{"move", `{"a":{"x":1},"b":{}}`, `[{"op":"move","from":"/a/x","path":"/b/y"}]`, `{"a":{},"b":{"y":1}}`},
+		{"copy", `{"a":[1]}`, `[{"op":"copy","from":"/a","path":"/b"}]`, `{"a":[1],"b":[1]}`},
+		{"test passes", `{"a":1}`, `[{"op":"test","path":"/a","value":1.0},{"op":"add","path":"/b","value":true}]`, `{"a":1,"b":true}`},
+		{"escaped pointer", `{"a/b":{"c~d":1}}`, `[{"op":"replace","path":"/a~1b/c~0d","value":2}]`, `{"a/b":{"c~d":2}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := apply(t, tt.doc, tt.patch)
+			if err != nil {
+				t.Fatalf("Apply: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApply_JSONPatchErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch string
+		want  string
+	}{
+		{"failed test", `[{"op":"test","path":"/a","value":2}]`, "test failed: value is 1, want 2"},
+		{"missing parent", `[{"op":"add","path":"/x/y","value":1}]`, `no member "x"`},
+		{"remove missing", `[{"op":"remove","path":"/zzz"}]`, `no member "zzz"`},
+		{"replace missing", `[{"op":"replace","path":"/zzz","value":1}]`, `no member "zzz"`},
+		{"index out of range", `[{"op":"add","path":"/l/5","value":1}]`, "out of range"},
+		{"move into child", `[{"op":"move","from":"/l","path":"/l/0"}]`, "into itself"},
+		{"bad pointer", `[{"op":"remove","path":"a"}]`, "must start with '/'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := apply(t, `{"a":1,"l":[0]}`, tt.patch)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestApply_FailureLeavesInputUntouched(t *testing.T) {
+	doc, _ := jsondoc.Parse([]byte(`{"a":1}`))
+	p, err := Parse([]byte(`[{"op":"add","path":"/b","value":2},{"op":"test","path":"/a","value":0}]`))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if _, err := p.Apply(doc); err == nil || !strings.HasPrefix(err.Error(), "operation 1 (test /a)") {
+		t.Fatalf("expected failure in operation 1, got %v", err)
+	}
+	if data, _ := jsondoc.Marshal(doc, ""); string(data) != `{"a":1}` {
+		t.Fatalf("input was modified: %s", data)
+	}
+}
+
+func TestApply_MergePatch(t *testing.T) {
+	doc := `{"model":"opus","env":{"A":"1","B":"2"},"permissions":{"allow":["Read"]}}`
+	patch := `{"env":{"A":null,"C":"3"},"permissions":{"allow":["Bash"]},"hooks":{"Stop":[]}}`
+	want := `{"model":"opus","env":{"B":"2","C":"3"},"permissions":{"allow":["Bash"]},"hooks":{"Stop":[]}}`
+	got, err := apply(t, doc, patch)
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+}
+
+func TestParse_Validation(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch string
+		want  string
+	}{
+		{"scalar", `"x"`, "got string"},
+		{"unknown op", `[{"op":"frob","path":"/a"}]`, `operation 0: unknown op "frob"`},
+		{"missing value", `[{"op":"add","path":"/a"}]`, `missing "value"`},
+		{"missing from", `[{"op":"copy","path":"/a"}]`, `missing "from"`},
+		{"non-string path", `[{"op":"remove","path":1}]`, `"path" must be a string`},
+		{"invalid JSON", `[`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.patch))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+	p, err := Parse([]byte(`{"a":1}`))
+	if err != nil || p.Kind != MergePatch {
+		t.Fatalf("expected a merge patch, got %+v, %v", p, err)
+	}
+}
@@ -0,0 +1,174 @@
+package ccs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/OpenGG/claude-code-switch-settings/internal/ccs/jsondoc"
+)
+
+// KeyEditOptions selects the file changed by SetKey and UnsetKey.
+type KeyEditOptions struct {
+	// Profile edits the named stored profile instead of settings.json.
+	Profile string
+	// NoValidate skips the settings schema check.
+	NoValidate bool
+}
+
+// KeyEdit reports the result of SetKey or UnsetKey.
+type KeyEdit struct {
+	// File is the settings file that was edited.
+	File string
+	// Profile is the edited profile, or empty for settings.json.
+	Profile string
+	Change  KeyChange
+	// Changed is false when the file already held the requested value; in
+	// that case nothing was written.
+	Changed bool
+	// Warnings lists schema warnings for the edited settings.
+	Warnings []SchemaIssue
+}
+
+// settingsDoc is a parsed settings file about to be read or edited.
+type settingsDoc struct {
+	profile  string
+	file     string
+	original []byte
+	existed  bool
+	doc      any
+}
+
+// loadSettingsDoc reads settings.json, or the named profile when profile is
+// non-empty. A missing settings.json is an empty document; a missing profile
+// is an error.
+func (m *Manager) loadSettingsDoc(profile string) (*settingsDoc, error) {
+	sd := &settingsDoc{file: m.paths.ActiveSettingsPath(), doc: jsondoc.NewObject()}
+	if profile != "" {
+		normalized, err := m.normalizeSettingsName(profile)
+		if err != nil {
+			return nil, err
+		}
+		profile = normalized
+		sd.profile = normalized
+		sd.file = m.paths.StoredSettingsPath(normalized)
+	}
+	data, err := m.storage.ReadFile(sd.file)
+	switch {
+	case err == nil:
+		sd.original, sd.existed = data, true
+		if sd.doc, err = jsondoc.Parse(data); err != nil {
+			return nil, fmt.Errorf("%s: %w", sd.file, err)
+		}
+	case errors.Is(err, os.ErrNotExist) && profile == "":
+	case errors.Is(err, os.ErrNotExist):
+		return nil, fmt.Errorf("settings '%s' not found", profile)
+	default:
+		return nil, fmt.Errorf("failed to read %s: %w", sd.file, err)
+	}
+	return sd, nil
+}
+
+// GetKey returns the value at keyPath in settings.json, or in the named
+// profile when profile is non-empty, and whether it is set.
+func (m *Manager) GetKey(keyPath, profile string) (any, bool, error) {
+	if err := m.InitInfra(); err != nil {
+		return nil, false, err
+	}
+	path, err := jsondoc.ParsePath(keyPath)
+	if err != nil {
+		return nil, false, err
+	}
+	sd, err := m.loadSettingsDoc(profile)
+	if err != nil {
+		return nil, false, err
+	}
+	value, present := jsondoc.Get(sd.doc, path)
+	return value, present, nil
+}
+
+// SetKey stores value at keyPath, creating missing parent objects. The order
+// and indentation of the other keys are preserved. The file is snapshotted
+// before it is replaced atomically; a missing settings.json is created.
+//
+// Returns a *ValidationError, leaving the file untouched, when the result has
+// schema errors and opts.NoValidate is false.
+func (m *Manager) SetKey(keyPath string, value any, opts KeyEditOptions) (KeyEdit, error) {
+	return m.editKey("set", keyPath, opts, func(doc any, path jsondoc.Path) (any, error) {
+		return jsondoc.Set(doc, path, value)
+	})
+}
+
+// UnsetKey removes the value at keyPath like SetKey stores one. Removing a key
+// that is not set changes nothing.
+func (m *Manager) UnsetKey(keyPath string, opts KeyEditOptions) (KeyEdit, error) {
+	return m.editKey("unset", keyPath, opts, func(doc any, path jsondoc.Path) (any, error) {
+		doc, _, err := jsondoc.Delete(doc, path)
+		return doc, err
+	})
+}
+
+// keyEdit is a computed edit of one settings file, not yet written.
+type keyEdit struct {
+	doc     *settingsDoc
+	result  KeyEdit
+	content []byte
+}
+
+type editFunc func(doc any, path jsondoc.Path) (any, error)
+
+func (m *Manager) editKey(op, keyPath string, opts KeyEditOptions, edit editFunc) (KeyEdit, error) {
+	if err := m.InitInfra(); err != nil {
+		return KeyEdit{}, err
+	}
+	path, err := jsondoc.ParsePath(keyPath)
+	if err != nil {
+		return KeyEdit{}, err
+	}
+	sd, err := m.loadSettingsDoc(opts.Profile)
+	if err != nil {
+		return KeyEdit{}, err
+	}
+	planned, err := m.planKeyEdit(sd, path, opts.NoValidate, edit)
+	if err != nil {
+		return KeyEdit{}, err
+	}
+	if !planned.result.Changed {
+		return planned.result, nil
+	}
+	if _, err := m.backup.Snapshot(op+" "+planned.result.Change.Key, sd.file); err != nil {
+		return KeyEdit{}, err
+	}
+	if err := m.storage.WriteFileAtomic(sd.file, planned.content); err != nil {
+		return KeyEdit{}, fmt.Errorf("failed to write %s: %w", sd.file, err)
+	}
+	m.applyRetention()
+	return planned.result, nil
+}
+
+// planKeyEdit applies edit to a copy of sd and encodes the result. Changed is
+// set when the value at path differs afterwards.
+func (m *Manager) planKeyEdit(sd *settingsDoc, path jsondoc.Path, noValidate bool, edit editFunc) (*keyEdit, error) {
+	planned := &keyEdit{doc: sd, result: KeyEdit{File: sd.file, Profile: sd.profile, Change: KeyChange{Key: path.String()}}}
+	change := &planned.result.Change
+	change.Old, change.OldPresent = jsondoc.Get(sd.doc, path)
+	doc, err := edit(jsondoc.Clone(sd.doc), path)
+	if err != nil {
+		return nil, err
+	}
+	change.New, change.NewPresent = jsondoc.Get(doc, path)
+	if change.OldPresent == change.NewPresent && (!change.OldPresent || jsondoc.Equal(change.Old, change.New)) {
+		return planned, nil
+	}
+
+	if planned.content, err = jsondoc.MarshalLike(doc, sd.original); err != nil {
+		return nil, err
+	}
+	if !noValidate {
+		if planned.result.Warnings, err = m.validateContent(sd.file, planned.content); err != nil {
+			return nil, err
+		}
+	}
+	planned.result.Changed = true
+	return planned, nil
+}
@@ -0,0 +1,83 @@
+package ccs
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+func TestSetKeyPreservesOrderAndSnapshots(t *testing.T) {
+	mgr := newTestManager(t)
+	fs := mgr.FileSystem()
+	original := "{\n    \"model\": \"haiku\",\n    \"env\": {\n        \"A\": \"1\"\n    }\n}\n"
+	if err := afero.WriteFile(fs, mgr.ActiveSettingsPath(), []byte(original), 0o644); err != nil {
+		t.Fatalf("write active: %v", err)
+	}
+
+	edit, err := mgr.SetKey("env.B", "2", KeyEditOptions{})
+	if err != nil {
+		t.Fatalf("SetKey: %v", err)
+	}
+	if !edit.Changed || edit.Change.OldPresent || edit.Change.New != "2" {
+		t.Fatalf("unexpected edit: %+v", edit)
+	}
+	want := "{\n    \"model\": \"haiku\",\n    \"env\": {\n        \"A\": \"1\",\n        \"B\": \"2\"\n    }\n}\n"
+	if data, _ := afero.ReadFile(fs, mgr.ActiveSettingsPath()); string(data) != want {
+		t.Fatalf("settings.json = %q, want %q", data, want)
+	}
+
+	snaps, err := mgr.Snapshots()
+	if err != nil {
+		t.Fatalf("Snapshots: %v", err)
+	}
+	if len(snaps) != 1 || snaps[0].Operation != "set env.B" {
+		t.Fatalf("expected a set snapshot, got %+v", snaps)
+	}
+
+	if edit, err := mgr.SetKey("env.B", "2", KeyEditOptions{}); err != nil || edit.Changed {
+		t.Fatalf("setting the same value should be a no-op: %+v, %v", edit, err)
+	}
+
+	value, present, err := mgr.GetKey("/env/B", "")
+	if err != nil || !present || value != "2" {
+		t.Fatalf("GetKey = %v, %v, %v", value, present, err)
+	}
+
+	edit, err = mgr.UnsetKey("model", KeyEditOptions{})
+	if err != nil || !edit.Changed || edit.Change.Old != "haiku" || edit.Change.NewPresent {
+		t.Fatalf("UnsetKey: %+v, %v", edit, err)
+	}
+	if _, present, _ := mgr.GetKey("model", ""); present {
+		t.Fatal("model should be removed")
+	}
+	if edit, err := mgr.UnsetKey("model", KeyEditOptions{}); err != nil || edit.Changed {
+		t.Fatalf("unsetting a missing key should be a no-op: %+v, %v", edit, err)
+	}
+}
+
+func TestSetKeyOnProfileValidatesSchema(t *testing.T) {
+	mgr := newTestManager(t)
+	profile := filepath.Join(mgr.SettingsStoreDir(), "work.json")
+	if err := afero.WriteFile(mgr.FileSystem(), profile, []byte(`{"model": "opus"}`), 0o644); err != nil {
+		t.Fatalf("write profile: %v", err)
+	}
+
+	var verr *ValidationError
+	if _, err := mgr.SetKey("permissions.allow", "Read", KeyEditOptions{Profile: "work"}); !errors.As(err, &verr) {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+	if data, _ := afero.ReadFile(mgr.FileSystem(), profile); string(data) != `{"model": "opus"}` {
+		t.Fatalf("rejected edit must not be written: %q", data)
+	}
+	if _, err := mgr.SetKey("permissions.allow", "Read", KeyEditOptions{Profile: "work", NoValidate: true}); err != nil {
+		t.Fatalf("SetKey --no-validate: %v", err)
+	}
+	if _, err := mgr.SetKey("model", "opus", KeyEditOptions{Profile: "missing"}); err == nil {
+		t.Fatal("expected an error for a missing profile")
+	}
+	if _, _, err := mgr.GetKey("model[", "work"); err == nil {
+		t.Fatal("expected an error for an invalid path")
+	}
+}
@@ -0,0 +1,168 @@
+package ccs
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/OpenGG/claude-code-switch-settings/internal/ccs/jsondoc"
+)
+
+// KeyChange describes how restoring one key changes the target file.
+type KeyChange struct {
+	Key string
+	// Old is the current value; OldPresent is false when the key is absent.
+	Old        any
+	OldPresent bool
+	// New is the value from the backup; NewPresent is false when the key is
+	// absent there, in which case restoring removes it.
+	New        any
+	NewPresent bool
+}
+
+// KeyRestoreOptions adjusts PlanKeyRestore.
+type KeyRestoreOptions struct {
+	// Profile restores into the named stored profile instead of settings.json.
+	Profile string
+	// NoValidate skips the settings schema check.
+	NoValidate bool
+}
+
+// KeyRestore is a planned partial restore, created by PlanKeyRestore and
+// carried out by ApplyKeyRestore.
+type KeyRestore struct {
+	// Hash is the full hash of the source backup.
+	Hash string
+	// File is the settings file that will be written.
+	File string
+	// Changes lists the keys whose value differs from the backup. Keys that
+	// already match are left out.
+	Changes []KeyChange
+	// Warnings holds schema warnings for the merged content.
+	Warnings []SchemaIssue
+
+	original []byte
+	existed  bool
+	merged   []byte
+}
+
+// PlanKeyRestore computes the result of merging the values at keys from the
+// backup with the given hash (or unique hash prefix) into settings.json, or
+// into opts.Profile when it is non-empty. Nothing is written.
+//
+// Keys use the same path syntax as Blame. A key missing from the backup is
+// removed from the target. All other content of the target, including key
+// order and formatting width, is preserved. Unless opts.NoValidate is set,
+// the merged content is checked against the settings schema and a
+// *ValidationError is returned when it has errors.
+func (m *Manager) PlanKeyRestore(hash string, keys []string, opts KeyRestoreOptions) (*KeyRestore, error) {
+	if err := m.InitInfra(); err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no keys to restore")
+	}
+	paths := make([]jsondoc.Path, 0, len(keys))
+	for _, key := range keys {
+		path, err := jsondoc.ParsePath(strings.TrimSpace(key))
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+
+	id, err := m.backup.ResolveHash(hash)
+	if err != nil {
+		return nil, err
+	}
+	data, err := m.backup.ReadBackup(id)
+	if err != nil {
+		return nil, err
+	}
+	source, err := jsondoc.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("backup %s: %w", id, err)
+	}
+
+	profile := opts.Profile
+	plan := &KeyRestore{Hash: id, File: m.paths.ActiveSettingsPath()}
+	if profile != "" {
+		normalized, err := m.normalizeSettingsName(profile)
+		if err != nil {
+			return nil, err
+		}
+		plan.File = m.paths.StoredSettingsPath(normalized)
+	}
+	var target any = jsondoc.NewObject()
+	plan.original, err = m.storage.ReadFile(plan.File)
+	switch {
+	case err == nil:
+		plan.existed = true
+		if target, err = jsondoc.Parse(plan.original); err != nil {
+			return nil, fmt.Errorf("%s: %w", plan.File, err)
+		}
+	case errors.Is(err, os.ErrNotExist) && profile == "":
+	case errors.Is(err, os.ErrNotExist):
+		return nil, fmt.Errorf("settings '%s' not found", profile)
+	default:
+		return nil, fmt.Errorf("failed to read %s: %w", plan.File, err)
+	}
+
+	for _, path := range paths {
+		change := KeyChange{Key: path.String()}
+		change.Old, change.OldPresent = jsondoc.Get(target, path)
+		change.New, change.NewPresent = jsondoc.Get(source, path)
+		if change.OldPresent == change.NewPresent && (!change.OldPresent || jsondoc.Equal(change.Old, change.New)) {
+			continue
+		}
+		if change.NewPresent {
+			target, err = jsondoc.Set(target, path, jsondoc.Clone(change.New))
+		} else {
+			target, _, err = jsondoc.Delete(target, path)
+		}
+		if err != nil {
+			return nil, err
+		}
+		plan.Changes = append(plan.Changes, change)
+	}
+
+	if plan.merged, err = jsondoc.MarshalLike(target, plan.original); err != nil {
+		return nil, err
+	}
+	if len(plan.Changes) > 0 && !opts.NoValidate {
+		if plan.Warnings, err = m.validateContent(plan.File, plan.merged); err != nil {
+			return nil, err
+		}
+	}
+	return plan, nil
+}
+
+// ApplyKeyRestore writes a plan created by PlanKeyRestore. The target is
+// snapshotted first and replaced atomically. If the target changed since the
+// plan was made, nothing is written and an error is returned.
+func (m *Manager) ApplyKeyRestore(plan *KeyRestore) error {
+	if err := m.InitInfra(); err != nil {
+		return err
+	}
+	if len(plan.Changes) == 0 {
+		return nil
+	}
+	current, err := m.storage.ReadFile(plan.File)
+	exists := err == nil
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read %s: %w", plan.File, err)
+	}
+	if exists != plan.existed || !bytes.Equal(current, plan.original) {
+		return fmt.Errorf("%s changed since the restore was planned; run the restore again", plan.File)
+	}
+	if _, err := m.backup.Snapshot("restore keys from "+ShortHash(plan.Hash), plan.File); err != nil {
+		return err
+	}
+	if err := m.storage.WriteFileAtomic(plan.File, plan.merged); err != nil {
+		return fmt.Errorf("failed to write %s: %w", plan.File, err)
+	}
+	m.applyRetention()
+	return nil
+}
@@ -0,0 +1,126 @@
+package ccs
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+// setupKeyRestore records a backup of old settings.json content by switching
+// to a "work" profile, and returns the backup hash.
+func setupKeyRestore(t *testing.T, mgr *Manager, old, work string) string {
+	t.Helper()
+	fs := mgr.FileSystem()
+	if err := afero.WriteFile(fs, mgr.ActiveSettingsPath(), []byte(old), 0o644); err != nil {
+		t.Fatalf("write active: %v", err)
+	}
+	if err := afero.WriteFile(fs, filepath.Join(mgr.SettingsStoreDir(), "work.json"), []byte(work), 0o644); err != nil {
+		t.Fatalf("write profile: %v", err)
+	}
+	if err := mgr.Use("work"); err != nil {
+		t.Fatalf("use: %v", err)
+	}
+	snaps, err := mgr.Snapshots()
+	if err != nil || len(snaps) != 1 {
+		t.Fatalf("snapshots: %v, %v", snaps, err)
+	}
+	return snaps[0].Files[0].Hash
+}
+
+func TestKeyRestoreMergesOnlySelectedKeys(t *testing.T) {
+	mgr := newTestManager(t)
+	hash := setupKeyRestore(t, mgr,
+		`{"model": "opus", "permissions": {"allow": ["Read"]}, "env": {"A": "1"}}`,
+		"{\n    \"model\": \"sonnet\",\n    \"permissions\": {\"allow\": []},\n    \"env\": {\"A\": \"2\", \"B\": \"3\"}\n}\n")
+
+	plan, err := mgr.PlanKeyRestore(hash[:10], []string{"permissions.allow", "env.B", "env.A"}, KeyRestoreOptions{})
+	if err != nil {
+		t.Fatalf("plan: %v", err)
+	}
+	if plan.Hash != hash || len(plan.Changes) != 3 {
+		t.Fatalf("unexpected plan: %+v", plan)
+	}
+	if b := plan.Changes[1]; b.Key != "env.B" || !b.OldPresent || b.NewPresent {
+		t.Fatalf("expected env.B to be removed: %+v", b)
+	}
+	if err := mgr.ApplyKeyRestore(plan); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+
+	content, err := afero.ReadFile(mgr.FileSystem(), mgr.ActiveSettingsPath())
+	if err != nil {
+		t.Fatalf("read active: %v", err)
+	}
+	want := "{\n    \"model\": \"sonnet\",\n    \"permissions\": {\n        \"allow\": [\n            \"Read\"\n        ]\n    },\n    \"env\": {\n        \"A\": \"1\"\n    }\n}\n"
+	if string(content) != want {
+		t.Fatalf("unexpected merged settings:\n%s", content)
+	}
+
+	snaps, err := mgr.Snapshots()
+	if err != nil || len(snaps) != 2 || !strings.HasPrefix(snaps[0].Operation, "restore keys from ") {
+		t.Fatalf("expected the target to be snapshotted first: %+v, %v", snaps, err)
+	}
+}
+
+func TestKeyRestoreRefusesChangedTarget(t *testing.T) {
+	mgr := newTestManager(t)
+	hash := setupKeyRestore(t, mgr, `{"model": "opus"}`, `{"model": "sonnet"}`)
+
+	plan, err := mgr.PlanKeyRestore(hash, []string{"model"}, KeyRestoreOptions{})
+	if err != nil {
+		t.Fatalf("plan: %v", err)
+	}
+	if err := afero.WriteFile(mgr.FileSystem(), mgr.ActiveSettingsPath(), []byte(`{"model": "haiku"}`), 0o644); err != nil {
+		t.Fatalf("edit active: %v", err)
+	}
+	if err := mgr.ApplyKeyRestore(plan); err == nil || !strings.Contains(err.Error(), "changed since") {
+		t.Fatalf("expected changed-target error, got %v", err)
+	}
+}
+
+func TestKeyRestoreIntoProfile(t *testing.T) {
+	mgr := newTestManager(t)
+	hash := setupKeyRestore(t, mgr, `{"model": "opus"}`, `{"model": "sonnet"}`)
+
+	plan, err := mgr.PlanKeyRestore(hash, []string{"model", "env.MISSING"}, KeyRestoreOptions{Profile: "work"})
+	if err != nil {
+		t.Fatalf("plan: %v", err)
+	}
+	if len(plan.Changes) != 1 || plan.File != filepath.Join(mgr.SettingsStoreDir(), "work.json") {
+		t.Fatalf("unexpected plan: %+v", plan)
+	}
+	if err := mgr.ApplyKeyRestore(plan); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	content, _ := afero.ReadFile(mgr.FileSystem(), plan.File)
+	if string(content) != "{\n  \"model\": \"opus\"\n}" {
+		t.Fatalf("unexpected profile content: %q", content)
+	}
+
+	if _, err := mgr.PlanKeyRestore(hash, []string{"model"}, KeyRestoreOptions{Profile: "missing"}); err == nil {
+		t.Fatal("expected error for unknown profile")
+	}
+	if _, err := mgr.PlanKeyRestore(hash, nil, KeyRestoreOptions{}); err == nil {
+		t.Fatal("expected error without keys")
+	}
+}
+
+func TestKeyRestoreValidatesMergedContent(t *testing.T) {
+	mgr := newTestManager(t)
+	hash := setupKeyRestore(t, mgr, `{"model": 42}`, `{"model": "sonnet"}`)
+
+	var verr *ValidationError
+	if _, err := mgr.PlanKeyRestore(hash, []string{"model"}, KeyRestoreOptions{}); !errors.As(err, &verr) {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+	plan, err := mgr.PlanKeyRestore(hash, []string{"model"}, KeyRestoreOptions{NoValidate: true})
+	if err != nil {
+		t.Fatalf("plan without validation: %v", err)
+	}
+	if len(plan.Changes) != 1 {
+		t.Fatalf("unexpected plan: %+v", plan)
+	}
+}
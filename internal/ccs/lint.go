@@ -0,0 +1,113 @@
+package ccs
+
+import (
+	"fmt"
+
+	"github.com/OpenGG/claude-code-switch-settings/internal/ccs/config"
+	"github.com/OpenGG/claude-code-switch-settings/internal/ccs/jsondoc"
+	"github.com/OpenGG/claude-code-switch-settings/internal/ccs/lint"
+	"github.com/OpenGG/claude-code-switch-settings/internal/ccs/schema"
+)
+
+// LintFinding is one problem reported by ccs lint.
+type LintFinding = lint.Finding
+
+// LintReport holds the findings for one settings file.
+type LintReport struct {
+	// Name is the profile name, or empty for the live settings.json.
+	Name     string
+	File     string
+	Findings []LintFinding
+}
+
+// schemaRuleID names the lint rule that reports settings schema issues.
+const schemaRuleID = "schema"
+
+// loadLintRules returns the built-in rules followed by the rules in
+// lint.rulesFile, minus those listed in lint.disable.
+func (m *Manager) loadLintRules(c config.Lint) ([]lint.Rule, error) {
+	rules := lint.Builtin()
+	if c.RulesFile != "" {
+		data, err := m.storage.ReadFile(m.paths.ExpandHome(c.RulesFile))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read rules file: %w", err)
+		}
+		user, err := lint.ParseRules(data)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, user...)
+	}
+	known := map[string]bool{schemaRuleID: true}
+	for _, rule := range rules {
+		if known[rule.ID()] {
+			return nil, fmt.Errorf("rule %q is already defined", rule.ID())
+		}
+		known[rule.ID()] = true
+	}
+	disabled := make(map[string]bool, len(c.Disable))
+	for _, id := range c.Disable {
+		if !known[id] {
+			return nil, fmt.Errorf("disable: unknown rule %q", id)
+		}
+		disabled[id] = true
+	}
+	kept := []lint.Rule{}
+	if !disabled[schemaRuleID] {
+		kept = append(kept, lint.NewRule(schemaRuleID, m.schemaFindings))
+	}
+	for _, rule := range rules {
+		if !disabled[rule.ID()] {
+			kept = append(kept, rule)
+		}
+	}
+	return kept, nil
+}
+
+// schemaFindings reports settings schema issues as lint findings.
+func (m *Manager) schemaFindings(t lint.Target) []lint.Finding {
+	var findings []lint.Finding
+	for _, issue := range m.schema.Validate(t.Doc) {
+		severity := lint.Warning
+		if issue.Severity == schema.Error {
+			severity = lint.Error
+		}
+		findings = append(findings, lint.Finding{Severity: severity, Path: issue.Path, Message: issue.Message})
+	}
+	return findings
+}
+
+// Lint checks settings files against the lint rules: the settings schema,
+// the built-in rules and any user rules from lint.rulesFile.
+//
+// With no names and all false, the live settings.json is checked, matched
+// against profile-scoped rules by the active profile name. With all, every
+// stored profile is checked. A file that is not valid JSON yields a single
+// "json" error finding.
+func (m *Manager) Lint(names []string, all bool) ([]LintReport, error) {
+	if err := m.InitInfra(); err != nil {
+		return nil, err
+	}
+	targets, err := m.selectFiles(names, all)
+	if err != nil {
+		return nil, err
+	}
+
+	engine := lint.New(m.lintRules...)
+	reports := make([]LintReport, 0, len(targets))
+	for _, t := range targets {
+		data, err := m.storage.ReadFile(t.file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", t.file, err)
+		}
+		report := LintReport{Name: t.profile, File: t.file}
+		doc, err := jsondoc.Parse(data)
+		if err != nil {
+			report.Findings = []LintFinding{{Rule: "json", Severity: lint.Error, Message: err.Error()}}
+		} else {
+			report.Findings = engine.Run(lint.Target{Name: t.match, Doc: doc})
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
@@ -0,0 +1,96 @@
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/OpenGG/claude-code-switch-settings/internal/ccs/jsondoc"
+	"github.com/OpenGG/claude-code-switch-settings/internal/ccs/secrets"
+)
+
+// Builtin returns the built-in rules:
+//
+//   - no-bash-wildcard: permissions.allow must not allow every shell command
+//   - no-bypass-permissions: permissions.defaultMode must not be bypassPermissions
+//   - no-plaintext-token: no value may look like a secret (see secrets.Scan)
+//     and env must not hold credentials in plain text
+func Builtin() []Rule {
+	return []Rule{
+		NewRule("no-bash-wildcard", checkBashWildcard),
+		NewRule("no-bypass-permissions", checkBypassMode),
+		NewRule("no-plaintext-token", checkPlaintextTokens),
+	}
+}
+
+var bashWildcards = map[string]bool{"Bash": true, "Bash(*)": true, "Bash(*:*)": true, "Bash(:*)": true}
+
+func checkBashWildcard(t Target) []Finding {
+	allow, _ := jsondoc.Get(t.Doc, jsondoc.Path{{Key: "permissions"}, {Key: "allow"}})
+	rules, _ := allow.([]any)
+	var findings []Finding
+	for i, rule := range rules {
+		s, _ := rule.(string)
+		if bashWildcards[strings.ReplaceAll(s, " ", "")] {
+			findings = append(findings, Finding{
+				Severity: Error,
+				Path:     fmt.Sprintf("permissions.allow[%d]", i),
+				Message:  fmt.Sprintf("%q allows every shell command; allow specific commands instead", s),
+			})
+		}
+	}
+	return findings
+}
+
+func checkBypassMode(t Target) []Finding {
+	mode, _ := jsondoc.Get(t.Doc, jsondoc.Path{{Key: "permissions"}, {Key: "defaultMode"}})
+	if mode != "bypassPermissions" {
+		return nil
+	}
+	return []Finding{{
+		Severity: Error,
+		Path:     "permissions.defaultMode",
+		Message:  "bypassPermissions skips every permission prompt",
+	}}
+}
+
+// secretKeyPattern matches environment variable names that usually hold
+// credentials.
+var secretKeyPattern = regexp.MustCompile(`(?i)(TOKEN|SECRET|PASSWORD|PASSWD|API_?KEY|CREDENTIALS?)$`)
+
+// checkPlaintextTokens reports the values secrets.Scan flags, so lint and
+// the secret check on save, export and import agree on the same document.
+// Literal env values named like credentials are reported too, since a short
+// or unknown token format does not look like a secret to the scanner.
+func checkPlaintextTokens(t Target) []Finding {
+	var findings []Finding
+	reported := make(map[string]bool)
+	for _, f := range secrets.Scan(t.Doc) {
+		reported[f.Path] = true
+		findings = append(findings, Finding{
+			Severity: Error,
+			Path:     f.Path,
+			Message:  fmt.Sprintf("%s stored in plain text (%s); use apiKeyHelper or an environment reference", f.Kind, f.Redacted),
+		})
+	}
+
+	env, _ := jsondoc.Get(t.Doc, jsondoc.Path{{Key: "env"}})
+	obj, ok := env.(*jsondoc.Object)
+	if !ok {
+		return findings
+	}
+	for _, key := range obj.Keys() {
+		v, _ := obj.Get(key)
+		s, _ := v.(string)
+		path := jsondoc.Path{{Key: "env"}, {Key: key}}.String()
+		if s == "" || strings.HasPrefix(s, "$") || !secretKeyPattern.MatchString(key) || reported[path] {
+			continue
+		}
+		findings = append(findings, Finding{
+			Severity: Error,
+			Path:     path,
+			Message:  "credential stored in plain text; use apiKeyHelper or an environment reference",
+		})
+	}
+	return findings
+}
@@ -0,0 +1,163 @@
+// Package lint runs team-specific checks over settings documents.
+//
+// Checks are Rules. Built-in rules cover common risks such as wildcard shell
+// permissions; user rules loaded with ParseRules assert a condition on one
+// JSON path. Documents are values decoded by jsondoc.Parse.
+package lint
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Severity ranks a finding.
+type Severity int
+
+const (
+	Info Severity = iota
+	Warning
+	Error
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Error:
+		return "error"
+	case Warning:
+		return "warning"
+	default:
+		return "info"
+	}
+}
+
+// MarshalText encodes the severity by name, so JSON output is readable.
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText decodes a severity name written by MarshalText.
+func (s *Severity) UnmarshalText(text []byte) error {
+	parsed, err := ParseSeverity(string(text))
+	if err != nil {
+		return err
+	}
+	*s = parsed
+	return nil
+}
+
+// ParseSeverity converts "info", "warning" or "error" to a Severity. An empty
+// string means Warning.
+func ParseSeverity(s string) (Severity, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "info":
+		return Info, nil
+	case "", "warning", "warn":
+		return Warning, nil
+	case "error":
+		return Error, nil
+	default:
+		return Warning, fmt.Errorf("unknown severity %q (want info, warning or error)", s)
+	}
+}
+
+// Finding is one problem reported by a rule.
+type Finding struct {
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	// Path locates the offending value in dotted form; empty for the root.
+	Path    string `json:"path,omitempty"`
+	Message string `json:"message"`
+}
+
+func (f Finding) String() string {
+	if f.Path == "" {
+		return fmt.Sprintf("%s [%s] %s", f.Severity, f.Rule, f.Message)
+	}
+	return fmt.Sprintf("%s [%s] %s: %s", f.Severity, f.Rule, f.Path, f.Message)
+}
+
+// Target is a settings document being linted.
+type Target struct {
+	// Name is the profile name, or "settings.json" for the live file.
+	Name string
+	Doc  any
+}
+
+// Rule checks a settings document.
+type Rule interface {
+	// ID names the rule in findings and in the lint.disable configuration.
+	ID() string
+	Check(t Target) []Finding
+}
+
+type funcRule struct {
+	id    string
+	check func(Target) []Finding
+}
+
+func (r funcRule) ID() string               { return r.id }
+func (r funcRule) Check(t Target) []Finding { return r.check(t) }
+
+// NewRule adapts a function into a Rule.
+func NewRule(id string, check func(Target) []Finding) Rule {
+	return funcRule{id: id, check: check}
+}
+
+// Engine runs a set of rules.
+type Engine struct {
+	rules []Rule
+}
+
+// New returns an Engine running rules in order.
+func New(rules ...Rule) *Engine {
+	return &Engine{rules: rules}
+}
+
+// Rules returns the rules in the order they run.
+func (e *Engine) Rules() []Rule {
+	return append([]Rule(nil), e.rules...)
+}
+
+// Run applies every rule to t. Findings are grouped by rule, in rule order.
+func (e *Engine) Run(t Target) []Finding {
+	var findings []Finding
+	for _, rule := range e.rules {
+		for _, f := range rule.Check(t) {
+			if f.Rule == "" {
+				f.Rule = rule.ID()
+			}
+			findings = append(findings, f)
+		}
+	}
+	return findings
+}
+
+// Highest returns the highest severity among findings, and false when there
+// are none.
+func Highest(findings []Finding) (Severity, bool) {
+	if len(findings) == 0 {
+		return Info, false
+	}
+	highest := Info
+	for _, f := range findings {
+		if f.Severity > highest {
+			highest = f.Severity
+		}
+	}
+	return highest, true
+}
+
+// matchesProfile reports whether name matches any of the glob patterns; an
+// empty pattern list matches every target.
+func matchesProfile(name string, patterns []string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		if ok, _ := filepath.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
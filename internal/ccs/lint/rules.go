@@ -0,0 +1,258 @@
+package lint
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/OpenGG/claude-code-switch-settings/internal/ccs/jsondoc"
+)
+
+// RuleSpec is a user rule as written in a rules file. The rule asserts that
+// Condition holds for the value at Path and reports Message when it does not.
+type RuleSpec struct {
+	ID   string `json:"id"`
+	Path string `json:"path"`
+	// Condition is one of exists, absent, equals, notEquals, contains,
+	// notContains, matches, notMatches or type. Except for exists and absent,
+	// conditions are only checked when the path is present.
+	Condition string          `json:"condition"`
+	Value     json.RawMessage `json:"value,omitempty"`
+	Severity  string          `json:"severity,omitempty"`
+	Message   string          `json:"message,omitempty"`
+	// Profiles limits the rule to targets whose name matches one of these
+	// glob patterns, such as "proxy-*".
+	Profiles []string `json:"profiles,omitempty"`
+}
+
+type rulesFile struct {
+	Rules []RuleSpec `json:"rules"`
+}
+
+// ParseRules decodes a rules file of the form {"rules": [...]}.
+func ParseRules(data []byte) ([]Rule, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var file rulesFile
+	if err := dec.Decode(&file); err != nil {
+		return nil, fmt.Errorf("invalid rules file: %w", err)
+	}
+	seen := make(map[string]bool, len(file.Rules))
+	rules := make([]Rule, 0, len(file.Rules))
+	for i, spec := range file.Rules {
+		rule, err := compileRule(spec)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i+1, err)
+		}
+		if seen[rule.id] {
+			return nil, fmt.Errorf("rule %d: duplicate id %q", i+1, rule.id)
+		}
+		seen[rule.id] = true
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+type pathRule struct {
+	id        string
+	path      jsondoc.Path
+	condition string
+	value     any
+	pattern   *regexp.Regexp
+	severity  Severity
+	message   string
+	profiles  []string
+}
+
+func compileRule(spec RuleSpec) (*pathRule, error) {
+	id := strings.TrimSpace(spec.ID)
+	if id == "" {
+		return nil, fmt.Errorf("id is required")
+	}
+	path, err := jsondoc.ParsePath(spec.Path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", id, err)
+	}
+	severity, err := ParseSeverity(spec.Severity)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", id, err)
+	}
+	for _, p := range spec.Profiles {
+		if _, err := filepath.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("%s: invalid profile pattern %q", id, p)
+		}
+	}
+	rule := &pathRule{
+		id:        id,
+		path:      path,
+		condition: spec.Condition,
+		severity:  severity,
+		message:   spec.Message,
+		profiles:  spec.Profiles,
+	}
+
+	switch spec.Condition {
+	case "exists", "absent":
+		if len(spec.Value) > 0 {
+			return nil, fmt.Errorf("%s: condition %q takes no value", id, spec.Condition)
+		}
+		return rule, nil
+	case "equals", "notEquals", "contains", "notContains", "matches", "notMatches", "type":
+	default:
+		return nil, fmt.Errorf("%s: unknown condition %q", id, spec.Condition)
+	}
+	if len(spec.Value) == 0 {
+		return nil, fmt.Errorf("%s: condition %q requires a value", id, spec.Condition)
+	}
+	value, err := jsondoc.Parse(spec.Value)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", id, err)
+	}
+	rule.value = value
+
+	switch spec.Condition {
+	case "matches", "notMatches":
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s: condition %q requires a string pattern", id, spec.Condition)
+		}
+		if rule.pattern, err = regexp.Compile(s); err != nil {
+			return nil, fmt.Errorf("%s: invalid pattern: %w", id, err)
+		}
+	case "type":
+		switch value {
+		case "object", "array", "string", "number", "boolean", "null":
+		default:
+			return nil, fmt.Errorf("%s: unknown type %v", id, value)
+		}
+	}
+	return rule, nil
+}
+
+func (r *pathRule) ID() string { return r.id }
+
+func (r *pathRule) Check(t Target) []Finding {
+	if !matchesProfile(t.Name, r.profiles) {
+		return nil
+	}
+	path := r.path.String()
+	got, present := jsondoc.Get(t.Doc, r.path)
+	fail := func(p, detail string) []Finding {
+		msg := r.message
+		if msg == "" {
+			msg = detail
+		}
+		return []Finding{{Rule: r.id, Severity: r.severity, Path: p, Message: msg}}
+	}
+
+	switch r.condition {
+	case "exists":
+		if !present {
+			return fail(path, "is required")
+		}
+		return nil
+	case "absent":
+		if present {
+			return fail(path, "must not be set")
+		}
+		return nil
+	}
+	if !present {
+		return nil
+	}
+
+	switch r.condition {
+	case "equals":
+		if !jsondoc.Equal(got, r.value) {
+			return fail(path, "must equal "+compact(r.value))
+		}
+	case "notEquals":
+		if jsondoc.Equal(got, r.value) {
+			return fail(path, "must not equal "+compact(r.value))
+		}
+	case "contains":
+		if !contains(got, r.value) {
+			return fail(path, "must contain "+compact(r.value))
+		}
+	case "notContains":
+		if i := index(got, r.value); i >= 0 {
+			return fail(fmt.Sprintf("%s[%d]", path, i), "must not contain "+compact(r.value))
+		}
+		if s, ok := got.(string); ok && containsString(s, r.value) {
+			return fail(path, "must not contain "+compact(r.value))
+		}
+	case "matches":
+		for _, el := range elements(got, path) {
+			if !r.pattern.MatchString(el.text) {
+				return fail(el.path, "must match "+r.pattern.String())
+			}
+		}
+	case "notMatches":
+		for _, el := range elements(got, path) {
+			if r.pattern.MatchString(el.text) {
+				return fail(el.path, "must not match "+r.pattern.String())
+			}
+		}
+	case "type":
+		if jsondoc.TypeName(got) != r.value {
+			return fail(path, fmt.Sprintf("must be of type %v, not %s", r.value, jsondoc.TypeName(got)))
+		}
+	}
+	return nil
+}
+
+// contains reports whether an array holds want or a string contains it.
+func contains(got, want any) bool {
+	if s, ok := got.(string); ok {
+		return containsString(s, want)
+	}
+	return index(got, want) >= 0
+}
+
+func containsString(s string, want any) bool {
+	sub, ok := want.(string)
+	return ok && strings.Contains(s, sub)
+}
+
+func index(got, want any) int {
+	arr, _ := got.([]any)
+	for i, el := range arr {
+		if jsondoc.Equal(el, want) {
+			return i
+		}
+	}
+	return -1
+}
+
+type textElement struct {
+	path string
+	text string
+}
+
+// elements returns the string at path, or every string element of an array.
+func elements(got any, path string) []textElement {
+	switch v := got.(type) {
+	case string:
+		return []textElement{{path: path, text: v}}
+	case []any:
+		var out []textElement
+		for i, el := range v {
+			if s, ok := el.(string); ok {
+				out = append(out, textElement{path: fmt.Sprintf("%s[%d]", path, i), text: s})
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func compact(v any) string {
+	data, err := jsondoc.Marshal(v, "")
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
@@ -0,0 +1,97 @@
+package ccs
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+func lintRuleIDs(report LintReport) []string {
+	var ids []string
+	for _, f := range report.Findings {
+		ids = append(ids, f.Rule+"@"+f.Path)
+	}
+	return ids
+}
+
+func TestLintAppliesBuiltinAndUserRules(t *testing.T) {
+	mgr := newTestManager(t)
+	fs := mgr.FileSystem()
+	files := map[string]string{
+		mgr.paths.ConfigPath(): `{"lint": {"rulesFile": "~/.claude/lint-rules.json", "disable": ["no-plaintext-token"]}}`,
+		"/home/test/.claude/lint-rules.json": `{"rules": [
+			{"id": "proxy-url", "path": "env.HTTPS_PROXY", "condition": "exists", "severity": "error", "profiles": ["proxy-*"]}
+		]}`,
+		filepath.Join(mgr.SettingsStoreDir(), "proxy-corp.json"): `{"permissions": {"allow": ["Bash(*)"]}, "env": {"API_TOKEN": "plain"}}`,
+		filepath.Join(mgr.SettingsStoreDir(), "personal.json"):   `{"permisions": {}}`,
+		filepath.Join(mgr.SettingsStoreDir(), "broken.json"):     `{`,
+		mgr.ActiveSettingsPath():                                 `{"permissions": {"defaultMode": "bypassPermissions"}}`,
+	}
+	for path, content := range files {
+		if err := afero.WriteFile(fs, path, []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", path, err)
+		}
+	}
+	if err := mgr.LoadConfig(); err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+
+	reports, err := mgr.Lint(nil, true)
+	if err != nil {
+		t.Fatalf("Lint --all: %v", err)
+	}
+	got := map[string]string{}
+	for _, r := range reports {
+		got[r.Name] = strings.Join(lintRuleIDs(r), ",")
+	}
+	want := map[string]string{
+		"broken":     "json@",
+		"personal":   "schema@permisions",
+		"proxy-corp": "no-bash-wildcard@permissions.allow[0],proxy-url@env.HTTPS_PROXY",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("reports = %v", got)
+	}
+	for name, ids := range want {
+		if got[name] != ids {
+			t.Errorf("%s findings = %q, want %q", name, got[name], ids)
+		}
+	}
+
+	reports, err = mgr.Lint(nil, false)
+	if err != nil {
+		t.Fatalf("Lint: %v", err)
+	}
+	if len(reports) != 1 || reports[0].Name != "" || reports[0].File != mgr.ActiveSettingsPath() {
+		t.Fatalf("expected a settings.json report, got %+v", reports)
+	}
+	if ids := lintRuleIDs(reports[0]); len(ids) != 1 || ids[0] != "no-bypass-permissions@permissions.defaultMode" {
+		t.Fatalf("settings.json findings = %v", ids)
+	}
+
+	if _, err := mgr.Lint([]string{"missing"}, false); err == nil {
+		t.Fatal("expected an error for a missing profile")
+	}
+	if _, err := mgr.Lint([]string{"personal"}, true); err == nil {
+		t.Fatal("expected an error when combining names with --all")
+	}
+}
+
+func TestLoadConfigRejectsInvalidLintConfiguration(t *testing.T) {
+	cases := map[string]string{
+		`{"lint": {"disable": ["no-such-rule"]}}`:           "unknown rule",
+		`{"lint": {"rulesFile": "~/.claude/missing.json"}}`: "failed to read rules file",
+	}
+	for config, want := range cases {
+		mgr := newTestManager(t)
+		if err := afero.WriteFile(mgr.FileSystem(), mgr.paths.ConfigPath(), []byte(config), 0o644); err != nil {
+			t.Fatalf("write config: %v", err)
+		}
+		err := mgr.LoadConfig()
+		if err == nil || !strings.Contains(err.Error(), "invalid lint configuration") || !strings.Contains(err.Error(), want) {
+			t.Errorf("LoadConfig(%s) error = %v, want %q", config, err, want)
+		}
+	}
+}
@@ -1,16 +1,26 @@
 package ccs
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
 	"time"
 
 	"github.com/spf13/afero"
 
 	"github.com/OpenGG/claude-code-switch-settings/internal/ccs/backup"
+	"github.com/OpenGG/claude-code-switch-settings/internal/ccs/config"
 	"github.com/OpenGG/claude-code-switch-settings/internal/ccs/domain"
+	"github.com/OpenGG/claude-code-switch-settings/internal/ccs/format"
+	"github.com/OpenGG/claude-code-switch-settings/internal/ccs/jsondoc"
+	"github.com/OpenGG/claude-code-switch-settings/internal/ccs/lint"
 	"github.com/OpenGG/claude-code-switch-settings/internal/ccs/paths"
+	"github.com/OpenGG/claude-code-switch-settings/internal/ccs/schema"
 	"github.com/OpenGG/claude-code-switch-settings/internal/ccs/settings"
 	"github.com/OpenGG/claude-code-switch-settings/internal/ccs/storage"
 	"github.com/OpenGG/claude-code-switch-settings/internal/ccs/validator"
@@ -37,7 +47,27 @@ var (
 //   - backup: Content-addressed backup management
 //   - settings: Settings persistence and retrieval
 type Manager struct {
-	paths *paths.PathBuilder
+	paths  *paths.PathBuilder
+	config config.Config
+	logger *slog.Logger
+	now    func() time.Time
+
+	// Automatic retention, enabled by backup.retention in the config file
+	retention         backup.RetentionPolicy
+	retentionInterval time.Duration
+
+	// Key paths left out of settings comparisons, from compare in the config file
+	ignore compareIgnores
+	// Key paths carried across profile switches, from use.sticky in the config file
+	sticky []jsondoc.Path
+	// Settings schema checked by Use and Save, replaceable by schema.file
+	schema *schema.Schema
+	// Rules run by Lint, from the built-ins and lint.rulesFile
+	lintRules []lint.Rule
+	// Profile groups for bulk edits, from tags in the config file
+	tags map[string][]string
+	// Secret detection on save, export and import, from secrets.mode
+	secretsMode secretsMode
 
 	// Services (dependency injection)
 	validator *validator.Validator
@@ -49,6 +79,10 @@ type Manager struct {
 // NewManager constructs a Manager using the provided filesystem and home directory.
 // If logger is nil, a default logger will be created that discards all output.
 func NewManager(fs afero.Fs, homeDir string, logger *slog.Logger) *Manager {
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+
 	// Create path builder
 	pathBuilder := paths.New(homeDir)
 
@@ -64,13 +98,19 @@ func NewManager(fs afero.Fs, homeDir string, logger *slog.Logger) *Manager {
 	// Create validator
 	val := validator.New()
 
-	return &Manager{
+	m := &Manager{
 		paths:     pathBuilder,
+		logger:    logger,
+		now:       time.Now,
+		schema:    schema.Default(),
 		validator: val,
 		storage:   stor,
 		backup:    backupSvc,
 		settings:  settingsSvc,
 	}
+	// The default lint configuration reads no files and cannot fail.
+	m.lintRules, _ = m.loadLintRules(config.Lint{})
+	return m
 }
 
 // InitInfra ensures that required directories exist.
@@ -84,6 +124,255 @@ func (m *Manager) InitInfra() error {
 	return nil
 }
 
+// LoadConfig reads the optional configuration file and applies it to the services.
+//
+// A missing configuration file keeps the defaults. Invalid values are reported
+// as errors rather than silently ignored.
+func (m *Manager) LoadConfig() error {
+	cfg, err := config.Load(m.storage, m.paths.ConfigPath())
+	if err != nil {
+		return err
+	}
+	compression, err := backup.ParseCompression(cfg.Backup.Compression)
+	if err != nil {
+		return fmt.Errorf("invalid backup configuration: %w", err)
+	}
+	layout, err := backup.ParseLayout(cfg.Backup.Layout)
+	if err != nil {
+		return fmt.Errorf("invalid backup configuration: %w", err)
+	}
+	keyring, err := m.keyringFor(cfg.Backup.Encryption)
+	if err != nil {
+		return fmt.Errorf("invalid backup configuration: %w", err)
+	}
+	mirrorDir, err := m.mirrorDir(cfg.Backup.Mirror)
+	if err != nil {
+		return fmt.Errorf("invalid backup configuration: %w", err)
+	}
+	retention, interval, err := parseRetention(cfg.Backup.Retention)
+	if err != nil {
+		return fmt.Errorf("invalid backup configuration: %w", err)
+	}
+	ignore, err := m.parseCompare(cfg.Compare)
+	if err != nil {
+		return fmt.Errorf("invalid compare configuration: %w", err)
+	}
+	sticky, err := parsePaths(cfg.Use.Sticky)
+	if err != nil {
+		return fmt.Errorf("invalid use configuration: sticky: %w", err)
+	}
+	settingsSchema, err := m.loadSchema(cfg.Schema)
+	if err != nil {
+		return fmt.Errorf("invalid schema configuration: %w", err)
+	}
+	lintRules, err := m.loadLintRules(cfg.Lint)
+	if err != nil {
+		return fmt.Errorf("invalid lint configuration: %w", err)
+	}
+	tags, err := m.parseTags(cfg.Tags)
+	if err != nil {
+		return fmt.Errorf("invalid tags configuration: %w", err)
+	}
+	secretsMode, err := parseSecretsMode(cfg.Secrets.Mode)
+	if err != nil {
+		return fmt.Errorf("invalid secrets configuration: %w", err)
+	}
+	m.backup.SetCompression(compression)
+	m.backup.SetLayout(layout)
+	m.backup.SetKeyring(keyring)
+	m.backup.SetMirror(mirrorDir)
+	m.retention = retention
+	m.retentionInterval = interval
+	m.ignore = ignore
+	m.sticky = sticky
+	m.schema = settingsSchema
+	m.lintRules = lintRules
+	m.tags = tags
+	m.secretsMode = secretsMode
+	m.config = cfg
+	return nil
+}
+
+// mirrorDir validates the mirror configuration and returns the absolute
+// mirror directory, or "" when mirroring is disabled.
+func (m *Manager) mirrorDir(mirror *config.Mirror) (string, error) {
+	if mirror == nil {
+		return "", nil
+	}
+	if mirror.Dir == "" {
+		return "", errors.New("mirror: dir is required")
+	}
+	dir := filepath.Clean(m.paths.ExpandHome(mirror.Dir))
+	if !filepath.IsAbs(dir) {
+		return "", fmt.Errorf("mirror: dir must be absolute: %s", mirror.Dir)
+	}
+	if m.paths.IsInClaudeDir(dir) {
+		return "", fmt.Errorf("mirror: dir must live outside %s: %s", m.paths.ClaudeDir(), dir)
+	}
+	return dir, nil
+}
+
+// compareIgnores holds the key paths excluded when comparing settings files.
+type compareIgnores struct {
+	global   []jsondoc.Path
+	profiles map[string][]jsondoc.Path
+}
+
+func (m *Manager) parseCompare(c config.Compare) (compareIgnores, error) {
+	var ignore compareIgnores
+	var err error
+	if ignore.global, err = parsePaths(c.Ignore); err != nil {
+		return compareIgnores{}, fmt.Errorf("ignore: %w", err)
+	}
+	for name, profile := range c.Profiles {
+		normalized, err := m.normalizeSettingsName(name)
+		if err != nil {
+			return compareIgnores{}, fmt.Errorf("profiles: %w", err)
+		}
+		paths, err := parsePaths(profile.Ignore)
+		if err != nil {
+			return compareIgnores{}, fmt.Errorf("profiles.%s.ignore: %w", name, err)
+		}
+		if ignore.profiles == nil {
+			ignore.profiles = make(map[string][]jsondoc.Path)
+		}
+		ignore.profiles[normalized] = append(ignore.profiles[normalized], paths...)
+	}
+	return ignore, nil
+}
+
+func parsePaths(raw []string) ([]jsondoc.Path, error) {
+	paths := make([]jsondoc.Path, 0, len(raw))
+	for _, r := range raw {
+		path, err := jsondoc.ParsePath(r)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// ignoredPaths returns the key paths to leave out when comparing settings
+// with the named profile: the global list, the profile's own, and the sticky
+// keys, which are expected to differ from the profile after ccs use.
+func (m *Manager) ignoredPaths(profile string) []jsondoc.Path {
+	paths := append([]jsondoc.Path(nil), m.ignore.global...)
+	paths = append(paths, m.ignore.profiles[profile]...)
+	return append(paths, m.sticky...)
+}
+
+// defaultRetentionInterval is the minimum time between automatic retention
+// runs when backup.retention.interval is not set.
+const defaultRetentionInterval = 24 * time.Hour
+
+func parseRetention(r *config.Retention) (backup.RetentionPolicy, time.Duration, error) {
+	var policy backup.RetentionPolicy
+	if r == nil {
+		return policy, 0, nil
+	}
+	if r.MaxAge == "" && r.KeepLast <= 0 {
+		return policy, 0, errors.New("retention: maxAge or keepLast is required")
+	}
+	if r.KeepLast < 0 {
+		return policy, 0, fmt.Errorf("retention: keepLast cannot be negative: %d", r.KeepLast)
+	}
+	policy.KeepLast = r.KeepLast
+	if r.MaxAge != "" {
+		maxAge, err := config.ParseDuration(r.MaxAge)
+		if err != nil {
+			return policy, 0, fmt.Errorf("retention: maxAge: %w", err)
+		}
+		policy.MaxAge = maxAge
+	}
+	interval := defaultRetentionInterval
+	if r.Interval != "" {
+		parsed, err := config.ParseDuration(r.Interval)
+		if err != nil {
+			return policy, 0, fmt.Errorf("retention: interval: %w", err)
+		}
+		interval = parsed
+	}
+	return policy, interval, nil
+}
+
+// applyRetention runs the configured retention policy if the configured
+// interval has passed since the last run. It is called after successful
+// mutating operations; failures are logged and never reported to the caller,
+// since the user's command has already succeeded.
+func (m *Manager) applyRetention() {
+	if m.config.Backup.Retention == nil {
+		return
+	}
+	if last := m.backup.LastRetentionRun(); !last.IsZero() && m.now().Sub(last) < m.retentionInterval {
+		return
+	}
+	deleted, err := m.backup.ApplyRetention(m.retention)
+	if err != nil {
+		m.logger.Warn("automatic backup retention failed", "error", err, "deleted", len(deleted))
+		return
+	}
+	if len(deleted) > 0 {
+		m.logger.Info("automatic backup retention completed", "deleted", len(deleted))
+	}
+}
+
+// keyringFor builds the backup keyring described by enc.
+//
+// Structural mistakes (no key source, two key sources, a key file inside
+// ~/.claude) are errors. A key file or passphrase that is merely unavailable
+// yields a keyring that fails on use, so commands that never touch backups
+// keep working.
+func (m *Manager) keyringFor(enc *config.Encryption) (*backup.Keyring, error) {
+	if enc == nil {
+		return nil, nil
+	}
+	switch {
+	case enc.KeyFile != "" && enc.PassphraseEnv != "":
+		return nil, errors.New("encryption: set either keyFile or passphraseEnv, not both")
+	case enc.KeyFile != "":
+		return m.loadKeyFile(m.paths.ExpandHome(enc.KeyFile))
+	case enc.PassphraseEnv != "":
+		passphrase := os.Getenv(enc.PassphraseEnv)
+		if passphrase == "" {
+			return backup.UnavailableKeyring(fmt.Errorf("backup encryption passphrase not set: export %s", enc.PassphraseEnv)), nil
+		}
+		return backup.NewPassphraseKeyring(passphrase)
+	default:
+		return nil, errors.New("encryption: keyFile or passphraseEnv is required")
+	}
+}
+
+func (m *Manager) loadKeyFile(path string) (*backup.Keyring, error) {
+	if !filepath.IsAbs(path) {
+		return nil, fmt.Errorf("encryption: key file path must be absolute: %s", path)
+	}
+	if m.paths.IsInClaudeDir(path) {
+		return nil, fmt.Errorf("encryption: key file must live outside %s: %s", m.paths.ClaudeDir(), path)
+	}
+	if err := m.storage.ValidatePathSafety(path); err != nil {
+		return nil, fmt.Errorf("encryption: %w", err)
+	}
+	info, err := m.storage.Stat(path)
+	if err != nil {
+		return backup.UnavailableKeyring(fmt.Errorf("backup encryption key file unavailable: %w", err)), nil
+	}
+	if info.Mode().Perm()&0o077 != 0 {
+		m.logger.Warn("backup key file is accessible by other users",
+			"path", path,
+			"mode", info.Mode().Perm().String())
+	}
+	content, err := m.storage.ReadFile(path)
+	if err != nil {
+		return backup.UnavailableKeyring(fmt.Errorf("backup encryption key file unavailable: %w", err)), nil
+	}
+	keyring, err := backup.NewKeyFileKeyring(content)
+	if err != nil {
+		return nil, fmt.Errorf("encryption: %s: %w", path, err)
+	}
+	return keyring, nil
+}
+
 // CalculateHash returns the SHA-256 hash of the given file.
 // Empty files return a special "empty" marker and log a warning.
 // Missing files return an empty string without error.
@@ -91,6 +380,13 @@ func (m *Manager) CalculateHash(path string) (string, error) {
 	return m.backup.CalculateHash(path)
 }
 
+// Fingerprint returns a hash of the canonical JSON form of the given file, so
+// formatting and key order do not affect it. Files that are not valid JSON
+// fall back to the byte hash of CalculateHash.
+func (m *Manager) Fingerprint(path string) (string, error) {
+	return m.backup.Fingerprint(path)
+}
+
 // GetActiveSettingsName returns the currently active settings name.
 func (m *Manager) GetActiveSettingsName() string {
 	return m.settings.GetActiveName()
@@ -129,15 +425,19 @@ func (m *Manager) normalizeSettingsName(name string) (string, error) {
 // The operation performs the following steps atomically:
 //  1. Validates the profile name (see ValidateSettingsName)
 //  2. Verifies the profile exists in the settings store
-//  3. Backs up the current active settings (if any)
-//  4. Atomically copies the profile to ~/.claude/settings.json
-//  5. Updates the active state file to track the current profile
+//  3. Records a snapshot of settings.json and the active state file
+//  4. Validates the profile against the settings schema
+//  5. Atomically copies the profile to ~/.claude/settings.json, carrying over
+//     the configured sticky keys from the current settings.json
+//  6. Updates the active state file to track the current profile
+//  7. Applies the configured retention policy, if due
 //
 // The operation is atomic - if it fails at any step, the current settings remain unchanged.
 //
 // Returns an error if:
 //   - The profile name is invalid (see ValidateSettingsName)
 //   - The profile doesn't exist in the settings store
+//   - The profile has schema errors (a *ValidationError)
 //   - File operations fail (permissions, disk space, etc.)
 //
 // Example:
@@ -147,29 +447,77 @@ func (m *Manager) normalizeSettingsName(name string) (string, error) {
 //	    log.Fatal(err)
 //	}
 func (m *Manager) Use(name string) error {
+	_, err := m.UseWithOptions(name, UseOptions{})
+	return err
+}
+
+// UseOptions adjusts how Use activates a profile.
+type UseOptions struct {
+	// NoSticky copies the profile verbatim, ignoring use.sticky.
+	NoSticky bool
+	// NoValidate skips the settings schema check.
+	NoValidate bool
+}
+
+// UseResult describes the outcome of UseWithOptions.
+type UseResult struct {
+	// Carried lists the sticky keys whose values were kept from the previous
+	// settings.json, in dotted form.
+	Carried []string
+	// Warnings lists schema warnings for the activated settings.
+	Warnings []SchemaIssue
+}
+
+// UseWithOptions activates a profile like Use and reports which sticky keys
+// were carried over from the previous settings.json and any schema warnings.
+func (m *Manager) UseWithOptions(name string, opts UseOptions) (UseResult, error) {
+	var result UseResult
 	if err := m.InitInfra(); err != nil {
-		return err
+		return result, err
 	}
 	normalized, err := m.normalizeSettingsName(name)
 	if err != nil {
-		return err
+		return result, err
 	}
 	targetPath := m.paths.StoredSettingsPath(normalized)
 	if exists, err := m.storage.Exists(targetPath); err != nil {
-		return fmt.Errorf("failed to inspect target settings: %w", err)
+		return result, fmt.Errorf("failed to inspect target settings: %w", err)
 	} else if !exists {
-		return fmt.Errorf("settings '%s' not found", normalized)
+		return result, fmt.Errorf("settings '%s' not found", normalized)
 	}
-	if err := m.backup.BackupFile(m.paths.ActiveSettingsPath()); err != nil {
-		return err
+	var content []byte
+	if !opts.NoSticky && len(m.sticky) > 0 {
+		if content, result.Carried, err = m.carrySticky(targetPath); err != nil {
+			return result, err
+		}
 	}
-	if err := m.storage.CopyFile(targetPath, m.paths.ActiveSettingsPath()); err != nil {
-		return fmt.Errorf("failed to copy settings: %w", err)
+	if !opts.NoValidate {
+		data := content
+		if data == nil {
+			if data, err = m.storage.ReadFile(targetPath); err != nil {
+				return result, fmt.Errorf("failed to read target settings: %w", err)
+			}
+		}
+		if result.Warnings, err = m.validateContent(targetPath, data); err != nil {
+			return result, err
+		}
+	}
+	if _, err := m.backup.Snapshot("use "+normalized, m.paths.ActiveSettingsPath(), m.paths.ActiveStatePath()); err != nil {
+		return result, err
+	}
+	if content != nil {
+		err = m.storage.WriteFileAtomic(m.paths.ActiveSettingsPath(), content)
+	} else {
+		err = m.storage.CopyFile(targetPath, m.paths.ActiveSettingsPath())
+	}
+	if err != nil {
+		return result, fmt.Errorf("failed to copy settings: %w", err)
 	}
 	if err := m.SetActiveSettings(normalized); err != nil {
-		return fmt.Errorf("failed to update active settings: %w", err)
+		return result, fmt.Errorf("failed to update active settings: %w", err)
 	}
-	return nil
+	m.applyRetention()
+	return result, nil
 }
 
 // Save persists the current active settings to a named profile in the settings store.
@@ -177,15 +525,19 @@ func (m *Manager) Use(name string) error {
 // The operation performs the following steps atomically:
 //  1. Validates the target profile name (see ValidateSettingsName)
 //  2. Verifies that ~/.claude/settings.json exists
-//  3. Backs up the existing profile (if overwriting)
-//  4. Atomically copies current settings to the profile location
-//  5. Updates the active state to track this profile
+//  3. Validates settings.json against the settings schema
+//  4. Records a snapshot of the existing profile (if overwriting) and the
+//     active state file
+//  5. Atomically copies current settings to the profile location
+//  6. Updates the active state to track this profile
+//  7. Applies the configured retention policy, if due
 //
 // The operation is atomic - if it fails at any step, existing profiles remain unchanged.
 //
 // Returns an error if:
 //   - The active settings.json doesn't exist
 //   - The target profile name is invalid
+//   - settings.json has schema errors (a *ValidationError)
 //   - File operations fail (permissions, disk space, etc.)
 //
 // Example:
@@ -195,30 +547,180 @@ func (m *Manager) Use(name string) error {
 //	    log.Fatal(err)
 //	}
 func (m *Manager) Save(targetName string) error {
+	_, err := m.SaveWithOptions(targetName, SaveOptions{})
+	return err
+}
+
+// SaveOptions adjusts how Save stores a profile.
+type SaveOptions struct {
+	// NoValidate skips the settings schema check.
+	NoValidate bool
+	// Strict refuses to save settings containing values that look like
+	// secrets, as secrets.mode "strict" does.
+	Strict bool
+	// Format rewrites settings.json in the canonical style of ccs fmt before
+	// storing it, as save.format does. Settings that are not valid JSON are
+	// stored as they are.
+	Format bool
+}
+
+// SaveResult describes the outcome of SaveWithOptions.
+type SaveResult struct {
+	// Warnings lists schema warnings for the saved settings.
+	Warnings []SchemaIssue
+	// Secrets lists values that look like secrets, unless secrets.mode is off.
+	Secrets []SecretReport
+}
+
+// SaveWithOptions stores settings.json like Save and reports any schema
+// warnings and values that look like secrets. In strict mode, detected
+// secrets fail the save with a *SecretError.
+func (m *Manager) SaveWithOptions(targetName string, opts SaveOptions) (SaveResult, error) {
+	var result SaveResult
 	if err := m.InitInfra(); err != nil {
-		return err
+		return result, err
 	}
 	activePath := m.paths.ActiveSettingsPath()
 	if exists, err := m.storage.Exists(activePath); err != nil {
-		return fmt.Errorf("failed to inspect settings.json: %w", err)
+		return result, fmt.Errorf("failed to inspect settings.json: %w", err)
 	} else if !exists {
-		return errors.New("settings.json not found. Nothing to save.")
+		return result, errors.New("settings.json not found. Nothing to save.")
 	}
 	normalized, err := m.normalizeSettingsName(targetName)
 	if err != nil {
-		return err
+		return result, err
+	}
+	data, err := m.storage.ReadFile(activePath)
+	if err != nil {
+		return result, fmt.Errorf("failed to read settings.json: %w", err)
+	}
+	if !opts.NoValidate {
+		if result.Warnings, err = m.validateContent(activePath, data); err != nil {
+			return result, err
+		}
+	}
+	if result.Secrets, err = m.checkSecrets(opts.Strict, func(report func(string, []byte)) error {
+		report("settings.json", data)
+		return nil
+	}); err != nil {
+		return result, err
 	}
 	targetPath := m.paths.StoredSettingsPath(normalized)
-	if err := m.backup.BackupFile(targetPath); err != nil {
-		return err
+	content := data
+	if opts.Format || m.config.Save.Format {
+		if canonical, err := format.Settings(data); err == nil {
+			content = canonical
+		} else {
+			m.logger.Warn("not formatting settings that are not valid JSON", "error", err)
+		}
+	}
+	reformat := !bytes.Equal(content, data)
+	snapshotPaths := []string{targetPath, m.paths.ActiveStatePath()}
+	if reformat {
+		snapshotPaths = append(snapshotPaths, activePath)
+	}
+	if _, err := m.backup.Snapshot("save "+normalized, snapshotPaths...); err != nil {
+		return result, err
+	}
+	if reformat {
+		if err := m.storage.WriteFileAtomic(activePath, content); err != nil {
+			return result, fmt.Errorf("failed to format settings.json: %w", err)
+		}
 	}
 	if err := m.storage.CopyFile(activePath, targetPath); err != nil {
-		return fmt.Errorf("failed to store settings: %w", err)
+		return result, fmt.Errorf("failed to store settings: %w", err)
 	}
 	if err := m.SetActiveSettings(normalized); err != nil {
-		return fmt.Errorf("failed to update active settings: %w", err)
+		return result, fmt.Errorf("failed to update active settings: %w", err)
 	}
-	return nil
+	m.applyRetention()
+	return result, nil
+}
+
+// ErrSettingsExist indicates that PromoteBackupWithOptions would overwrite an
+// existing profile and PromoteOptions.Force is not set.
+var ErrSettingsExist = errors.New("settings profile already exists")
+
+// PromoteOptions adjusts how PromoteBackupWithOptions stores a backup.
+type PromoteOptions struct {
+	// Force overwrites an existing profile of the same name.
+	Force bool
+	// NoValidate skips the settings schema check.
+	NoValidate bool
+}
+
+// PromoteResult describes the outcome of PromoteBackupWithOptions.
+type PromoteResult struct {
+	// Hash is the full hash of the promoted backup.
+	Hash string
+	// Active reports that the promoted profile is the active one. settings.json
+	// keeps its previous content until the profile is used again.
+	Active bool
+	// Warnings holds schema warnings for the promoted content.
+	Warnings []SchemaIssue
+}
+
+// PromoteBackup stores the content of the backup with the given hash (or
+// unique hash prefix) as the named profile. An existing profile of that name
+// is not overwritten; ErrSettingsExist is returned instead. See
+// PromoteBackupWithOptions.
+//
+// Returns the full hash of the promoted backup.
+func (m *Manager) PromoteBackup(hash, name string) (string, error) {
+	result, err := m.PromoteBackupWithOptions(hash, name, PromoteOptions{})
+	return result.Hash, err
+}
+
+// PromoteBackupWithOptions stores the content of the backup with the given
+// hash (or unique hash prefix) as the named profile in the settings store.
+//
+// The name goes through the same validation as Save. An existing profile of
+// that name is only overwritten with Force set; otherwise an error wrapping
+// ErrSettingsExist is returned. Unless NoValidate is set, the backup is
+// checked against the settings schema first and a *ValidationError is
+// returned when it has errors. The previous content is recorded in a
+// snapshot. settings.json is left unchanged, even when the promoted profile
+// is the active one; PromoteResult.Active reports that case.
+func (m *Manager) PromoteBackupWithOptions(hash, name string, opts PromoteOptions) (PromoteResult, error) {
+	var result PromoteResult
+	if err := m.InitInfra(); err != nil {
+		return result, err
+	}
+	normalized, err := m.normalizeSettingsName(name)
+	if err != nil {
+		return result, err
+	}
+	id, err := m.backup.ResolveHash(hash)
+	if err != nil {
+		return result, err
+	}
+	content, err := m.backup.ReadBackup(id)
+	if err != nil {
+		return result, err
+	}
+	targetPath := m.paths.StoredSettingsPath(normalized)
+	if !opts.NoValidate {
+		if result.Warnings, err = m.validateContent(targetPath, content); err != nil {
+			return result, err
+		}
+	}
+	if !opts.Force {
+		if exists, err := m.storage.Exists(targetPath); err != nil {
+			return result, fmt.Errorf("failed to inspect target settings: %w", err)
+		} else if exists {
+			return result, fmt.Errorf("%w: %s", ErrSettingsExist, normalized)
+		}
+	}
+	if _, err := m.backup.Snapshot("promote "+normalized, targetPath); err != nil {
+		return result, err
+	}
+	if err := m.storage.WriteFileAtomic(targetPath, content); err != nil {
+		return result, fmt.Errorf("failed to store settings: %w", err)
+	}
+	m.applyRetention()
+	result.Hash = id
+	result.Active = m.settings.GetActiveName() == normalized
+	return result, nil
 }
 
 // StoredSettings returns the names of all stored settings profiles, sorted lexicographically.
@@ -247,14 +749,20 @@ type ListEntry = settings.ListEntry
 //
 // The function compares stored profiles with the active settings and annotates
 // entries with their status. If the active profile has been modified locally,
-// it will be marked with "modified".
+// it will be marked with "modified". Files are compared by Fingerprint, so
+// reformatting or reordering keys is not a modification, and the key paths
+// listed under compare.ignore in the config file (globally or for the active
+// profile) are left out.
 //
 // Returns an error if the settings store or active settings cannot be accessed.
 func (m *Manager) ListSettings() ([]ListEntry, error) {
 	if err := m.InitInfra(); err != nil {
 		return nil, err
 	}
-	return m.settings.ListEntries(m.paths.ActiveSettingsPath(), m.CalculateHash)
+	ignore := m.ignoredPaths(m.settings.GetActiveName())
+	return m.settings.ListEntries(m.paths.ActiveSettingsPath(), func(path string) (string, error) {
+		return m.backup.Fingerprint(path, ignore...)
+	})
 }
 
 // PruneBackups removes backup files older than the specified duration.
@@ -280,6 +788,407 @@ func (m *Manager) PruneBackups(olderThan time.Duration) (int, error) {
 	return m.backup.PruneBackups(olderThan)
 }
 
+// CompressBackups rewrites existing backups in place using the given compression
+// ("none" or "gzip"). An empty algorithm uses the configured compression, or gzip
+// when the configuration does not enable compression.
+//
+// Each blob is replaced atomically and keeps its modification time, so the
+// migration is safe to interrupt and re-run.
+//
+// Returns the number of backups rewritten.
+func (m *Manager) CompressBackups(algorithm string) (int, error) {
+	if err := m.InitInfra(); err != nil {
+		return 0, err
+	}
+	if algorithm == "" {
+		algorithm = m.config.Backup.Compression
+		if algorithm == "" || algorithm == string(backup.CompressionNone) {
+			algorithm = string(backup.CompressionGzip)
+		}
+	}
+	compression, err := backup.ParseCompression(algorithm)
+	if err != nil {
+		return 0, err
+	}
+	return m.backup.Recompress(compression)
+}
+
+// MigrateBackupLayout moves existing backups into the given directory layout
+// ("flat" or "sharded"). An empty layout uses the configured layout, or sharded
+// when the configuration keeps the default flat layout.
+//
+// Backups are moved with atomic renames and both layouts stay readable, so the
+// migration is safe to interrupt and re-run. Set backup.layout to keep new
+// backups in the migrated layout.
+//
+// Returns the number of backups moved.
+func (m *Manager) MigrateBackupLayout(layout string) (int, error) {
+	if err := m.InitInfra(); err != nil {
+		return 0, err
+	}
+	if layout == "" {
+		layout = m.config.Backup.Layout
+		if layout == "" || layout == string(backup.LayoutFlat) {
+			layout = string(backup.LayoutSharded)
+		}
+	}
+	parsed, err := backup.ParseLayout(layout)
+	if err != nil {
+		return 0, err
+	}
+	return m.backup.MigrateLayout(parsed)
+}
+
+// MirrorSyncResult reports what SyncMirror copied.
+type MirrorSyncResult = backup.MirrorSyncResult
+
+// SyncMirror reconciles the backup directory with the configured mirror
+// (backup.mirror.dir): mirror copies that are missing or differ from the
+// local backups are replaced, mirror-only backups are copied back unless they
+// were deleted locally, and the snapshot indexes are merged. Backups whose
+// earlier mirroring failed are covered as well.
+func (m *Manager) SyncMirror() (MirrorSyncResult, error) {
+	if err := m.InitInfra(); err != nil {
+		return MirrorSyncResult{}, err
+	}
+	result, err := m.backup.SyncMirror()
+	if errors.Is(err, backup.ErrNoMirror) {
+		return result, fmt.Errorf("%w: set backup.mirror.dir in %s", err, m.paths.ConfigPath())
+	}
+	return result, err
+}
+
+// Snapshot describes the files captured by one backed-up operation.
+type Snapshot = backup.Snapshot
+
+// Snapshots returns the recorded operation snapshots, newest first.
+func (m *Manager) Snapshots() ([]Snapshot, error) {
+	if err := m.InitInfra(); err != nil {
+		return nil, err
+	}
+	return m.backup.Snapshots()
+}
+
+// RestoreSnapshot brings every file captured by the snapshot with the given ID
+// (or unique ID prefix) back to its recorded content: settings.json, the active
+// state file and any stored profile, all as of the same moment.
+//
+// The current state of those files is itself snapshotted first, so a restore
+// can be undone by restoring the snapshot it creates.
+//
+// Returns the restored snapshot.
+func (m *Manager) RestoreSnapshot(id string) (Snapshot, error) {
+	if err := m.InitInfra(); err != nil {
+		return Snapshot{}, err
+	}
+	snap, err := m.backup.FindSnapshot(id)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	paths := make([]string, 0, len(snap.Files))
+	for _, f := range snap.Files {
+		path, err := m.backup.SnapshotPath(f)
+		if err != nil {
+			return Snapshot{}, err
+		}
+		paths = append(paths, path)
+	}
+	if _, err := m.backup.Snapshot("restore "+snap.ID, paths...); err != nil {
+		return Snapshot{}, err
+	}
+	if err := m.backup.RestoreSnapshot(snap); err != nil {
+		return Snapshot{}, err
+	}
+	m.applyRetention()
+	return snap, nil
+}
+
+// BlobMeta holds the pin and note of a backup.
+type BlobMeta = backup.BlobMeta
+
+// ShortHash abbreviates a backup hash for display and labels. An empty hash,
+// recorded for a file that did not exist, is shown as "(absent)".
+func ShortHash(hash string) string {
+	switch {
+	case hash == "":
+		return "(absent)"
+	case len(hash) > 12:
+		return hash[:12]
+	default:
+		return hash
+	}
+}
+
+// BackupAnnotations returns the pins and notes of all annotated backups, keyed by hash.
+func (m *Manager) BackupAnnotations() (map[string]BlobMeta, error) {
+	if err := m.InitInfra(); err != nil {
+		return nil, err
+	}
+	return m.backup.Annotations()
+}
+
+// PinBackup protects the backup with the given hash (or unique hash prefix)
+// from every kind of pruning. Returns the full hash.
+func (m *Manager) PinBackup(hash string) (string, error) {
+	if err := m.InitInfra(); err != nil {
+		return "", err
+	}
+	return m.backup.SetPinned(hash, true)
+}
+
+// UnpinBackup makes the backup with the given hash prunable again.
+// Returns the full hash.
+func (m *Manager) UnpinBackup(hash string) (string, error) {
+	if err := m.InitInfra(); err != nil {
+		return "", err
+	}
+	return m.backup.SetPinned(hash, false)
+}
+
+// NoteBackup attaches a note to the backup with the given hash; an empty note
+// removes it. Returns the full hash.
+func (m *Manager) NoteBackup(hash, note string) (string, error) {
+	if err := m.InitInfra(); err != nil {
+		return "", err
+	}
+	return m.backup.SetNote(hash, note)
+}
+
+// TransferOptions adjusts ExportBackupsWithOptions and ImportBackupsWithOptions.
+type TransferOptions struct {
+	// Strict refuses archives whose backups contain values that look like
+	// secrets, as secrets.mode "strict" does.
+	Strict bool
+	// NoValidate skips the settings schema check of imported backups.
+	NoValidate bool
+}
+
+// ExportResult reports the outcome of ExportBackupsWithOptions.
+type ExportResult struct {
+	Exported int
+	// Secrets lists backups holding values that look like secrets, unless
+	// secrets.mode is off.
+	Secrets []SecretReport
+}
+
+// ImportResult reports what ImportBackups added to the backup store.
+type ImportResult struct {
+	backup.ImportResult
+	// Secrets lists archived backups holding values that look like secrets,
+	// unless secrets.mode is off.
+	Secrets []SecretReport
+	// Warnings holds schema warnings for the archived settings backups. The
+	// path of each issue starts with the backup's short hash.
+	Warnings []SchemaIssue
+}
+
+// ExportBackups writes every backup, snapshot and annotation to a tar.gz
+// archive at path. Returns the number of backups exported.
+func (m *Manager) ExportBackups(path string) (int, error) {
+	result, err := m.ExportBackupsWithOptions(path, TransferOptions{})
+	return result.Exported, err
+}
+
+// ExportBackupsWithOptions exports backups like ExportBackups and reports
+// backups containing values that look like secrets. In strict mode, detected
+// secrets fail the export with a *SecretError before the archive is written.
+func (m *Manager) ExportBackupsWithOptions(path string, opts TransferOptions) (ExportResult, error) {
+	var result ExportResult
+	if err := m.InitInfra(); err != nil {
+		return result, err
+	}
+	var err error
+	if result.Secrets, err = m.checkSecrets(opts.Strict, func(report func(string, []byte)) error {
+		return m.backup.Contents(func(hash string, content []byte) error {
+			report("backup "+ShortHash(hash), content)
+			return nil
+		})
+	}); err != nil {
+		return result, err
+	}
+	var buf bytes.Buffer
+	if result.Exported, err = m.backup.Export(&buf); err != nil {
+		return result, err
+	}
+	if err := m.storage.WriteFileAtomic(path, buf.Bytes()); err != nil {
+		return result, fmt.Errorf("failed to write archive: %w", err)
+	}
+	return result, nil
+}
+
+// ImportBackups merges a tar.gz archive created by ExportBackups into the
+// backup store. Every backup is verified against its hash before anything is
+// written. Encrypted archives need the same backup.encryption key.
+func (m *Manager) ImportBackups(path string) (ImportResult, error) {
+	return m.ImportBackupsWithOptions(path, TransferOptions{})
+}
+
+// ImportBackupsWithOptions imports an archive like ImportBackups and reports
+// archived backups containing values that look like secrets. In strict mode,
+// detected secrets fail the import with a *SecretError and nothing is written.
+//
+// Unless NoValidate is set, every archived backup except those the archive's
+// snapshots record only as the active state file is checked against the
+// settings schema first; a backup that is not valid JSON or has schema errors
+// fails the import with a *ValidationError and nothing is written.
+func (m *Manager) ImportBackupsWithOptions(path string, opts TransferOptions) (ImportResult, error) {
+	var result ImportResult
+	if err := m.InitInfra(); err != nil {
+		return result, err
+	}
+	if err := m.storage.ValidatePathSafety(path); err != nil {
+		return result, fmt.Errorf("path validation failed: %w", err)
+	}
+	data, err := m.storage.ReadFile(path)
+	if err != nil {
+		return result, fmt.Errorf("failed to read archive: %w", err)
+	}
+	result.ImportResult, err = m.backup.ImportChecked(bytes.NewReader(data), func(contents map[string][]byte, snapshots []Snapshot) error {
+		hashes := make([]string, 0, len(contents))
+		for hash := range contents {
+			hashes = append(hashes, hash)
+		}
+		sort.Strings(hashes)
+		if !opts.NoValidate {
+			state := m.stateOnlyHashes(snapshots)
+			for _, hash := range hashes {
+				if state[hash] {
+					continue
+				}
+				label := "backup " + ShortHash(hash)
+				warnings, err := m.validateContent(label, contents[hash])
+				if err != nil {
+					return err
+				}
+				for _, issue := range warnings {
+					if issue.Path == "" {
+						issue.Path = label
+					} else {
+						issue.Path = label + ": " + issue.Path
+					}
+					result.Warnings = append(result.Warnings, issue)
+				}
+			}
+		}
+		var err error
+		result.Secrets, err = m.checkSecrets(opts.Strict, func(report func(string, []byte)) error {
+			for _, hash := range hashes {
+				report("backup "+ShortHash(hash), contents[hash])
+			}
+			return nil
+		})
+		return err
+	})
+	return result, err
+}
+
+// stateOnlyHashes returns the backups that snapshots record as the active
+// state file and as no other file. They hold ccs state, not settings.
+func (m *Manager) stateOnlyHashes(snapshots []Snapshot) map[string]bool {
+	state := make(map[string]bool)
+	other := make(map[string]bool)
+	for _, snap := range snapshots {
+		for _, f := range snap.Files {
+			if f.Hash == "" {
+				continue
+			}
+			if path, err := m.backup.SnapshotPath(f); err == nil && path == m.paths.ActiveStatePath() {
+				state[f.Hash] = true
+			} else {
+				other[f.Hash] = true
+			}
+		}
+	}
+	for hash := range other {
+		delete(state, hash)
+	}
+	return state
+}
+
+// RekeyOptions selects the new key for RekeyBackups. Exactly one field must be set.
+type RekeyOptions struct {
+	// NewKeyFile is the path of the new key file. It is generated when missing.
+	NewKeyFile string
+	// NewPassphraseEnv names the environment variable holding the new passphrase.
+	NewPassphraseEnv string
+	// Decrypt rewrites all backups unencrypted.
+	Decrypt bool
+}
+
+// RekeyResult reports the outcome of RekeyBackups.
+type RekeyResult struct {
+	Rewritten int
+	// Unreadable counts backups the current key could not open. When it is
+	// non-zero, RekeyBackups fails and no backup is rewritten.
+	Unreadable       int
+	GeneratedKeyFile string
+}
+
+// RekeyBackups re-encrypts every backup with a new key.
+//
+// Backups are decrypted with the currently configured key (unencrypted backups
+// need none), so rekeying also encrypts a store for the first time. Each blob
+// is replaced atomically. The configuration file is not modified; callers
+// should point backup.encryption at the new key afterwards.
+//
+// When any backup cannot be opened with the current key, an error is
+// returned before anything is rewritten, so the store stays under one key.
+func (m *Manager) RekeyBackups(opts RekeyOptions) (RekeyResult, error) {
+	var result RekeyResult
+	if err := m.InitInfra(); err != nil {
+		return result, err
+	}
+
+	sources := 0
+	for _, set := range []bool{opts.NewKeyFile != "", opts.NewPassphraseEnv != "", opts.Decrypt} {
+		if set {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return result, errors.New("specify exactly one of a new key file, a new passphrase variable, or decryption")
+	}
+
+	var newKey *backup.Keyring
+	switch {
+	case opts.NewKeyFile != "":
+		path := m.paths.ExpandHome(opts.NewKeyFile)
+		if exists, err := m.storage.Exists(path); err != nil {
+			return result, fmt.Errorf("failed to inspect key file: %w", err)
+		} else if !exists {
+			if !filepath.IsAbs(path) || m.paths.IsInClaudeDir(path) {
+				return result, fmt.Errorf("key file must be an absolute path outside %s: %s", m.paths.ClaudeDir(), path)
+			}
+			content, err := backup.GenerateKeyFile()
+			if err != nil {
+				return result, err
+			}
+			if err := m.storage.MkdirAll(filepath.Dir(path)); err != nil {
+				return result, fmt.Errorf("failed to create key file directory: %w", err)
+			}
+			if err := m.storage.WriteFileAtomic(path, content); err != nil {
+				return result, fmt.Errorf("failed to write key file: %w", err)
+			}
+			result.GeneratedKeyFile = path
+		}
+		keyring, err := m.loadKeyFile(path)
+		if err != nil {
+			return result, err
+		}
+		newKey = keyring
+	case opts.NewPassphraseEnv != "":
+		keyring, err := backup.NewPassphraseKeyring(os.Getenv(opts.NewPassphraseEnv))
+		if err != nil {
+			return result, fmt.Errorf("%s: %w", opts.NewPassphraseEnv, err)
+		}
+		newKey = keyring
+	}
+
+	rewritten, unreadable, err := m.backup.Rekey(newKey)
+	result.Rewritten, result.Unreadable = rewritten, unreadable
+	return result, err
+}
+
 // ActiveSettingsPath returns the path to settings.json for consumers like tests.
 func (m *Manager) ActiveSettingsPath() string {
 	return m.paths.ActiveSettingsPath()
@@ -295,6 +1204,11 @@ func (m *Manager) BackupDir() string {
 	return m.paths.BackupDir()
 }
 
+// ConfigPath returns the configuration file path.
+func (m *Manager) ConfigPath() string {
+	return m.paths.ConfigPath()
+}
+
 // SettingsStoreDir returns the store directory path.
 func (m *Manager) SettingsStoreDir() string {
 	return m.paths.SettingsStoreDir()
@@ -316,5 +1230,9 @@ func (m *Manager) StoredSettingsPath(name string) (string, error) {
 
 // SetNow overrides the clock used by the manager for testing.
 func (m *Manager) SetNow(now func() time.Time) {
+	if now == nil {
+		now = time.Now
+	}
+	m.now = now
 	m.backup.SetNow(now)
 }
@@ -3,6 +3,7 @@ package ccs
 import (
 	"errors"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -163,10 +164,10 @@ func contains(list []string, target string) bool {
 func TestUseSwitchesSettingsAndUpdatesTimestamp(t *testing.T) {
 	mgr := newTestManager(t)
 	store := mgr.SettingsStoreDir()
-	if err := afero.WriteFile(mgr.FileSystem(), filepath.Join(store, "work.json"), []byte("stored"), 0o644); err != nil {
+	if err := afero.WriteFile(mgr.FileSystem(), filepath.Join(store, "work.json"), []byte(`{"model": "stored"}`), 0o644); err != nil {
 		t.Fatalf("write work: %v", err)
 	}
-	if err := afero.WriteFile(mgr.FileSystem(), mgr.ActiveSettingsPath(), []byte("current"), 0o644); err != nil {
+	if err := afero.WriteFile(mgr.FileSystem(), mgr.ActiveSettingsPath(), []byte(`{"model": "current"}`), 0o644); err != nil {
 		t.Fatalf("write current: %v", err)
 	}
 	time1 := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
@@ -191,7 +192,7 @@ func TestUseSwitchesSettingsAndUpdatesTimestamp(t *testing.T) {
 	if err != nil {
 		t.Fatalf("read active: %v", err)
 	}
-	if string(content) != "stored" {
+	if string(content) != `{"model": "stored"}` {
 		t.Fatalf("expected stored content, got %s", content)
 	}
 	if mgr.GetActiveSettingsName() != "work" {
@@ -202,10 +203,10 @@ func TestUseSwitchesSettingsAndUpdatesTimestamp(t *testing.T) {
 func TestSaveOverwritesStoredSettings(t *testing.T) {
 	mgr := newTestManager(t)
 	store := mgr.SettingsStoreDir()
-	if err := afero.WriteFile(mgr.FileSystem(), filepath.Join(store, "personal.json"), []byte("initial"), 0o644); err != nil {
+	if err := afero.WriteFile(mgr.FileSystem(), filepath.Join(store, "personal.json"), []byte(`{"model": "initial"}`), 0o644); err != nil {
 		t.Fatalf("write personal: %v", err)
 	}
-	if err := afero.WriteFile(mgr.FileSystem(), mgr.ActiveSettingsPath(), []byte("Mod"), 0o644); err != nil {
+	if err := afero.WriteFile(mgr.FileSystem(), mgr.ActiveSettingsPath(), []byte(`{"model": "Mod"}`), 0o644); err != nil {
 		t.Fatalf("write active: %v", err)
 	}
 	if err := mgr.Save("personal"); err != nil {
@@ -215,7 +216,7 @@ func TestSaveOverwritesStoredSettings(t *testing.T) {
 	if err != nil {
 		t.Fatalf("read personal: %v", err)
 	}
-	if string(content) != "Mod" {
+	if string(content) != `{"model": "Mod"}` {
 		t.Fatalf("expected updated content, got %s", content)
 	}
 	if mgr.GetActiveSettingsName() != "personal" {
@@ -225,7 +226,7 @@ func TestSaveOverwritesStoredSettings(t *testing.T) {
 
 func TestSaveCreatesNewSettings(t *testing.T) {
 	mgr := newTestManager(t)
-	if err := afero.WriteFile(mgr.FileSystem(), mgr.ActiveSettingsPath(), []byte("data"), 0o644); err != nil {
+	if err := afero.WriteFile(mgr.FileSystem(), mgr.ActiveSettingsPath(), []byte(`{"model": "data"}`), 0o644); err != nil {
 		t.Fatalf("write active: %v", err)
 	}
 	if err := mgr.Save("dev"); err != nil {
@@ -239,7 +240,7 @@ func TestSaveCreatesNewSettings(t *testing.T) {
 	if err != nil {
 		t.Fatalf("read dev: %v", err)
 	}
-	if string(content) != "data" {
+	if string(content) != `{"model": "data"}` {
 		t.Fatalf("expected stored data, got %s", content)
 	}
 }
@@ -278,7 +279,7 @@ func TestStoredSettingsPathInvalidName(t *testing.T) {
 
 func TestSaveTrimsSettingsName(t *testing.T) {
 	mgr := newTestManager(t)
-	if err := afero.WriteFile(mgr.FileSystem(), mgr.ActiveSettingsPath(), []byte("data"), 0o644); err != nil {
+	if err := afero.WriteFile(mgr.FileSystem(), mgr.ActiveSettingsPath(), []byte(`{"model": "data"}`), 0o644); err != nil {
 		t.Fatalf("write active: %v", err)
 	}
 	if err := mgr.Save(" dev "); err != nil {
@@ -531,10 +532,10 @@ func TestPruneBackupsNoDeletion(t *testing.T) {
 func TestUseCreatesBackup(t *testing.T) {
 	mgr := newTestManager(t)
 	store := mgr.SettingsStoreDir()
-	if err := afero.WriteFile(mgr.FileSystem(), mgr.ActiveSettingsPath(), []byte("current"), 0o644); err != nil {
+	if err := afero.WriteFile(mgr.FileSystem(), mgr.ActiveSettingsPath(), []byte(`{"model": "current"}`), 0o644); err != nil {
 		t.Fatalf("write active: %v", err)
 	}
-	if err := afero.WriteFile(mgr.FileSystem(), filepath.Join(store, "work.json"), []byte("stored"), 0o644); err != nil {
+	if err := afero.WriteFile(mgr.FileSystem(), filepath.Join(store, "work.json"), []byte(`{"model": "stored"}`), 0o644); err != nil {
 		t.Fatalf("write work: %v", err)
 	}
 	if err := mgr.Use("work"); err != nil {
@@ -552,10 +553,10 @@ func TestUseCreatesBackup(t *testing.T) {
 func TestSaveCreatesBackupOfTarget(t *testing.T) {
 	mgr := newTestManager(t)
 	store := mgr.SettingsStoreDir()
-	if err := afero.WriteFile(mgr.FileSystem(), filepath.Join(store, "personal.json"), []byte("old"), 0o644); err != nil {
+	if err := afero.WriteFile(mgr.FileSystem(), filepath.Join(store, "personal.json"), []byte(`{"model": "old"}`), 0o644); err != nil {
 		t.Fatalf("write personal: %v", err)
 	}
-	if err := afero.WriteFile(mgr.FileSystem(), mgr.ActiveSettingsPath(), []byte("new"), 0o644); err != nil {
+	if err := afero.WriteFile(mgr.FileSystem(), mgr.ActiveSettingsPath(), []byte(`{"model": "new"}`), 0o644); err != nil {
 		t.Fatalf("write active: %v", err)
 	}
 	if err := mgr.Save("personal"); err != nil {
@@ -593,3 +594,626 @@ func TestInitInfraError(t *testing.T) {
 		t.Fatalf("expected error initializing read-only fs")
 	}
 }
+
+func TestLoadConfigAppliesCompression(t *testing.T) {
+	mgr := newTestManager(t)
+	if err := afero.WriteFile(mgr.FileSystem(), mgr.paths.ConfigPath(), []byte(`{"backup": {"compression": "gzip"}}`), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if err := mgr.LoadConfig(); err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	if err := afero.WriteFile(mgr.FileSystem(), mgr.ActiveSettingsPath(), []byte(`{"model": "opus"}`), 0o644); err != nil {
+		t.Fatalf("write active: %v", err)
+	}
+	if err := mgr.Save("work"); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if err := afero.WriteFile(mgr.FileSystem(), mgr.ActiveSettingsPath(), []byte(`{"model": "sonnet"}`), 0o644); err != nil {
+		t.Fatalf("write active: %v", err)
+	}
+	if err := mgr.Save("work"); err != nil {
+		t.Fatalf("save again: %v", err)
+	}
+	snaps, err := mgr.Snapshots()
+	if err != nil {
+		t.Fatalf("snapshots: %v", err)
+	}
+	if len(snaps) != 1 || snaps[0].Files[0].Path != "switch-settings/work.json" {
+		t.Fatalf("expected one snapshot of work.json, got %+v", snaps)
+	}
+	content, err := afero.ReadFile(mgr.FileSystem(), filepath.Join(mgr.BackupDir(), snaps[0].Files[0].Hash+".json"))
+	if err != nil {
+		t.Fatalf("read backup: %v", err)
+	}
+	if len(content) < 2 || content[0] != 0x1f || content[1] != 0x8b {
+		t.Fatalf("expected gzip-compressed backup, got %q", content)
+	}
+}
+
+func TestLoadConfigRejectsUnknownCompression(t *testing.T) {
+	mgr := newTestManager(t)
+	if err := afero.WriteFile(mgr.FileSystem(), mgr.paths.ConfigPath(), []byte(`{"backup": {"compression": "lz4"}}`), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if err := mgr.LoadConfig(); err == nil {
+		t.Fatalf("expected error for unsupported compression")
+	}
+}
+
+func TestLoadConfigRejectsKeyFileInsideClaudeDir(t *testing.T) {
+	mgr := newTestManager(t)
+	config := `{"backup": {"encryption": {"keyFile": "~/.claude/backup.key"}}}`
+	if err := afero.WriteFile(mgr.FileSystem(), mgr.paths.ConfigPath(), []byte(config), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if err := mgr.LoadConfig(); err == nil {
+		t.Fatalf("expected error for key file inside ~/.claude")
+	}
+}
+
+func TestRekeyBackupsEncryptsExistingStore(t *testing.T) {
+	mgr := newTestManager(t)
+	store := mgr.SettingsStoreDir()
+	if err := afero.WriteFile(mgr.FileSystem(), filepath.Join(store, "work.json"), []byte(`{"model": "opus"}`), 0o644); err != nil {
+		t.Fatalf("write work: %v", err)
+	}
+	if err := afero.WriteFile(mgr.FileSystem(), mgr.ActiveSettingsPath(), []byte(`{"env": {"TOKEN": "secret"}}`), 0o644); err != nil {
+		t.Fatalf("write active: %v", err)
+	}
+	if err := mgr.Use("work"); err != nil {
+		t.Fatalf("use work: %v", err)
+	}
+
+	result, err := mgr.RekeyBackups(RekeyOptions{NewKeyFile: "~/.config/ccs/backup.key"})
+	if err != nil {
+		t.Fatalf("rekey: %v", err)
+	}
+	if result.Rewritten != 1 {
+		t.Fatalf("expected 1 rewritten, got %d", result.Rewritten)
+	}
+	if result.GeneratedKeyFile != "/home/test/.config/ccs/backup.key" {
+		t.Fatalf("expected generated key file, got %q", result.GeneratedKeyFile)
+	}
+
+	files, err := afero.ReadDir(mgr.FileSystem(), mgr.BackupDir())
+	if err != nil {
+		t.Fatalf("read backups: %v", err)
+	}
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+		content, err := afero.ReadFile(mgr.FileSystem(), filepath.Join(mgr.BackupDir(), f.Name()))
+		if err != nil {
+			t.Fatalf("read backup: %v", err)
+		}
+		if strings.Contains(string(content), "secret") {
+			t.Fatalf("backup should be encrypted after rekey")
+		}
+	}
+
+	// Configuring the generated key lets new backups be written encrypted
+	config := `{"backup": {"encryption": {"keyFile": "~/.config/ccs/backup.key"}}}`
+	if err := afero.WriteFile(mgr.FileSystem(), mgr.paths.ConfigPath(), []byte(config), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if err := mgr.LoadConfig(); err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	if err := mgr.Use("work"); err != nil {
+		t.Fatalf("use work with encryption: %v", err)
+	}
+}
+
+func TestRekeyBackupsFailsWhenBackupsCannotBeOpened(t *testing.T) {
+	mgr := newTestManager(t)
+	t.Setenv("CCS_TEST_PASSPHRASE", "secret passphrase")
+	config := `{"backup": {"encryption": {"passphraseEnv": "CCS_TEST_PASSPHRASE"}}}`
+	if err := afero.WriteFile(mgr.FileSystem(), mgr.paths.ConfigPath(), []byte(config), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if err := mgr.LoadConfig(); err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	if err := afero.WriteFile(mgr.FileSystem(), filepath.Join(mgr.SettingsStoreDir(), "work.json"), []byte(`{"model": "opus"}`), 0o644); err != nil {
+		t.Fatalf("write work: %v", err)
+	}
+	if err := afero.WriteFile(mgr.FileSystem(), mgr.ActiveSettingsPath(), []byte(`{"model": "sonnet"}`), 0o644); err != nil {
+		t.Fatalf("write active: %v", err)
+	}
+	if err := mgr.Use("work"); err != nil {
+		t.Fatalf("use work: %v", err)
+	}
+
+	// Without the passphrase the encrypted backup cannot be decrypted
+	t.Setenv("CCS_TEST_PASSPHRASE", "")
+	if err := mgr.LoadConfig(); err != nil {
+		t.Fatalf("reload config: %v", err)
+	}
+	result, err := mgr.RekeyBackups(RekeyOptions{Decrypt: true})
+	if err == nil {
+		t.Fatalf("expected error when backups cannot be opened")
+	}
+	if result.Unreadable != 1 || result.Rewritten != 0 {
+		t.Fatalf("expected 1 unreadable and 0 rewritten, got %+v", result)
+	}
+}
+
+func TestRekeyBackupsRequiresSingleSource(t *testing.T) {
+	mgr := newTestManager(t)
+	if _, err := mgr.RekeyBackups(RekeyOptions{}); err == nil {
+		t.Fatalf("expected error without key source")
+	}
+	if _, err := mgr.RekeyBackups(RekeyOptions{NewKeyFile: "/keys/a", Decrypt: true}); err == nil {
+		t.Fatalf("expected error with two key sources")
+	}
+}
+
+func TestUseRecordsSnapshotOfSettingsAndState(t *testing.T) {
+	mgr := newTestManager(t)
+	store := mgr.SettingsStoreDir()
+	for name, content := range map[string]string{"work": `{"model": "opus"}`, "home": `{"model": "sonnet"}`} {
+		if err := afero.WriteFile(mgr.FileSystem(), filepath.Join(store, name+".json"), []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	if err := afero.WriteFile(mgr.FileSystem(), mgr.ActiveSettingsPath(), []byte(`{"model": "haiku"}`), 0o644); err != nil {
+		t.Fatalf("write active: %v", err)
+	}
+
+	mgr.SetNow(func() time.Time { return time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC) })
+	if err := mgr.Use("work"); err != nil {
+		t.Fatalf("use work: %v", err)
+	}
+	if err := mgr.Use("home"); err != nil {
+		t.Fatalf("use home: %v", err)
+	}
+
+	snaps, err := mgr.Snapshots()
+	if err != nil {
+		t.Fatalf("snapshots: %v", err)
+	}
+	if len(snaps) != 2 {
+		t.Fatalf("expected 2 snapshots, got %d", len(snaps))
+	}
+	if snaps[0].ID == snaps[1].ID {
+		t.Fatalf("snapshot IDs must be unique, got %q twice", snaps[0].ID)
+	}
+	first := snaps[1]
+	if first.Operation != "use work" || len(first.Files) != 2 {
+		t.Fatalf("unexpected first snapshot: %+v", first)
+	}
+	if first.Files[1].Path != "settings.json.active" || first.Files[1].Hash != "" {
+		t.Fatalf("state file should be recorded as absent: %+v", first.Files[1])
+	}
+
+	// Restoring the first snapshot brings back the unsaved settings and clears the state
+	if _, err := mgr.RestoreSnapshot(first.ID); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	content, err := afero.ReadFile(mgr.FileSystem(), mgr.ActiveSettingsPath())
+	if err != nil {
+		t.Fatalf("read active: %v", err)
+	}
+	if string(content) != `{"model": "haiku"}` {
+		t.Fatalf("expected original settings, got %s", content)
+	}
+	if name := mgr.GetActiveSettingsName(); name != "" {
+		t.Fatalf("expected no active profile after restore, got %q", name)
+	}
+
+	// The restore itself was snapshotted and can be undone
+	snaps, err = mgr.Snapshots()
+	if err != nil {
+		t.Fatalf("snapshots after restore: %v", err)
+	}
+	if len(snaps) != 3 || snaps[0].Operation != "restore "+first.ID {
+		t.Fatalf("expected restore snapshot first, got %+v", snaps)
+	}
+	if _, err := mgr.RestoreSnapshot(snaps[0].ID); err != nil {
+		t.Fatalf("undo restore: %v", err)
+	}
+	if name := mgr.GetActiveSettingsName(); name != "home" {
+		t.Fatalf("expected home active after undo, got %q", name)
+	}
+}
+
+func TestPromoteBackupStoresProfileAndSnapshotsOverwrite(t *testing.T) {
+	mgr := newTestManager(t)
+	store := mgr.SettingsStoreDir()
+	if err := afero.WriteFile(mgr.FileSystem(), mgr.ActiveSettingsPath(), []byte(`{"model": "opus"}`), 0o644); err != nil {
+		t.Fatalf("write active: %v", err)
+	}
+	if err := mgr.Save("work"); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	// Overwriting work backs up the opus content
+	if err := afero.WriteFile(mgr.FileSystem(), mgr.ActiveSettingsPath(), []byte(`{"model": "sonnet"}`), 0o644); err != nil {
+		t.Fatalf("write active: %v", err)
+	}
+	if err := mgr.Save("work"); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	snaps, err := mgr.Snapshots()
+	if err != nil {
+		t.Fatalf("snapshots: %v", err)
+	}
+	opus := snaps[0].Files[0].Hash
+
+	if _, err := mgr.PromoteBackup(opus[:10], "bad/name"); err == nil {
+		t.Fatalf("expected invalid name to be rejected")
+	}
+	if _, err := mgr.PromoteBackup(opus[:10], "work"); !errors.Is(err, ErrSettingsExist) {
+		t.Fatalf("expected PromoteBackup to refuse overwriting work, got %v", err)
+	}
+	result, err := mgr.PromoteBackupWithOptions(opus[:10], "work", PromoteOptions{Force: true})
+	if err != nil {
+		t.Fatalf("promote: %v", err)
+	}
+	if result.Hash != opus {
+		t.Fatalf("expected full hash %s, got %s", opus, result.Hash)
+	}
+	content, err := afero.ReadFile(mgr.FileSystem(), filepath.Join(store, "work.json"))
+	if err != nil {
+		t.Fatalf("read promoted: %v", err)
+	}
+	if string(content) != `{"model": "opus"}` {
+		t.Fatalf("unexpected promoted content: %s", content)
+	}
+
+	snaps, err = mgr.Snapshots()
+	if err != nil {
+		t.Fatalf("snapshots: %v", err)
+	}
+	if snaps[0].Operation != "promote work" {
+		t.Fatalf("expected promote snapshot, got %+v", snaps[0])
+	}
+	if _, err := mgr.RestoreSnapshot(snaps[0].ID); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	content, err = afero.ReadFile(mgr.FileSystem(), filepath.Join(store, "work.json"))
+	if err != nil {
+		t.Fatalf("read restored: %v", err)
+	}
+	if string(content) != `{"model": "sonnet"}` {
+		t.Fatalf("expected overwritten profile to be recoverable, got %s", content)
+	}
+}
+
+func TestPromoteBackupWithOptionsRefusesOverwriteAndReportsActive(t *testing.T) {
+	mgr := newTestManager(t)
+	fs := mgr.FileSystem()
+	if err := afero.WriteFile(fs, mgr.ActiveSettingsPath(), []byte(`{"model": "opus"}`), 0o644); err != nil {
+		t.Fatalf("write active: %v", err)
+	}
+	if err := mgr.Save("work"); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if err := afero.WriteFile(fs, mgr.ActiveSettingsPath(), []byte(`{"model": "sonnet"}`), 0o644); err != nil {
+		t.Fatalf("write active: %v", err)
+	}
+	if err := mgr.Save("work"); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	snaps, err := mgr.Snapshots()
+	if err != nil {
+		t.Fatalf("snapshots: %v", err)
+	}
+	opus := snaps[0].Files[0].Hash
+
+	if _, err := mgr.PromoteBackupWithOptions(opus, "work", PromoteOptions{}); !errors.Is(err, ErrSettingsExist) {
+		t.Fatalf("expected ErrSettingsExist, got %v", err)
+	}
+	result, err := mgr.PromoteBackupWithOptions(opus, "fresh", PromoteOptions{})
+	if err != nil {
+		t.Fatalf("promote new profile: %v", err)
+	}
+	if result.Hash != opus || result.Active {
+		t.Fatalf("unexpected result for a new profile: %+v", result)
+	}
+	result, err = mgr.PromoteBackupWithOptions(opus, "work", PromoteOptions{Force: true})
+	if err != nil {
+		t.Fatalf("promote over active: %v", err)
+	}
+	if !result.Active {
+		t.Fatalf("expected the active profile to be reported")
+	}
+	if content, _ := afero.ReadFile(fs, mgr.ActiveSettingsPath()); string(content) != `{"model": "sonnet"}` {
+		t.Fatalf("settings.json should be left unchanged, got %s", content)
+	}
+}
+
+func TestPromoteBackupValidatesContent(t *testing.T) {
+	mgr := newTestManager(t)
+	fs := mgr.FileSystem()
+	if err := afero.WriteFile(fs, mgr.ActiveSettingsPath(), []byte(`{"model": 42}`), 0o644); err != nil {
+		t.Fatalf("write active: %v", err)
+	}
+	if err := afero.WriteFile(fs, filepath.Join(mgr.SettingsStoreDir(), "work.json"), []byte(`{"model": "opus"}`), 0o644); err != nil {
+		t.Fatalf("write work: %v", err)
+	}
+	if err := mgr.Use("work"); err != nil {
+		t.Fatalf("use: %v", err)
+	}
+	snaps, err := mgr.Snapshots()
+	if err != nil {
+		t.Fatalf("snapshots: %v", err)
+	}
+	invalid := snaps[0].Files[0].Hash
+
+	var verr *ValidationError
+	if _, err := mgr.PromoteBackupWithOptions(invalid, "broken", PromoteOptions{}); !errors.As(err, &verr) {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+	if exists, _ := afero.Exists(fs, filepath.Join(mgr.SettingsStoreDir(), "broken.json")); exists {
+		t.Fatalf("an invalid backup must not be stored")
+	}
+	if _, err := mgr.PromoteBackupWithOptions(invalid, "broken", PromoteOptions{NoValidate: true}); err != nil {
+		t.Fatalf("promote without validation: %v", err)
+	}
+}
+
+func TestImportBackupsValidatesSettingsBackups(t *testing.T) {
+	mgr := newTestManager(t)
+	fs := mgr.FileSystem()
+	store := mgr.SettingsStoreDir()
+	if err := afero.WriteFile(fs, mgr.ActiveSettingsPath(), []byte(`{"model": "opus", "modle": "x"}`), 0o644); err != nil {
+		t.Fatalf("write active: %v", err)
+	}
+	if err := afero.WriteFile(fs, filepath.Join(store, "work.json"), []byte(`{"model": "opus"}`), 0o644); err != nil {
+		t.Fatalf("write work: %v", err)
+	}
+	if err := afero.WriteFile(fs, filepath.Join(store, "home.json"), []byte(`{"model": "sonnet"}`), 0o644); err != nil {
+		t.Fatalf("write home: %v", err)
+	}
+	// The second switch also backs up the state file, which is not settings
+	for _, name := range []string{"work", "home"} {
+		if err := mgr.Use(name); err != nil {
+			t.Fatalf("use %s: %v", name, err)
+		}
+	}
+	archive := "/home/test/export.tar.gz"
+	if _, err := mgr.ExportBackups(archive); err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	imported, err := NewManager(fs, "/home/other", nil).ImportBackupsWithOptions(archive, TransferOptions{})
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	if len(imported.Warnings) != 1 || !strings.HasPrefix(imported.Warnings[0].Path, "backup ") {
+		t.Fatalf("expected one warning for the archived settings, got %+v", imported.Warnings)
+	}
+
+	if err := afero.WriteFile(fs, mgr.ActiveSettingsPath(), []byte(`{"model": 42}`), 0o644); err != nil {
+		t.Fatalf("write active: %v", err)
+	}
+	if err := mgr.Use("work"); err != nil {
+		t.Fatalf("use work: %v", err)
+	}
+	if _, err := mgr.ExportBackups(archive); err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	third := NewManager(fs, "/home/third", nil)
+	var verr *ValidationError
+	if _, err := third.ImportBackupsWithOptions(archive, TransferOptions{}); !errors.As(err, &verr) {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+	if snaps, _ := third.Snapshots(); len(snaps) != 0 {
+		t.Fatalf("a rejected import must not add snapshots: %+v", snaps)
+	}
+	if _, err := third.ImportBackupsWithOptions(archive, TransferOptions{NoValidate: true}); err != nil {
+		t.Fatalf("import without validation: %v", err)
+	}
+}
+
+func TestAutomaticRetentionRunsAtMostOncePerInterval(t *testing.T) {
+	mgr := newTestManager(t)
+	config := `{"backup": {"retention": {"maxAge": "1d", "interval": "12h"}}}`
+	if err := afero.WriteFile(mgr.FileSystem(), mgr.ConfigPath(), []byte(config), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if err := mgr.LoadConfig(); err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	stale := filepath.Join(mgr.BackupDir(), "44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a.json")
+	writeStale := func() {
+		t.Helper()
+		if err := afero.WriteFile(mgr.FileSystem(), stale, []byte("{}"), 0o600); err != nil {
+			t.Fatalf("write stale backup: %v", err)
+		}
+		old := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		if err := mgr.FileSystem().Chtimes(stale, old, old); err != nil {
+			t.Fatalf("chtimes: %v", err)
+		}
+	}
+	writeStale()
+	if err := afero.WriteFile(mgr.FileSystem(), mgr.ActiveSettingsPath(), []byte(`{"a":1}`), 0o644); err != nil {
+		t.Fatalf("write active: %v", err)
+	}
+
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	mgr.SetNow(func() time.Time { return now })
+	if err := mgr.Save("work"); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if exists, _ := afero.Exists(mgr.FileSystem(), stale); exists {
+		t.Fatalf("expected stale backup to be pruned after save")
+	}
+
+	// Within the interval, retention does not run again
+	writeStale()
+	now = now.Add(time.Hour)
+	if err := mgr.Use("work"); err != nil {
+		t.Fatalf("use: %v", err)
+	}
+	if exists, _ := afero.Exists(mgr.FileSystem(), stale); !exists {
+		t.Fatalf("retention should be throttled by the interval")
+	}
+
+	now = now.Add(12 * time.Hour)
+	if err := mgr.Use("work"); err != nil {
+		t.Fatalf("use: %v", err)
+	}
+	if exists, _ := afero.Exists(mgr.FileSystem(), stale); exists {
+		t.Fatalf("expected retention to run once the interval passed")
+	}
+}
+
+func TestLoadConfigRejectsInvalidRetention(t *testing.T) {
+	mgr := newTestManager(t)
+	for _, config := range []string{
+		`{"backup": {"retention": {}}}`,
+		`{"backup": {"retention": {"maxAge": "soon"}}}`,
+		`{"backup": {"retention": {"keepLast": -1}}}`,
+	} {
+		if err := afero.WriteFile(mgr.FileSystem(), mgr.ConfigPath(), []byte(config), 0o600); err != nil {
+			t.Fatalf("write config: %v", err)
+		}
+		if err := mgr.LoadConfig(); err == nil {
+			t.Fatalf("expected error for %s", config)
+		}
+	}
+}
+
+func TestLoadConfigRejectsMirrorInsideClaudeDir(t *testing.T) {
+	mgr := newTestManager(t)
+	config := `{"backup": {"mirror": {"dir": "~/.claude/mirror"}}}`
+	if err := afero.WriteFile(mgr.FileSystem(), mgr.ConfigPath(), []byte(config), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	err := mgr.LoadConfig()
+	if err == nil || !strings.Contains(err.Error(), "outside") {
+		t.Fatalf("expected mirror inside ~/.claude to be rejected, got %v", err)
+	}
+}
+
+func TestSyncMirrorRequiresConfiguration(t *testing.T) {
+	mgr := newTestManager(t)
+	if _, err := mgr.SyncMirror(); err == nil || !strings.Contains(err.Error(), "backup.mirror.dir") {
+		t.Fatalf("expected configuration hint, got %v", err)
+	}
+}
+
+func TestListSettingsIgnoresReformattedJSON(t *testing.T) {
+	mgr := newTestManager(t)
+	if err := afero.WriteFile(mgr.FileSystem(), filepath.Join(mgr.SettingsStoreDir(), "work.json"), []byte(`{"model":"opus","n":1}`), 0o644); err != nil {
+		t.Fatalf("write work: %v", err)
+	}
+	if err := mgr.SetActiveSettings("work"); err != nil {
+		t.Fatalf("set active: %v", err)
+	}
+	if err := afero.WriteFile(mgr.FileSystem(), mgr.ActiveSettingsPath(), []byte("{\n  \"n\": 1.0,\n  \"model\": \"opus\"\n}\n"), 0o644); err != nil {
+		t.Fatalf("write active: %v", err)
+	}
+
+	entries, err := mgr.ListSettings()
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if contains(entries[0].Qualifiers, "modified") {
+		t.Fatalf("reformatting should not count as a modification: %+v", entries[0])
+	}
+}
+
+func TestListSettingsSkipsIgnoredKeys(t *testing.T) {
+	mgr := newTestManager(t)
+	config := `{"compare": {"ignore": ["feedbackSurveyState"], "profiles": {"work": {"ignore": ["env.LOCAL"]}}}}`
+	if err := afero.WriteFile(mgr.FileSystem(), mgr.paths.ConfigPath(), []byte(config), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if err := mgr.LoadConfig(); err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	if err := afero.WriteFile(mgr.FileSystem(), filepath.Join(mgr.SettingsStoreDir(), "work.json"), []byte(`{"model":"opus"}`), 0o644); err != nil {
+		t.Fatalf("write work: %v", err)
+	}
+	if err := mgr.SetActiveSettings("work"); err != nil {
+		t.Fatalf("set active: %v", err)
+	}
+
+	for content, modified := range map[string]bool{
+		`{"model":"opus","feedbackSurveyState":{"n":3},"env":{"LOCAL":"1"}}`: false,
+		`{"model":"sonnet","feedbackSurveyState":{"n":3}}`:                   true,
+	} {
+		if err := afero.WriteFile(mgr.FileSystem(), mgr.ActiveSettingsPath(), []byte(content), 0o644); err != nil {
+			t.Fatalf("write active: %v", err)
+		}
+		entries, err := mgr.ListSettings()
+		if err != nil {
+			t.Fatalf("list: %v", err)
+		}
+		if got := contains(entries[0].Qualifiers, "modified"); got != modified {
+			t.Fatalf("settings %s: modified = %v, want %v", content, got, modified)
+		}
+	}
+}
+
+func TestLoadConfigRejectsInvalidIgnorePath(t *testing.T) {
+	mgr := newTestManager(t)
+	for _, config := range []string{
+		`{"compare": {"ignore": ["env..x"]}}`,
+		`{"compare": {"profiles": {"../work": {"ignore": ["env"]}}}}`,
+	} {
+		if err := afero.WriteFile(mgr.FileSystem(), mgr.paths.ConfigPath(), []byte(config), 0o600); err != nil {
+			t.Fatalf("write config: %v", err)
+		}
+		if err := mgr.LoadConfig(); err == nil {
+			t.Fatalf("expected error for %s", config)
+		}
+	}
+}
+
+func TestUseCarriesStickyKeys(t *testing.T) {
+	mgr := newTestManager(t)
+	fs := mgr.FileSystem()
+	config := `{"use": {"sticky": ["statusLine", "env.LOCAL", "env.MISSING"]}}`
+	if err := afero.WriteFile(fs, mgr.paths.ConfigPath(), []byte(config), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if err := mgr.LoadConfig(); err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	current := `{"model":"haiku","statusLine":{"type":"command","command":"~/bin/status"},"env":{"LOCAL":"1"}}`
+	if err := afero.WriteFile(fs, mgr.ActiveSettingsPath(), []byte(current), 0o644); err != nil {
+		t.Fatalf("write active: %v", err)
+	}
+	profile := "{\n    \"model\": \"opus\",\n    \"statusLine\": {\"type\": \"command\", \"command\": \"other\"}\n}\n"
+	if err := afero.WriteFile(fs, filepath.Join(mgr.SettingsStoreDir(), "work.json"), []byte(profile), 0o644); err != nil {
+		t.Fatalf("write profile: %v", err)
+	}
+
+	result, err := mgr.UseWithOptions("work", UseOptions{})
+	if err != nil {
+		t.Fatalf("use: %v", err)
+	}
+	if strings.Join(result.Carried, ",") != "statusLine,env.LOCAL" {
+		t.Fatalf("unexpected carried keys: %v", result.Carried)
+	}
+	content, _ := afero.ReadFile(fs, mgr.ActiveSettingsPath())
+	want := "{\n    \"model\": \"opus\",\n    \"statusLine\": {\n        \"type\": \"command\",\n        \"command\": \"~/bin/status\"\n    },\n    \"env\": {\n        \"LOCAL\": \"1\"\n    }\n}\n"
+	if string(content) != want {
+		t.Fatalf("unexpected active settings:\n%s", content)
+	}
+	entries, err := mgr.ListSettings()
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if contains(entries[0].Qualifiers, "modified") {
+		t.Fatalf("sticky keys should not mark the profile modified: %+v", entries[0])
+	}
+
+	// The stored profile is untouched, and --no-sticky copies it verbatim
+	stored, _ := afero.ReadFile(fs, filepath.Join(mgr.SettingsStoreDir(), "work.json"))
+	if string(stored) != profile {
+		t.Fatalf("profile must not change: %s", stored)
+	}
+	result, err = mgr.UseWithOptions("work", UseOptions{NoSticky: true})
+	if err != nil {
+		t.Fatalf("use no-sticky: %v", err)
+	}
+	content, _ = afero.ReadFile(fs, mgr.ActiveSettingsPath())
+	if len(result.Carried) != 0 || string(content) != profile {
+		t.Fatalf("expected verbatim copy, got %v:\n%s", result.Carried, content)
+	}
+}
@@ -0,0 +1,98 @@
+package ccs
+
+import (
+	"github.com/OpenGG/claude-code-switch-settings/internal/ccs/jsondoc"
+	"github.com/OpenGG/claude-code-switch-settings/internal/ccs/secrets"
+)
+
+// DefaultMatrixKeys are the key paths compared by Matrix when none are given.
+var DefaultMatrixKeys = []string{"model", "env.ANTHROPIC_BASE_URL", "permissions.defaultMode", "hooks"}
+
+// Matrix holds the values of a set of key paths across stored profiles.
+type Matrix struct {
+	// Keys are the compared key paths in dotted form.
+	Keys []string
+	Rows []MatrixRow
+}
+
+// MatrixRow holds one profile's values, in the order of Matrix.Keys.
+type MatrixRow struct {
+	Name   string
+	Active bool
+	Cells  []MatrixCell
+	// Err describes why the profile could not be read; Cells is empty then.
+	Err string
+}
+
+// MatrixCell is the value of one key in one profile.
+type MatrixCell struct {
+	Value   any
+	Present bool
+}
+
+// Matrix reads keys (DefaultMatrixKeys when empty) from every stored profile.
+// Unless reveal is set, values that look like secrets are replaced by their
+// redacted form, including inside objects such as "env". Profiles that are
+// not valid JSON are reported in MatrixRow.Err.
+func (m *Manager) Matrix(keys []string, reveal bool) (Matrix, error) {
+	if err := m.InitInfra(); err != nil {
+		return Matrix{}, err
+	}
+	if len(keys) == 0 {
+		keys = DefaultMatrixKeys
+	}
+	paths, err := parsePaths(keys)
+	if err != nil {
+		return Matrix{}, err
+	}
+	names, err := m.settings.ListStored()
+	if err != nil {
+		return Matrix{}, err
+	}
+
+	matrix := Matrix{Keys: make([]string, len(paths))}
+	for i, path := range paths {
+		matrix.Keys[i] = path.String()
+	}
+	active := m.settings.GetActiveName()
+	for _, name := range names {
+		row := MatrixRow{Name: name, Active: name == active}
+		sd, err := m.loadSettingsDoc(name)
+		if err != nil {
+			row.Err = err.Error()
+			matrix.Rows = append(matrix.Rows, row)
+			continue
+		}
+		doc := sd.doc
+		if !reveal {
+			doc = redactSecrets(doc)
+		}
+		for _, path := range paths {
+			var cell MatrixCell
+			cell.Value, cell.Present = jsondoc.Get(doc, path)
+			row.Cells = append(row.Cells, cell)
+		}
+		matrix.Rows = append(matrix.Rows, row)
+	}
+	return matrix, nil
+}
+
+// redactSecrets returns a copy of doc with every value reported by
+// secrets.Scan replaced by its redacted form.
+func redactSecrets(doc any) any {
+	findings := secrets.Scan(doc)
+	if len(findings) == 0 {
+		return doc
+	}
+	doc = jsondoc.Clone(doc)
+	for _, f := range findings {
+		path, err := jsondoc.ParsePath(f.Path)
+		if err != nil {
+			continue
+		}
+		if doc, err = jsondoc.Set(doc, path, f.Redacted); err != nil {
+			continue
+		}
+	}
+	return doc
+}
@@ -0,0 +1,62 @@
+package ccs
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMatrixRedactsSecretsAndReportsInvalidProfiles(t *testing.T) {
+	mgr := newTestManager(t)
+	token := "sk-ant-api03-" + strings.Repeat("aB3", 12)
+	writeProfiles(t, mgr, map[string]string{
+		"work":   `{"model": "opus", "env": {"ANTHROPIC_AUTH_TOKEN": "` + token + `", "ANTHROPIC_BASE_URL": "https://proxy"}}`,
+		"home":   `{"permissions": {"defaultMode": "plan"}}`,
+		"broken": `{"model":`,
+	})
+	if err := mgr.settings.SetActiveName("work"); err != nil {
+		t.Fatalf("set active: %v", err)
+	}
+
+	matrix, err := mgr.Matrix([]string{"model", "env.ANTHROPIC_AUTH_TOKEN"}, false)
+	if err != nil {
+		t.Fatalf("Matrix: %v", err)
+	}
+	if len(matrix.Rows) != 3 {
+		t.Fatalf("expected 3 rows, got %+v", matrix.Rows)
+	}
+	broken, home, work := matrix.Rows[0], matrix.Rows[1], matrix.Rows[2]
+	if broken.Name != "broken" || broken.Err == "" || len(broken.Cells) != 0 {
+		t.Fatalf("expected broken profile to report an error, got %+v", broken)
+	}
+	if home.Active || home.Cells[0].Present || home.Cells[1].Present {
+		t.Fatalf("expected no values for home, got %+v", home)
+	}
+	if !work.Active || work.Cells[0].Value != "opus" {
+		t.Fatalf("unexpected work row: %+v", work)
+	}
+	if got := work.Cells[1].Value; got != "sk-a****" {
+		t.Fatalf("expected redacted token, got %v", got)
+	}
+
+	matrix, err = mgr.Matrix([]string{"env.ANTHROPIC_AUTH_TOKEN"}, true)
+	if err != nil {
+		t.Fatalf("Matrix reveal: %v", err)
+	}
+	if got := matrix.Rows[2].Cells[0].Value; got != token {
+		t.Fatalf("expected token with reveal, got %v", got)
+	}
+}
+
+func TestMatrixDefaultKeysAndInvalidPath(t *testing.T) {
+	mgr := newTestManager(t)
+	matrix, err := mgr.Matrix(nil, false)
+	if err != nil {
+		t.Fatalf("Matrix: %v", err)
+	}
+	if strings.Join(matrix.Keys, ",") != strings.Join(DefaultMatrixKeys, ",") || len(matrix.Rows) != 0 {
+		t.Fatalf("unexpected matrix: %+v", matrix)
+	}
+	if _, err := mgr.Matrix([]string{"env..x"}, false); err == nil {
+		t.Fatalf("expected invalid path error")
+	}
+}
@@ -0,0 +1,88 @@
+package ccs
+
+import (
+	"fmt"
+
+	"github.com/OpenGG/claude-code-switch-settings/internal/ccs/jsondoc"
+	"github.com/OpenGG/claude-code-switch-settings/internal/ccs/jsonpatch"
+)
+
+// PatchOptions selects the file changed by PatchSettings.
+type PatchOptions struct {
+	// Profile patches the named stored profile instead of settings.json.
+	Profile string
+	// DryRun computes the patched file without writing it.
+	DryRun bool
+	// NoValidate skips the settings schema check.
+	NoValidate bool
+}
+
+// PatchResult reports the result of PatchSettings.
+type PatchResult struct {
+	// File is the patched settings file.
+	File string
+	// Profile is the patched profile, or empty for settings.json.
+	Profile string
+	Kind    jsonpatch.Kind
+	// Changed is false when the patch left the settings as they were; in
+	// that case nothing was written.
+	Changed bool
+	// Before and After hold the file contents before and after the patch.
+	Before []byte
+	After  []byte
+	// Warnings lists schema warnings for the patched settings.
+	Warnings []SchemaIssue
+}
+
+// PatchSettings applies an RFC 6902 JSON Patch (a JSON array) or an RFC 7386
+// merge patch (a JSON object) to settings.json, or to the named profile.
+// Operations apply all or nothing: when one fails, such as a "test" that
+// does not hold, nothing is written. The file is snapshotted before it is
+// rewritten; with DryRun set, the result is reported but not written.
+func (m *Manager) PatchSettings(patch []byte, opts PatchOptions) (PatchResult, error) {
+	if err := m.InitInfra(); err != nil {
+		return PatchResult{}, err
+	}
+	p, err := jsonpatch.Parse(patch)
+	if err != nil {
+		return PatchResult{}, fmt.Errorf("invalid patch: %w", err)
+	}
+	sd, err := m.loadSettingsDoc(opts.Profile)
+	if err != nil {
+		return PatchResult{}, err
+	}
+	doc, err := p.Apply(sd.doc)
+	if err != nil {
+		return PatchResult{}, fmt.Errorf("patch not applied to %s: %w", sd.file, err)
+	}
+
+	result := PatchResult{File: sd.file, Profile: sd.profile, Kind: p.Kind, Before: sd.original, After: sd.original}
+	if jsondoc.Equal(sd.doc, doc) {
+		return result, nil
+	}
+	if result.After, err = jsondoc.MarshalLike(doc, sd.original); err != nil {
+		return PatchResult{}, err
+	}
+	if !opts.NoValidate {
+		if result.Warnings, err = m.validateContent(sd.file, result.After); err != nil {
+			return PatchResult{}, err
+		}
+	}
+	result.Changed = true
+	if opts.DryRun {
+		return result, nil
+	}
+
+	target := "settings.json"
+	if sd.profile != "" {
+		target = sd.profile
+	}
+	if _, err := m.backup.Snapshot("patch "+target, sd.file); err != nil {
+		return PatchResult{}, err
+	}
+	if err := m.storage.WriteFileAtomic(sd.file, result.After); err != nil {
+		return PatchResult{}, fmt.Errorf("failed to write %s: %w", sd.file, err)
+	}
+	m.applyRetention()
+	return result, nil
+}
@@ -0,0 +1,94 @@
+package ccs
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+
+	"github.com/OpenGG/claude-code-switch-settings/internal/ccs/jsonpatch"
+)
+
+func TestPatchSettingsAppliesAndSnapshots(t *testing.T) {
+	mgr := newTestManager(t)
+	writeProfiles(t, mgr, map[string]string{"work": "{\n  \"model\": \"opus\"\n}\n"})
+	file := filepath.Join(mgr.SettingsStoreDir(), "work.json")
+
+	patch := `[{"op": "test", "path": "/model", "value": "opus"}, {"op": "add", "path": "/permissions", "value": {"deny": ["Bash(rm:*)"]}}]`
+	dry, err := mgr.PatchSettings([]byte(patch), PatchOptions{Profile: "work", DryRun: true})
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if !dry.Changed || dry.Kind != jsonpatch.JSONPatch || !strings.Contains(string(dry.After), `"Bash(rm:*)"`) {
+		t.Fatalf("unexpected dry run result: %+v", dry)
+	}
+	if data, _ := afero.ReadFile(mgr.FileSystem(), file); string(data) != string(dry.Before) {
+		t.Fatalf("dry run wrote the file: %s", data)
+	}
+
+	result, err := mgr.PatchSettings([]byte(patch), PatchOptions{Profile: "work"})
+	if err != nil {
+		t.Fatalf("PatchSettings: %v", err)
+	}
+	if data, _ := afero.ReadFile(mgr.FileSystem(), file); string(data) != string(result.After) || string(data) != string(dry.After) {
+		t.Fatalf("unexpected file content: %s", data)
+	}
+	snaps, err := mgr.backup.Snapshots()
+	if err != nil {
+		t.Fatalf("Snapshots: %v", err)
+	}
+	if len(snaps) != 1 || snaps[0].Operation != "patch work" {
+		t.Fatalf("expected one patch snapshot, got %+v", snaps)
+	}
+
+	again, err := mgr.PatchSettings([]byte(`{"model": "opus"}`), PatchOptions{Profile: "work"})
+	if err != nil {
+		t.Fatalf("merge patch: %v", err)
+	}
+	if again.Changed || again.Kind != jsonpatch.MergePatch {
+		t.Fatalf("expected an unchanged merge patch, got %+v", again)
+	}
+}
+
+func TestPatchSettingsFailedTestWritesNothing(t *testing.T) {
+	mgr := newTestManager(t)
+	fs := mgr.FileSystem()
+	original := `{"model": "sonnet"}`
+	if err := afero.WriteFile(fs, mgr.ActiveSettingsPath(), []byte(original), 0o644); err != nil {
+		t.Fatalf("write active: %v", err)
+	}
+
+	patch := `[{"op": "replace", "path": "/model", "value": "haiku"}, {"op": "test", "path": "/model", "value": "opus"}]`
+	if _, err := mgr.PatchSettings([]byte(patch), PatchOptions{}); err == nil || !strings.Contains(err.Error(), "test failed") {
+		t.Fatalf("expected test failure, got %v", err)
+	}
+	if data, _ := afero.ReadFile(fs, mgr.ActiveSettingsPath()); string(data) != original {
+		t.Fatalf("settings.json changed: %s", data)
+	}
+	if snaps, _ := mgr.backup.Snapshots(); len(snaps) != 0 {
+		t.Fatalf("expected no snapshot, got %+v", snaps)
+	}
+
+	if _, err := mgr.PatchSettings([]byte(`"x"`), PatchOptions{}); err == nil || !strings.HasPrefix(err.Error(), "invalid patch") {
+		t.Fatalf("expected invalid patch error, got %v", err)
+	}
+	if _, err := mgr.PatchSettings([]byte(`{}`), PatchOptions{Profile: "missing"}); err == nil {
+		t.Fatalf("expected missing profile error")
+	}
+}
+
+func TestPatchSettingsValidatesResult(t *testing.T) {
+	mgr := newTestManager(t)
+	writeProfiles(t, mgr, map[string]string{"work": `{"model": "opus"}`})
+
+	patch := []byte(`{"permissions": {"defaultMode": 3}}`)
+	var verr *ValidationError
+	if _, err := mgr.PatchSettings(patch, PatchOptions{Profile: "work"}); !errors.As(err, &verr) {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+	if _, err := mgr.PatchSettings(patch, PatchOptions{Profile: "work", NoValidate: true}); err != nil {
+		t.Fatalf("expected --no-validate to allow the patch: %v", err)
+	}
+}
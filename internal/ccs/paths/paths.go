@@ -1,6 +1,9 @@
 package paths
 
-import "path/filepath"
+import (
+	"path/filepath"
+	"strings"
+)
 
 // Directory and file name constants for Claude Code settings
 const (
@@ -9,6 +12,7 @@ const (
 	ActiveFileName   = "settings.json.active"
 	StoreDirName     = "switch-settings"
 	BackupDirName    = "switch-settings-backup"
+	ConfigFileName   = "switch-settings-config.json"
 )
 
 // PathBuilder provides methods to construct Claude Code paths relative to a home directory.
@@ -21,6 +25,26 @@ func New(homeDir string) *PathBuilder {
 	return &PathBuilder{homeDir: homeDir}
 }
 
+// ExpandHome replaces a leading "~/" in path with the home directory.
+func (p *PathBuilder) ExpandHome(path string) string {
+	if path == "~" {
+		return p.homeDir
+	}
+	if strings.HasPrefix(path, "~/") {
+		return filepath.Join(p.homeDir, path[2:])
+	}
+	return path
+}
+
+// IsInClaudeDir reports whether path is the .claude directory or inside it.
+func (p *PathBuilder) IsInClaudeDir(path string) bool {
+	rel, err := filepath.Rel(p.ClaudeDir(), filepath.Clean(path))
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
 // ClaudeDir returns the .claude directory path.
 func (p *PathBuilder) ClaudeDir() string {
 	return filepath.Join(p.homeDir, ClaudeDirName)
@@ -46,6 +70,11 @@ func (p *PathBuilder) BackupDir() string {
 	return filepath.Join(p.ClaudeDir(), BackupDirName)
 }
 
+// ConfigPath returns the path to the optional ccs configuration file.
+func (p *PathBuilder) ConfigPath() string {
+	return filepath.Join(p.ClaudeDir(), ConfigFileName)
+}
+
 // StoredSettingsPath returns the path for a named settings profile.
 func (p *PathBuilder) StoredSettingsPath(name string) string {
 	return filepath.Join(p.SettingsStoreDir(), name+".json")
@@ -101,6 +101,59 @@ func TestBackupDir(t *testing.T) {
 	}
 }
 
+func TestConfigPath(t *testing.T) {
+	homeDir := "/home/test"
+	pb := New(homeDir)
+
+	got := pb.ConfigPath()
+	want := filepath.Join(homeDir, ClaudeDirName, ConfigFileName)
+
+	if got != want {
+		t.Errorf("ConfigPath() = %q, want %q", got, want)
+	}
+}
+
+func TestExpandHome(t *testing.T) {
+	pb := New("/home/test")
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"~", "/home/test"},
+		{"~/keys/backup.key", filepath.Join("/home/test", "keys", "backup.key")},
+		{"/etc/ccs.key", "/etc/ccs.key"},
+		{"relative/~/path", "relative/~/path"},
+	}
+
+	for _, tt := range tests {
+		if got := pb.ExpandHome(tt.input); got != tt.want {
+			t.Errorf("ExpandHome(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestIsInClaudeDir(t *testing.T) {
+	pb := New("/home/test")
+
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{"/home/test/.claude", true},
+		{"/home/test/.claude/backup.key", true},
+		{"/home/test/.claude/../.config/backup.key", false},
+		{"/home/test/.claude-keys/backup.key", false},
+		{"/home/test/backup.key", false},
+	}
+
+	for _, tt := range tests {
+		if got := pb.IsInClaudeDir(tt.input); got != tt.want {
+			t.Errorf("IsInClaudeDir(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
+
 func TestStoredSettingsPath(t *testing.T) {
 	homeDir := "/home/test"
 	pb := New(homeDir)
@@ -159,6 +212,7 @@ func TestPathHierarchy(t *testing.T) {
 		{"ActiveStatePath", pb.ActiveStatePath()},
 		{"SettingsStoreDir", pb.SettingsStoreDir()},
 		{"BackupDir", pb.BackupDir()},
+		{"ConfigPath", pb.ConfigPath()},
 	}
 
 	for _, tt := range paths {
@@ -0,0 +1,124 @@
+package ccs
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/OpenGG/claude-code-switch-settings/internal/ccs/jsondoc"
+)
+
+var (
+	// ErrSettingsCorrupt indicates that settings.json exists but is not valid JSON.
+	ErrSettingsCorrupt = errors.New("settings.json is not valid JSON")
+	// ErrNoValidBackup indicates that no recorded backup of settings.json parses.
+	ErrNoValidBackup = errors.New("no backup of settings.json contains valid JSON")
+)
+
+// CheckActiveSettings reports whether settings.json parses as JSON. It returns
+// an error wrapping ErrSettingsCorrupt when it does not, and nil when the file
+// is valid or absent.
+func (m *Manager) CheckActiveSettings() error {
+	data, err := m.storage.ReadFile(m.paths.ActiveSettingsPath())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to read settings.json: %w", err)
+	}
+	if _, err := jsondoc.Parse(data); err != nil {
+		return fmt.Errorf("%w: %v", ErrSettingsCorrupt, err)
+	}
+	return nil
+}
+
+// Recovery is a planned restore of settings.json from its most recent valid
+// backup, created by PlanRecovery and carried out by ApplyRecovery.
+type Recovery struct {
+	// Snapshot is the snapshot that recorded the recovered content.
+	Snapshot Snapshot
+	// Hash names the backup blob that will be restored.
+	Hash string
+	// File is the path of settings.json.
+	File string
+	// Current is the broken content; Content is what will replace it.
+	Current []byte
+	Content []byte
+
+	existed bool
+}
+
+// PlanRecovery finds the most recent backup of settings.json that parses as
+// JSON and differs from the current content. Nothing is written.
+//
+// It fails when settings.json is valid, so a working file is never replaced
+// by accident; use RestoreSnapshot to go back deliberately.
+func (m *Manager) PlanRecovery() (*Recovery, error) {
+	if err := m.InitInfra(); err != nil {
+		return nil, err
+	}
+	r := &Recovery{File: m.paths.ActiveSettingsPath()}
+	current, err := m.storage.ReadFile(r.File)
+	switch {
+	case err == nil:
+		r.existed = true
+		r.Current = current
+		if _, err := jsondoc.Parse(current); err == nil {
+			return nil, errors.New("settings.json is valid JSON; nothing to recover")
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return nil, fmt.Errorf("failed to read settings.json: %w", err)
+	}
+
+	versions, err := m.backup.History(r.File)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(versions) - 1; i >= 0; i-- {
+		v := versions[i]
+		if v.Hash == "" {
+			continue
+		}
+		content, err := m.backup.ReadBackup(v.Hash)
+		if err != nil {
+			m.logger.Warn("skipping unreadable backup", "hash", v.Hash, "error", err)
+			continue
+		}
+		if bytes.Equal(content, r.Current) {
+			continue
+		}
+		if _, err := jsondoc.Parse(content); err != nil {
+			continue
+		}
+		r.Snapshot, r.Hash, r.Content = v.Snapshot, v.Hash, content
+		return r, nil
+	}
+	return nil, ErrNoValidBackup
+}
+
+// ApplyRecovery writes the content chosen by PlanRecovery to settings.json.
+// The broken file is snapshotted first so it can still be inspected, and the
+// replacement is written atomically. If settings.json changed since the plan
+// was made, nothing is written.
+func (m *Manager) ApplyRecovery(r *Recovery) error {
+	if err := m.InitInfra(); err != nil {
+		return err
+	}
+	current, err := m.storage.ReadFile(r.File)
+	exists := err == nil
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read settings.json: %w", err)
+	}
+	if exists != r.existed || !bytes.Equal(current, r.Current) {
+		return errors.New("settings.json changed since the recovery was planned; run the recovery again")
+	}
+	if _, err := m.backup.Snapshot("recover from "+r.Snapshot.ID, r.File); err != nil {
+		return err
+	}
+	if err := m.storage.WriteFileAtomic(r.File, r.Content); err != nil {
+		return fmt.Errorf("failed to write settings.json: %w", err)
+	}
+	m.applyRetention()
+	return nil
+}
@@ -0,0 +1,70 @@
+package ccs
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+func TestRecoverRestoresMostRecentValidBackup(t *testing.T) {
+	mgr := newTestManager(t)
+	fs := mgr.FileSystem()
+	if err := afero.WriteFile(fs, filepath.Join(mgr.SettingsStoreDir(), "work.json"), []byte(`{"model": "opus"}`), 0o644); err != nil {
+		t.Fatalf("write profile: %v", err)
+	}
+	clock := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	mgr.SetNow(func() time.Time { return clock })
+	// Each use backs up the settings.json it replaces: first a valid file,
+	// then a broken one.
+	for _, content := range []string{`{"model": "haiku"}`, `{"model": `} {
+		clock = clock.Add(time.Hour)
+		if err := afero.WriteFile(fs, mgr.ActiveSettingsPath(), []byte(content), 0o644); err != nil {
+			t.Fatalf("write active: %v", err)
+		}
+		if err := mgr.Use("work"); err != nil {
+			t.Fatalf("use: %v", err)
+		}
+	}
+	if err := afero.WriteFile(fs, mgr.ActiveSettingsPath(), []byte(`{"model": `), 0o644); err != nil {
+		t.Fatalf("corrupt active: %v", err)
+	}
+
+	if err := mgr.CheckActiveSettings(); !errors.Is(err, ErrSettingsCorrupt) {
+		t.Fatalf("expected ErrSettingsCorrupt, got %v", err)
+	}
+	plan, err := mgr.PlanRecovery()
+	if err != nil {
+		t.Fatalf("plan: %v", err)
+	}
+	if string(plan.Content) != `{"model": "haiku"}` || plan.Snapshot.Operation != "use work" {
+		t.Fatalf("unexpected recovery plan: %+v", plan)
+	}
+	if err := mgr.ApplyRecovery(plan); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if err := mgr.CheckActiveSettings(); err != nil {
+		t.Fatalf("expected valid settings after recovery, got %v", err)
+	}
+
+	snaps, err := mgr.Snapshots()
+	if err != nil || !strings.HasPrefix(snaps[0].Operation, "recover from ") {
+		t.Fatalf("expected broken file to be snapshotted: %+v, %v", snaps, err)
+	}
+	if _, err := mgr.PlanRecovery(); err == nil {
+		t.Fatal("expected valid settings to have nothing to recover")
+	}
+}
+
+func TestRecoverWithoutValidBackup(t *testing.T) {
+	mgr := newTestManager(t)
+	if err := afero.WriteFile(mgr.FileSystem(), mgr.ActiveSettingsPath(), []byte("{"), 0o644); err != nil {
+		t.Fatalf("write active: %v", err)
+	}
+	if _, err := mgr.PlanRecovery(); !errors.Is(err, ErrNoValidBackup) {
+		t.Fatalf("expected ErrNoValidBackup, got %v", err)
+	}
+}
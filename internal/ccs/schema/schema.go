@@ -0,0 +1,332 @@
+// Package schema validates settings documents against a JSON Schema.
+//
+// A schema for Claude Code's settings.json is embedded; an updated schema can
+// be loaded with Parse. Only the subset of JSON Schema needed to describe
+// settings is implemented:
+//
+//	type, enum, properties, additionalProperties, required, items, minimum,
+//	and $ref to "#/definitions/..." or "#/$defs/..."
+//
+// Other keywords are ignored. Keys rejected by "additionalProperties": false
+// are reported as warnings, since Claude Code gains new settings faster than
+// the schema; every other violation is an error.
+package schema
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/OpenGG/claude-code-switch-settings/internal/ccs/jsondoc"
+)
+
+//go:embed claude-settings.schema.json
+var defaultSchema []byte
+
+// Severity ranks a validation issue.
+type Severity int
+
+const (
+	// Warning marks a likely mistake that does not break Claude Code, such
+	// as an unknown (possibly misspelled) key.
+	Warning Severity = iota
+	// Error marks a value Claude Code cannot use, such as a wrong type.
+	Error
+)
+
+func (s Severity) String() string {
+	if s == Error {
+		return "error"
+	}
+	return "warning"
+}
+
+// Issue is one validation finding.
+type Issue struct {
+	// Path locates the offending value in dotted form; empty for the root.
+	Path     string
+	Severity Severity
+	Message  string
+}
+
+func (i Issue) String() string {
+	path := i.Path
+	if path == "" {
+		path = "(root)"
+	}
+	return path + ": " + i.Message
+}
+
+// HasErrors reports whether any issue has Error severity.
+func HasErrors(issues []Issue) bool {
+	for _, issue := range issues {
+		if issue.Severity == Error {
+			return true
+		}
+	}
+	return false
+}
+
+// maxRefHops bounds how many $ref hops are followed for one value.
+const maxRefHops = 16
+
+// Schema is a parsed JSON Schema.
+type Schema struct {
+	root *node
+}
+
+type node struct {
+	Type                 typeList          `json:"type"`
+	Enum                 []json.RawMessage `json:"enum"`
+	Properties           map[string]*node  `json:"properties"`
+	AdditionalProperties *additional       `json:"additionalProperties"`
+	Required             []string          `json:"required"`
+	Items                *node             `json:"items"`
+	Minimum              *json.Number      `json:"minimum"`
+	Ref                  string            `json:"$ref"`
+	Definitions          map[string]*node  `json:"definitions"`
+	Defs                 map[string]*node  `json:"$defs"`
+
+	enum     []any
+	resolved *node
+}
+
+// typeList accepts "type" as a single name or a list of names.
+type typeList []string
+
+func (t *typeList) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*t = typeList{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return errors.New("type must be a string or an array of strings")
+	}
+	*t = many
+	return nil
+}
+
+// additional is "additionalProperties": either a boolean or a schema.
+type additional struct {
+	allowed bool
+	schema  *node
+}
+
+func (a *additional) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &a.allowed); err == nil {
+		return nil
+	}
+	a.allowed = true
+	return json.Unmarshal(data, &a.schema)
+}
+
+// Default returns the embedded Claude Code settings schema.
+func Default() *Schema {
+	s, err := Parse(defaultSchema)
+	if err != nil {
+		panic(fmt.Sprintf("embedded settings schema is invalid: %v", err))
+	}
+	return s
+}
+
+// Parse reads a JSON Schema document.
+func Parse(data []byte) (*Schema, error) {
+	var root node
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	if err := root.prepare(&root); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	return &Schema{root: &root}, nil
+}
+
+// prepare resolves references and decodes enum values, recursively.
+func (n *node) prepare(root *node) error {
+	if n == nil {
+		return nil
+	}
+	if n.Ref != "" {
+		var defs map[string]*node
+		var name string
+		switch {
+		case strings.HasPrefix(n.Ref, "#/definitions/"):
+			defs, name = root.Definitions, strings.TrimPrefix(n.Ref, "#/definitions/")
+		case strings.HasPrefix(n.Ref, "#/$defs/"):
+			defs, name = root.Defs, strings.TrimPrefix(n.Ref, "#/$defs/")
+		default:
+			return fmt.Errorf("unsupported $ref %q", n.Ref)
+		}
+		if n.resolved = defs[name]; n.resolved == nil {
+			return fmt.Errorf("unresolved $ref %q", n.Ref)
+		}
+	}
+	for _, raw := range n.Enum {
+		v, err := jsondoc.Parse(raw)
+		if err != nil {
+			return fmt.Errorf("enum: %w", err)
+		}
+		n.enum = append(n.enum, v)
+	}
+	children := []*node{n.Items}
+	if n.AdditionalProperties != nil {
+		children = append(children, n.AdditionalProperties.schema)
+	}
+	for _, group := range []map[string]*node{n.Properties, n.Definitions, n.Defs} {
+		for _, child := range group {
+			children = append(children, child)
+		}
+	}
+	for _, child := range children {
+		if err := child.prepare(root); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate checks doc, a value decoded by jsondoc.Parse, and returns the
+// issues found in document order.
+func (s *Schema) Validate(doc any) []Issue {
+	var issues []Issue
+	s.root.validate(doc, nil, &issues)
+	return issues
+}
+
+func (n *node) validate(v any, path jsondoc.Path, issues *[]Issue) {
+	// Follow references, bounding the hops so a cyclic chain cannot loop
+	for hops := 0; n.resolved != nil && hops < maxRefHops; hops++ {
+		n = n.resolved
+	}
+	report := func(sev Severity, format string, args ...any) {
+		*issues = append(*issues, Issue{Path: path.String(), Severity: sev, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if len(n.Type) > 0 && !matchesType(v, n.Type) {
+		report(Error, "expected %s, got %s", strings.Join(n.Type, " or "), jsondoc.TypeName(v))
+		return
+	}
+	if n.enum != nil && !inEnum(v, n.enum) {
+		report(Error, "must be one of %s", enumList(n.enum))
+		return
+	}
+	if n.Minimum != nil {
+		if num, ok := v.(json.Number); ok {
+			if f, err := num.Float64(); err == nil {
+				if floor, _ := n.Minimum.Float64(); f < floor {
+					report(Error, "must be at least %s", n.Minimum.String())
+				}
+			}
+		}
+	}
+
+	switch t := v.(type) {
+	case *jsondoc.Object:
+		for _, key := range n.Required {
+			if _, ok := t.Get(key); !ok {
+				report(Error, "missing required key %q", key)
+			}
+		}
+		for _, key := range t.Keys() {
+			child, _ := t.Get(key)
+			childPath := append(append(jsondoc.Path(nil), path...), jsondoc.Segment{Key: key})
+			if prop, ok := n.Properties[key]; ok {
+				prop.validate(child, childPath, issues)
+				continue
+			}
+			switch {
+			case n.AdditionalProperties == nil:
+			case n.AdditionalProperties.schema != nil:
+				n.AdditionalProperties.schema.validate(child, childPath, issues)
+			case !n.AdditionalProperties.allowed:
+				msg := "unknown key"
+				if suggestion := closestKey(key, n.Properties); suggestion != "" {
+					msg += fmt.Sprintf(" (did you mean %q?)", suggestion)
+				}
+				*issues = append(*issues, Issue{Path: childPath.String(), Severity: Warning, Message: msg})
+			}
+		}
+	case []any:
+		if n.Items != nil {
+			for i, item := range t {
+				childPath := append(append(jsondoc.Path(nil), path...), jsondoc.Segment{Key: fmt.Sprint(i), Index: i, IsIndex: true})
+				n.Items.validate(item, childPath, issues)
+			}
+		}
+	}
+}
+
+func matchesType(v any, types []string) bool {
+	actual := jsondoc.TypeName(v)
+	for _, t := range types {
+		if t == actual {
+			return true
+		}
+		if t == "integer" && actual == "number" {
+			if _, err := v.(json.Number).Int64(); err == nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func inEnum(v any, values []any) bool {
+	for _, allowed := range values {
+		if jsondoc.Equal(v, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+func enumList(values []any) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		data, _ := jsondoc.Marshal(v, "")
+		parts[i] = string(data)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// closestKey suggests a known property for a misspelled key: the one within
+// an edit distance of two, preferring the closest and then alphabetical order.
+func closestKey(key string, properties map[string]*node) string {
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	best, bestDist := "", 3
+	for _, name := range names {
+		if d := editDistance(strings.ToLower(key), strings.ToLower(name)); d < bestDist {
+			best, bestDist = name, d
+		}
+	}
+	return best
+}
+
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,92 @@
+package schema
+
+// Tests for settings schema validation.
+//
+// Focus: the embedded Claude Code schema, warning vs error severity, $ref
+// resolution and loading custom schemas.
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/OpenGG/claude-code-switch-settings/internal/ccs/jsondoc"
+)
+
+func validate(t *testing.T, s *Schema, doc string) []Issue {
+	t.Helper()
+	v, err := jsondoc.Parse([]byte(doc))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	return s.Validate(v)
+}
+
+func TestDefault_AcceptsTypicalSettings(t *testing.T) {
+	doc := `{
+		"model": "opus",
+		"env": {"ANTHROPIC_BASE_URL": "https://example.test"},
+		"permissions": {"allow": ["Read", "Bash(ls:*)"], "defaultMode": "acceptEdits"},
+		"hooks": {"Stop": [{"matcher": "", "hooks": [{"type": "command", "command": "notify", "timeout": 5}]}]},
+		"statusLine": {"type": "command", "command": "~/bin/status"},
+		"cleanupPeriodDays": 30
+	}`
+	if issues := validate(t, Default(), doc); len(issues) != 0 {
+		t.Fatalf("expected no issues, got %v", issues)
+	}
+}
+
+func TestDefault_UnknownKeysAreWarnings(t *testing.T) {
+	issues := validate(t, Default(), `{"permisions": {"allow": []}, "hooks": {"Stopp": []}}`)
+	if len(issues) != 2 || HasErrors(issues) {
+		t.Fatalf("expected two warnings, got %v", issues)
+	}
+	if issues[0].Path != "permisions" || !strings.Contains(issues[0].Message, `did you mean "permissions"?`) {
+		t.Fatalf("unexpected warning: %v", issues[0])
+	}
+	if issues[1].Path != "hooks.Stopp" {
+		t.Fatalf("unexpected warning path: %v", issues[1])
+	}
+}
+
+func TestDefault_TypeMismatchesAreErrors(t *testing.T) {
+	issues := validate(t, Default(), `{
+		"permissions": {"allow": "Read", "defaultMode": "yolo"},
+		"env": {"N": 1},
+		"cleanupPeriodDays": 1.5,
+		"hooks": {"Stop": [{"hooks": [{"type": "command"}]}]}
+	}`)
+	want := []string{
+		"permissions.allow: expected array, got string",
+		`permissions.defaultMode: must be one of "default", "acceptEdits", "plan", "bypassPermissions"`,
+		"env.N: expected string, got number",
+		"cleanupPeriodDays: expected integer, got number",
+		`hooks.Stop[0].hooks[0]: missing required key "command"`,
+	}
+	if len(issues) != len(want) {
+		t.Fatalf("expected %d issues, got %v", len(want), issues)
+	}
+	for i, issue := range issues {
+		if issue.Severity != Error || issue.String() != want[i] {
+			t.Errorf("issue %d = %v (%s), want %q", i, issue, issue.Severity, want[i])
+		}
+	}
+}
+
+func TestParse_CustomSchema(t *testing.T) {
+	s, err := Parse([]byte(`{"type": "object", "properties": {"n": {"$ref": "#/$defs/count"}}, "$defs": {"count": {"type": ["integer", "null"], "minimum": 1}}}`))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if issues := validate(t, s, `{"n": null, "extra": true}`); len(issues) != 0 {
+		t.Fatalf("expected no issues, got %v", issues)
+	}
+	if issues := validate(t, s, `{"n": 0}`); len(issues) != 1 || issues[0].String() != "n: must be at least 1" {
+		t.Fatalf("unexpected issues %v", issues)
+	}
+
+	for _, bad := range []string{`{"$ref": "#/definitions/missing"}`, `{"$ref": "http://example.test/s.json"}`, `{"type": 1}`, `[`} {
+		if _, err := Parse([]byte(bad)); err == nil {
+			t.Errorf("expected error for %s", bad)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package ccs
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/OpenGG/claude-code-switch-settings/internal/ccs/jsondoc"
+	"github.com/OpenGG/claude-code-switch-settings/internal/ccs/secrets"
+)
+
+// SecretFinding is a settings value that looks like a secret.
+type SecretFinding = secrets.Finding
+
+// SecretReport lists the values that look like secrets in one file or backup.
+type SecretReport struct {
+	// Source names the scanned file or backup, such as "settings.json" or
+	// "backup 1a2b3c4d5e6f".
+	Source   string
+	Findings []SecretFinding
+}
+
+// SecretError reports that secret detection is strict and secrets were found.
+// Nothing has been written.
+type SecretError struct {
+	Reports []SecretReport
+}
+
+func (e *SecretError) Error() string {
+	var b strings.Builder
+	b.WriteString("refusing to store values that look like secrets:")
+	var vars []string
+	for _, r := range e.Reports {
+		for _, f := range r.Findings {
+			fmt.Fprintf(&b, "\n  %s: %s", r.Source, f)
+			if name, ok := strings.CutPrefix(f.Path, "env."); ok {
+				vars = append(vars, name)
+			}
+		}
+	}
+	if len(vars) > 0 {
+		fmt.Fprintf(&b, "\nMove them to environment references: export %s in your shell instead of keeping the values in the settings file", strings.Join(vars, ", "))
+	} else {
+		b.WriteString("\nMove them to environment references, or fetch API keys with apiKeyHelper")
+	}
+	return b.String()
+}
+
+type secretsMode int
+
+const (
+	secretsWarn secretsMode = iota
+	secretsStrict
+	secretsOff
+)
+
+func parseSecretsMode(s string) (secretsMode, error) {
+	switch s {
+	case "", "warn":
+		return secretsWarn, nil
+	case "strict":
+		return secretsStrict, nil
+	case "off":
+		return secretsOff, nil
+	default:
+		return secretsWarn, fmt.Errorf("mode: unknown value %q (want warn, strict or off)", s)
+	}
+}
+
+// checkSecrets scans the contents passed to report by scan and returns a
+// report for each source holding secrets. With strict set, or secrets.mode
+// "strict", any finding is returned as a *SecretError. With secrets.mode
+// "off" and strict unset, nothing is scanned. Content that is not valid JSON
+// is skipped.
+func (m *Manager) checkSecrets(strict bool, scan func(report func(source string, content []byte)) error) ([]SecretReport, error) {
+	if m.secretsMode == secretsOff && !strict {
+		return nil, nil
+	}
+	var reports []SecretReport
+	err := scan(func(source string, content []byte) {
+		doc, err := jsondoc.Parse(content)
+		if err != nil {
+			return
+		}
+		if findings := secrets.Scan(doc); len(findings) > 0 {
+			reports = append(reports, SecretReport{Source: source, Findings: findings})
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(reports) > 0 && (strict || m.secretsMode == secretsStrict) {
+		return nil, &SecretError{Reports: reports}
+	}
+	return reports, nil
+}
@@ -0,0 +1,146 @@
+// Package secrets detects credentials stored in settings documents.
+//
+// Scan looks for well-known token formats anywhere in a document and for
+// random-looking strings in env, where Claude Code settings usually carry
+// API keys. Documents are values decoded by jsondoc.Parse.
+package secrets
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+
+	"github.com/OpenGG/claude-code-switch-settings/internal/ccs/jsondoc"
+)
+
+// Finding is a value that looks like a secret.
+type Finding struct {
+	// Path locates the value in dotted form.
+	Path string `json:"path"`
+	// Kind describes the match, such as "Anthropic API key".
+	Kind string `json:"kind"`
+	// Redacted shows the start of the value only.
+	Redacted string `json:"redacted"`
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: %s (%s)", f.Path, f.Kind, f.Redacted)
+}
+
+type tokenPattern struct {
+	kind    string
+	pattern *regexp.Regexp
+}
+
+// tokenPatterns lists well-known credential formats, most specific first.
+var tokenPatterns = []tokenPattern{
+	{"Anthropic API key", regexp.MustCompile(`sk-ant-[A-Za-z0-9_-]{20,}`)},
+	{"OpenAI API key", regexp.MustCompile(`sk-(?:proj-)?[A-Za-z0-9_-]{20,}`)},
+	{"GitHub token", regexp.MustCompile(`(?:gh[pousr]_[A-Za-z0-9]{30,}|github_pat_[A-Za-z0-9_]{30,})`)},
+	{"GitLab token", regexp.MustCompile(`glpat-[A-Za-z0-9_-]{20,}`)},
+	{"Slack token", regexp.MustCompile(`xox[abprs]-[A-Za-z0-9-]{10,}`)},
+	{"AWS access key", regexp.MustCompile(`(?:AKIA|ASIA)[0-9A-Z]{16}`)},
+	{"Google API key", regexp.MustCompile(`AIza[0-9A-Za-z_-]{35}`)},
+	{"private key", regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----`)},
+}
+
+const (
+	// minRandomLength is the shortest env value considered as a random token.
+	minRandomLength = 20
+	// minEntropy is the Shannon entropy, in bits per character, above which
+	// a token-shaped env value is treated as random.
+	minEntropy = 3.5
+)
+
+var tokenCharset = regexp.MustCompile(`^[A-Za-z0-9+/=_-]+$`)
+var hexCharset = regexp.MustCompile(`^[0-9a-fA-F]+$`)
+
+// Scan returns the values in doc that look like secrets, in document order.
+// Strings starting with "$" are references to the environment and are never
+// reported.
+func Scan(doc any) []Finding {
+	var findings []Finding
+	walk(doc, nil, func(path jsondoc.Path, s string) {
+		if strings.HasPrefix(s, "$") {
+			return
+		}
+		if kind := Classify(s); kind != "" {
+			findings = append(findings, Finding{Path: path.String(), Kind: kind, Redacted: Redact(s)})
+			return
+		}
+		if len(path) == 2 && path[0].Key == "env" && !path[0].IsIndex && looksRandom(s) {
+			findings = append(findings, Finding{Path: path.String(), Kind: "high-entropy value", Redacted: Redact(s)})
+		}
+	})
+	return findings
+}
+
+// Classify returns the kind of well-known token contained in s, or "".
+func Classify(s string) string {
+	for _, p := range tokenPatterns {
+		if p.pattern.MatchString(s) {
+			return p.kind
+		}
+	}
+	return ""
+}
+
+// looksRandom reports whether s is shaped like a generated token: long,
+// without separators, high in entropy, and either mixing upper case, lower
+// case and digits or made of at least 32 hex digits.
+func looksRandom(s string) bool {
+	if len(s) < minRandomLength || !tokenCharset.MatchString(s) || Entropy(s) < minEntropy {
+		return false
+	}
+	if len(s) >= 32 && hexCharset.MatchString(s) {
+		return true
+	}
+	return strings.ContainsAny(s, "abcdefghijklmnopqrstuvwxyz") &&
+		strings.ContainsAny(s, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") &&
+		strings.ContainsAny(s, "0123456789")
+}
+
+// Entropy returns the Shannon entropy of s in bits per character.
+func Entropy(s string) float64 {
+	if s == "" {
+		return 0
+	}
+	counts := make(map[rune]int)
+	n := 0
+	for _, r := range s {
+		counts[r]++
+		n++
+	}
+	var h float64
+	for _, c := range counts {
+		p := float64(c) / float64(n)
+		h -= p * math.Log2(p)
+	}
+	return h
+}
+
+// Redact hides all but the first four characters of a secret.
+func Redact(s string) string {
+	const shown = 4
+	if len(s) <= 2*shown {
+		return "****"
+	}
+	return s[:shown] + "****"
+}
+
+func walk(v any, path jsondoc.Path, visit func(jsondoc.Path, string)) {
+	switch v := v.(type) {
+	case *jsondoc.Object:
+		for _, key := range v.Keys() {
+			child, _ := v.Get(key)
+			walk(child, append(path[:len(path):len(path)], jsondoc.Segment{Key: key}), visit)
+		}
+	case []any:
+		for i, child := range v {
+			walk(child, append(path[:len(path):len(path)], jsondoc.Segment{Index: i, IsIndex: true}), visit)
+		}
+	case string:
+		visit(path, v)
+	}
+}
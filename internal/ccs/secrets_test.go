@@ -0,0 +1,115 @@
+package ccs
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+const settingsWithToken = `{"env": {"ANTHROPIC_AUTH_TOKEN": "sk-ant-REDACTED"}}`
+
+func TestSaveReportsSecrets(t *testing.T) {
+	mgr := newTestManager(t)
+	fs := mgr.FileSystem()
+	if err := afero.WriteFile(fs, mgr.ActiveSettingsPath(), []byte(settingsWithToken), 0o644); err != nil {
+		t.Fatalf("write active: %v", err)
+	}
+
+	result, err := mgr.SaveWithOptions("work", SaveOptions{})
+	if err != nil {
+		t.Fatalf("SaveWithOptions: %v", err)
+	}
+	if len(result.Secrets) != 1 || result.Secrets[0].Source != "settings.json" || result.Secrets[0].Findings[0].Path != "env.ANTHROPIC_AUTH_TOKEN" {
+		t.Fatalf("expected a secret warning, got %+v", result.Secrets)
+	}
+
+	var serr *SecretError
+	_, err = mgr.SaveWithOptions("strict", SaveOptions{Strict: true})
+	if !errors.As(err, &serr) {
+		t.Fatalf("expected a secret error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "export ANTHROPIC_AUTH_TOKEN in your shell") || strings.Contains(err.Error(), "abcdefghijkl") {
+		t.Fatalf("unexpected error message: %v", err)
+	}
+	if exists, _ := afero.Exists(fs, filepath.Join(mgr.SettingsStoreDir(), "strict.json")); exists {
+		t.Fatal("a refused save must not store the profile")
+	}
+
+	if err := afero.WriteFile(fs, mgr.paths.ConfigPath(), []byte(`{"secrets": {"mode": "strict"}}`), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if err := mgr.LoadConfig(); err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if _, err := mgr.SaveWithOptions("work", SaveOptions{}); !errors.As(err, &serr) {
+		t.Fatalf("secrets.mode strict should refuse the save, got %v", err)
+	}
+
+	if err := afero.WriteFile(fs, mgr.paths.ConfigPath(), []byte(`{"secrets": {"mode": "off"}}`), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if err := mgr.LoadConfig(); err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if result, err := mgr.SaveWithOptions("work", SaveOptions{}); err != nil || len(result.Secrets) != 0 {
+		t.Fatalf("secrets.mode off should not scan: %+v, %v", result, err)
+	}
+}
+
+func TestExportAndImportCheckSecrets(t *testing.T) {
+	mgr := newTestManager(t)
+	fs := mgr.FileSystem()
+	if err := afero.WriteFile(fs, mgr.ActiveSettingsPath(), []byte(settingsWithToken), 0o644); err != nil {
+		t.Fatalf("write active: %v", err)
+	}
+	if err := afero.WriteFile(fs, filepath.Join(mgr.SettingsStoreDir(), "work.json"), []byte(`{"model": "opus"}`), 0o644); err != nil {
+		t.Fatalf("write profile: %v", err)
+	}
+	if err := mgr.Use("work"); err != nil {
+		t.Fatalf("use: %v", err)
+	}
+
+	archive := "/home/test/export.tar.gz"
+	var serr *SecretError
+	if _, err := mgr.ExportBackupsWithOptions(archive, TransferOptions{Strict: true}); !errors.As(err, &serr) {
+		t.Fatalf("expected a secret error, got %v", err)
+	}
+	if exists, _ := afero.Exists(fs, archive); exists {
+		t.Fatal("a refused export must not write the archive")
+	}
+	exported, err := mgr.ExportBackupsWithOptions(archive, TransferOptions{})
+	if err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	if exported.Exported != 1 || len(exported.Secrets) != 1 || !strings.HasPrefix(exported.Secrets[0].Source, "backup ") {
+		t.Fatalf("unexpected export result: %+v", exported)
+	}
+
+	other := NewManager(fs, "/home/other", nil)
+	if _, err := other.ImportBackupsWithOptions(archive, TransferOptions{Strict: true}); !errors.As(err, &serr) {
+		t.Fatalf("expected a secret error, got %v", err)
+	}
+	if snaps, _ := other.Snapshots(); len(snaps) != 0 {
+		t.Fatalf("a refused import must not add snapshots: %+v", snaps)
+	}
+	imported, err := other.ImportBackupsWithOptions(archive, TransferOptions{})
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	if imported.Imported != 1 || len(imported.Secrets) != 1 {
+		t.Fatalf("unexpected import result: %+v", imported)
+	}
+}
+
+func TestLoadConfigRejectsUnknownSecretsMode(t *testing.T) {
+	mgr := newTestManager(t)
+	if err := afero.WriteFile(mgr.FileSystem(), mgr.paths.ConfigPath(), []byte(`{"secrets": {"mode": "loud"}}`), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if err := mgr.LoadConfig(); err == nil || !strings.Contains(err.Error(), "invalid secrets configuration") {
+		t.Fatalf("expected secrets error, got %v", err)
+	}
+}
@@ -99,7 +99,9 @@ type ListEntry struct {
 //
 // The function compares stored profiles with the active settings and annotates
 // entries with their status. If the active profile has been modified locally,
-// it will be marked with "modified".
+// it will be marked with "modified". Files are compared by the hashes
+// calculateHash returns, so a caller can ignore formatting differences by
+// passing a semantic fingerprint.
 func (s *Service) ListEntries(activePath string, calculateHash func(string) (string, error)) ([]ListEntry, error) {
 	activeName := s.GetActiveName()
 	currentHash, err := calculateHash(activePath)
@@ -0,0 +1,64 @@
+package ccs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/OpenGG/claude-code-switch-settings/internal/ccs/jsondoc"
+)
+
+// carrySticky returns the content of the profile at profilePath with the
+// values of the sticky keys set in the current settings.json carried over,
+// and the keys carried. Keys absent from the current file keep the profile's
+// value.
+//
+// It returns nil content when nothing needs carrying, in which case the
+// profile should be copied verbatim. When either file is not valid JSON the
+// keys cannot be merged; a warning is logged and nil content is returned.
+func (m *Manager) carrySticky(profilePath string) ([]byte, []string, error) {
+	current, err := m.storage.ReadFile(m.paths.ActiveSettingsPath())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil, nil
+		}
+		return nil, nil, fmt.Errorf("failed to read settings.json: %w", err)
+	}
+	currentDoc, err := jsondoc.Parse(current)
+	if err != nil {
+		m.logger.Warn("not carrying sticky keys: settings.json is not valid JSON", "error", err)
+		return nil, nil, nil
+	}
+	profile, err := m.storage.ReadFile(profilePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read target settings: %w", err)
+	}
+	doc, err := jsondoc.Parse(profile)
+	if err != nil {
+		m.logger.Warn("not carrying sticky keys: profile is not valid JSON", "path", profilePath, "error", err)
+		return nil, nil, nil
+	}
+
+	var carried []string
+	for _, path := range m.sticky {
+		value, ok := jsondoc.Get(currentDoc, path)
+		if !ok {
+			continue
+		}
+		if existing, ok := jsondoc.Get(doc, path); ok && jsondoc.Equal(existing, value) {
+			continue
+		}
+		if doc, err = jsondoc.Set(doc, path, value); err != nil {
+			return nil, nil, fmt.Errorf("cannot carry sticky key: %w", err)
+		}
+		carried = append(carried, path.String())
+	}
+	if len(carried) == 0 {
+		return nil, nil, nil
+	}
+	content, err := jsondoc.MarshalLike(doc, profile)
+	if err != nil {
+		return nil, nil, err
+	}
+	return content, carried, nil
+}
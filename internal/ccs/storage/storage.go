@@ -110,6 +110,63 @@ func (s *Storage) WriteFile(path string, data []byte) error {
 	return afero.WriteFile(s.fs, path, data, 0o600)
 }
 
+// WriteFileAtomic writes data to a temp file next to path and renames it into
+// place, so readers never observe a partially written file.
+func (s *Storage) WriteFileAtomic(path string, data []byte) error {
+	return s.WriteFileVerified(path, data, nil)
+}
+
+// WriteFileVerified is WriteFileAtomic with a check of what actually reached
+// the disk: the temp file is synced, read back and passed to verify, and only
+// renamed into place if verify returns nil. A nil verify skips the read-back.
+func (s *Storage) WriteFileVerified(path string, data []byte, verify func(written []byte) error) error {
+	if err := s.ValidatePathSafety(path); err != nil {
+		return fmt.Errorf("validate destination: %w", err)
+	}
+	if err := s.fs.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("create directory: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := s.writeSynced(tmp, data); err != nil {
+		s.fs.Remove(tmp)
+		return fmt.Errorf("write temp file: %w", err)
+	}
+	if verify != nil {
+		written, err := afero.ReadFile(s.fs, tmp)
+		if err == nil {
+			err = verify(written)
+		}
+		if err != nil {
+			s.fs.Remove(tmp)
+			return fmt.Errorf("verify temp file: %w", err)
+		}
+	}
+	if err := s.fs.Rename(tmp, path); err != nil {
+		s.fs.Remove(tmp)
+		return fmt.Errorf("atomic rename: %w", err)
+	}
+	return nil
+}
+
+// writeSynced writes data to path and flushes it to stable storage before
+// closing, so a rename after a crash never exposes an empty file.
+func (s *Storage) writeSynced(path string, data []byte) error {
+	f, err := s.fs.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
 // Exists checks if a path exists.
 func (s *Storage) Exists(path string) (bool, error) {
 	return afero.Exists(s.fs, path)
@@ -130,6 +187,17 @@ func (s *Storage) ReadDir(path string) ([]os.FileInfo, error) {
 	return afero.ReadDir(s.fs, path)
 }
 
+// Rename atomically moves a file, validating both paths first.
+func (s *Storage) Rename(oldPath, newPath string) error {
+	if err := s.ValidatePathSafety(oldPath); err != nil {
+		return fmt.Errorf("validate source: %w", err)
+	}
+	if err := s.ValidatePathSafety(newPath); err != nil {
+		return fmt.Errorf("validate destination: %w", err)
+	}
+	return s.fs.Rename(oldPath, newPath)
+}
+
 // Remove deletes a file.
 func (s *Storage) Remove(path string) error {
 	return s.fs.Remove(path)
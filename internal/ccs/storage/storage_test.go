@@ -2,7 +2,8 @@ package storage
 
 // Tests for atomic file operations and security requirements.
 //
-// Focus: CopyFile (atomic with temp files), ValidatePathSafety (symlink protection),
+// Focus: CopyFile (atomic with temp files), WriteFileVerified (read-back check),
+// ValidatePathSafety (symlink protection),
 // secure permissions (0600 files, 0700 dirs).
 //
 // Note: Simple wrappers (ReadFile, WriteFile, etc.) tested via integration tests.
@@ -216,3 +217,65 @@ func TestMkdirAll_SecurePermissions(t *testing.T) {
 		t.Errorf("expected secure mode 0700, got %o", info.Mode().Perm())
 	}
 }
+
+func TestWriteFileAtomic_ReplacesContent(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	storage := New(fs)
+
+	path := "/test/file.json"
+	if err := afero.WriteFile(fs, path, []byte("old"), 0o644); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+
+	if err := storage.WriteFileAtomic(path, []byte("new")); err != nil {
+		t.Fatalf("WriteFileAtomic failed: %v", err)
+	}
+
+	content, err := afero.ReadFile(fs, path)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if string(content) != "new" {
+		t.Errorf("expected 'new', got %q", string(content))
+	}
+	if exists, _ := afero.Exists(fs, path+".tmp"); exists {
+		t.Error("temp file should not remain after atomic write")
+	}
+	info, err := fs.Stat(path)
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("expected file mode 0600, got %o", info.Mode().Perm())
+	}
+}
+
+func TestWriteFileVerified_RejectedWriteKeepsOriginal(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	storage := New(fs)
+
+	path := "/test/file.json"
+	if err := afero.WriteFile(fs, path, []byte("old"), 0o600); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+
+	var seen string
+	errMismatch := errors.New("mismatch")
+	err := storage.WriteFileVerified(path, []byte("new"), func(written []byte) error {
+		seen = string(written)
+		return errMismatch
+	})
+	if !errors.Is(err, errMismatch) {
+		t.Fatalf("expected verification error, got %v", err)
+	}
+	if seen != "new" {
+		t.Errorf("verify should receive the written bytes, got %q", seen)
+	}
+	content, _ := afero.ReadFile(fs, path)
+	if string(content) != "old" {
+		t.Errorf("original should be untouched, got %q", string(content))
+	}
+	if exists, _ := afero.Exists(fs, path+".tmp"); exists {
+		t.Error("temp file should be removed after failed verification")
+	}
+}
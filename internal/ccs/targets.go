@@ -0,0 +1,48 @@
+package ccs
+
+import "fmt"
+
+// settingsFile is a settings file selected by name on the command line.
+type settingsFile struct {
+	// profile is the stored profile name, or empty for settings.json.
+	profile string
+	file    string
+	// match is the name matched against profile patterns: the profile name,
+	// or the active profile's name for settings.json.
+	match string
+}
+
+// selectFiles resolves the [names...|--all] arguments shared by lint and fmt:
+// the named stored profiles, every stored profile with all, or settings.json
+// when neither is given. Named profiles must exist.
+func (m *Manager) selectFiles(names []string, all bool) ([]settingsFile, error) {
+	if all {
+		if len(names) > 0 {
+			return nil, fmt.Errorf("cannot combine profile names with --all")
+		}
+		stored, err := m.settings.ListStored()
+		if err != nil {
+			return nil, err
+		}
+		names = stored
+	}
+
+	var files []settingsFile
+	if len(names) == 0 && !all {
+		files = append(files, settingsFile{file: m.paths.ActiveSettingsPath(), match: m.settings.GetActiveName()})
+	}
+	for _, name := range names {
+		normalized, err := m.normalizeSettingsName(name)
+		if err != nil {
+			return nil, err
+		}
+		file := m.paths.StoredSettingsPath(normalized)
+		if exists, err := m.storage.Exists(file); err != nil {
+			return nil, fmt.Errorf("failed to inspect settings: %w", err)
+		} else if !exists {
+			return nil, fmt.Errorf("settings '%s' not found", normalized)
+		}
+		files = append(files, settingsFile{profile: normalized, file: file, match: normalized})
+	}
+	return files, nil
+}
@@ -0,0 +1,147 @@
+// Package textdiff renders line-based unified diffs of small text files such
+// as settings documents.
+package textdiff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Op is the kind of a diff line.
+type Op byte
+
+const (
+	Equal  Op = ' '
+	Delete Op = '-'
+	Insert Op = '+'
+)
+
+// Line is one line of an edit script.
+type Line struct {
+	Op   Op
+	Text string
+}
+
+// Lines returns the edit script that turns a into b, line by line, using a
+// longest-common-subsequence alignment.
+func Lines(a, b string) []Line {
+	x, y := splitLines(a), splitLines(b)
+	// lcs[i][j] is the LCS length of x[i:] and y[j:]
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	var script []Line
+	i, j := 0, 0
+	for i < len(x) && j < len(y) {
+		switch {
+		case x[i] == y[j]:
+			script = append(script, Line{Equal, x[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			script = append(script, Line{Delete, x[i]})
+			i++
+		default:
+			script = append(script, Line{Insert, y[j]})
+			j++
+		}
+	}
+	for ; i < len(x); i++ {
+		script = append(script, Line{Delete, x[i]})
+	}
+	for ; j < len(y); j++ {
+		script = append(script, Line{Insert, y[j]})
+	}
+	return script
+}
+
+// Unified renders the differences between a and b as a unified diff with the
+// given number of context lines. It returns an empty string when a and b
+// have the same lines.
+func Unified(a, b, nameA, nameB string, context int) string {
+	script := Lines(a, b)
+	var out strings.Builder
+	// Positions (1-based) in a and b of every script line
+	posA := make([]int, len(script))
+	posB := make([]int, len(script))
+	na, nb := 1, 1
+	for k, l := range script {
+		posA[k], posB[k] = na, nb
+		if l.Op != Insert {
+			na++
+		}
+		if l.Op != Delete {
+			nb++
+		}
+	}
+
+	for k := 0; k < len(script); {
+		if script[k].Op == Equal {
+			k++
+			continue
+		}
+		start := max(k-context, 0)
+		end := k
+		// Extend the hunk while changes are within 2*context lines of each other
+		for end < len(script) {
+			if script[end].Op != Equal {
+				end++
+				continue
+			}
+			next := end
+			for next < len(script) && script[next].Op == Equal {
+				next++
+			}
+			if next == len(script) || next-end > 2*context {
+				end = min(end+context, len(script))
+				break
+			}
+			end = next
+		}
+		if out.Len() == 0 {
+			fmt.Fprintf(&out, "--- %s\n+++ %s\n", nameA, nameB)
+		}
+		countA, countB := 0, 0
+		for _, l := range script[start:end] {
+			if l.Op != Insert {
+				countA++
+			}
+			if l.Op != Delete {
+				countB++
+			}
+		}
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(posA[start], countA), hunkRange(posB[start], countB))
+		for _, l := range script[start:end] {
+			fmt.Fprintf(&out, "%c%s\n", l.Op, l.Text)
+		}
+		k = end
+	}
+	return out.String()
+}
+
+func hunkRange(start, count int) string {
+	if count == 0 {
+		start--
+	}
+	if count == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
@@ -0,0 +1,48 @@
+package textdiff
+
+// Tests for line-based unified diffs.
+//
+// Focus: edit scripts, hunk headers, context merging and identical input.
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLines_EditScript(t *testing.T) {
+	script := Lines("a\nb\nc\n", "a\nx\nc\nd\n")
+	var got strings.Builder
+	for _, l := range script {
+		got.WriteString(string(l.Op) + l.Text + ";")
+	}
+	if got.String() != " a;-b;+x; c;+d;" {
+		t.Fatalf("unexpected script %q", got.String())
+	}
+}
+
+func TestUnified_Hunks(t *testing.T) {
+	a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n"
+	b := "1\n2\nthree\n4\n5\n6\n7\n8\n9\n10\n11\n"
+	want := "--- old\n+++ new\n" +
+		"@@ -2,3 +2,3 @@\n 2\n-3\n+three\n 4\n" +
+		"@@ -10 +10,2 @@\n 10\n+11\n"
+	if got := Unified(a, b, "old", "new", 1); got != want {
+		t.Fatalf("unexpected diff:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestUnified_MergesNearbyChanges(t *testing.T) {
+	got := Unified("a\nb\nc\nd\n", "A\nb\nc\nD\n", "old", "new", 3)
+	if strings.Count(got, "@@ ") != 1 || !strings.Contains(got, "@@ -1,4 +1,4 @@") {
+		t.Fatalf("expected one merged hunk:\n%s", got)
+	}
+}
+
+func TestUnified_IdenticalAndEmpty(t *testing.T) {
+	if got := Unified("same\n", "same", "a", "b", 3); got != "" {
+		t.Fatalf("expected no diff, got %q", got)
+	}
+	if got := Unified("", "x\n", "a", "b", 3); !strings.Contains(got, "@@ -0,0 +1 @@\n+x\n") {
+		t.Fatalf("unexpected diff from empty input:\n%s", got)
+	}
+}
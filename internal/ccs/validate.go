@@ -0,0 +1,62 @@
+package ccs
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/OpenGG/claude-code-switch-settings/internal/ccs/config"
+	"github.com/OpenGG/claude-code-switch-settings/internal/ccs/jsondoc"
+	"github.com/OpenGG/claude-code-switch-settings/internal/ccs/schema"
+)
+
+// SchemaIssue is one finding from validating settings against the schema.
+type SchemaIssue = schema.Issue
+
+// ValidationError reports that a settings file has schema errors. Issues
+// holds every finding, warnings included.
+type ValidationError struct {
+	File   string
+	Issues []SchemaIssue
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s does not match the settings schema:", e.File)
+	for _, issue := range e.Issues {
+		fmt.Fprintf(&b, "\n  %s: %s", issue.Severity, issue)
+	}
+	return b.String()
+}
+
+// loadSchema returns the schema configured by schema.file, or the embedded
+// Claude Code settings schema when none is configured.
+func (m *Manager) loadSchema(s *config.Schema) (*schema.Schema, error) {
+	if s == nil || s.File == "" {
+		return schema.Default(), nil
+	}
+	path := m.paths.ExpandHome(s.File)
+	data, err := m.storage.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema file: %w", err)
+	}
+	return schema.Parse(data)
+}
+
+// validateContent checks content about to be written for file. It returns a
+// *ValidationError when the content is not valid JSON or the schema reports
+// errors, and the warnings otherwise.
+func (m *Manager) validateContent(file string, content []byte) ([]SchemaIssue, error) {
+	doc, err := jsondoc.Parse(content)
+	if err != nil {
+		message := err.Error()
+		if file == m.paths.ActiveSettingsPath() {
+			message += "; run 'ccs recover' to restore the last valid backup"
+		}
+		return nil, &ValidationError{File: file, Issues: []SchemaIssue{{Severity: schema.Error, Message: message}}}
+	}
+	issues := m.schema.Validate(doc)
+	if schema.HasErrors(issues) {
+		return nil, &ValidationError{File: file, Issues: issues}
+	}
+	return issues, nil
+}
@@ -0,0 +1,111 @@
+package ccs
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+func TestUseValidatesProfileAgainstSchema(t *testing.T) {
+	mgr := newTestManager(t)
+	fs := mgr.FileSystem()
+	profiles := map[string]string{
+		"typo":   `{"permisions": {"allow": ["Read"]}}`,
+		"broken": `{"permissions": {"allow": "Read"}}`,
+	}
+	for name, content := range profiles {
+		if err := afero.WriteFile(fs, filepath.Join(mgr.SettingsStoreDir(), name+".json"), []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	if err := afero.WriteFile(fs, mgr.ActiveSettingsPath(), []byte(`{"model": "opus"}`), 0o644); err != nil {
+		t.Fatalf("write active: %v", err)
+	}
+
+	result, err := mgr.UseWithOptions("typo", UseOptions{})
+	if err != nil {
+		t.Fatalf("use typo: %v", err)
+	}
+	if len(result.Warnings) != 1 || result.Warnings[0].Path != "permisions" {
+		t.Fatalf("expected unknown key warning, got %v", result.Warnings)
+	}
+
+	_, err = mgr.UseWithOptions("broken", UseOptions{})
+	var verr *ValidationError
+	if !errors.As(err, &verr) || len(verr.Issues) != 1 || verr.Issues[0].Path != "permissions.allow" {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+	if mgr.GetActiveSettingsName() != "typo" {
+		t.Fatalf("a rejected profile must not be activated")
+	}
+	if _, err := mgr.UseWithOptions("broken", UseOptions{NoValidate: true}); err != nil {
+		t.Fatalf("use --no-validate: %v", err)
+	}
+}
+
+func TestSaveValidatesSettingsAgainstSchema(t *testing.T) {
+	mgr := newTestManager(t)
+	if err := afero.WriteFile(mgr.FileSystem(), mgr.ActiveSettingsPath(), []byte(`{"env": {"N": 1}}`), 0o644); err != nil {
+		t.Fatalf("write active: %v", err)
+	}
+	var verr *ValidationError
+	if _, err := mgr.SaveWithOptions("work", SaveOptions{}); !errors.As(err, &verr) {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+	if exists, _ := afero.Exists(mgr.FileSystem(), filepath.Join(mgr.SettingsStoreDir(), "work.json")); exists {
+		t.Fatal("rejected settings must not be stored")
+	}
+	if _, err := mgr.SaveWithOptions("work", SaveOptions{NoValidate: true}); err != nil {
+		t.Fatalf("save --no-validate: %v", err)
+	}
+}
+
+func TestSaveRefusesSettingsThatAreNotJSON(t *testing.T) {
+	mgr := newTestManager(t)
+	if err := afero.WriteFile(mgr.FileSystem(), mgr.ActiveSettingsPath(), []byte(`{"model": `), 0o644); err != nil {
+		t.Fatalf("write active: %v", err)
+	}
+	_, err := mgr.SaveWithOptions("work", SaveOptions{})
+	var verr *ValidationError
+	if !errors.As(err, &verr) || !strings.Contains(err.Error(), "ccs recover") {
+		t.Fatalf("expected a validation error pointing at ccs recover, got %v", err)
+	}
+	if exists, _ := afero.Exists(mgr.FileSystem(), filepath.Join(mgr.SettingsStoreDir(), "work.json")); exists {
+		t.Fatal("settings that are not JSON must not be stored")
+	}
+	if _, err := mgr.SaveWithOptions("work", SaveOptions{NoValidate: true}); err != nil {
+		t.Fatalf("save --no-validate: %v", err)
+	}
+}
+
+func TestLoadConfigUsesSchemaFile(t *testing.T) {
+	mgr := newTestManager(t)
+	fs := mgr.FileSystem()
+	if err := afero.WriteFile(fs, "/home/test/schemas/settings.json", []byte(`{"type": "object", "properties": {"model": {"enum": ["opus"]}}}`), 0o644); err != nil {
+		t.Fatalf("write schema: %v", err)
+	}
+	if err := afero.WriteFile(fs, mgr.paths.ConfigPath(), []byte(`{"schema": {"file": "~/schemas/settings.json"}}`), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if err := mgr.LoadConfig(); err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	if err := afero.WriteFile(fs, mgr.ActiveSettingsPath(), []byte(`{"model": "sonnet", "custom": true}`), 0o644); err != nil {
+		t.Fatalf("write active: %v", err)
+	}
+	_, err := mgr.SaveWithOptions("work", SaveOptions{})
+	var verr *ValidationError
+	if !errors.As(err, &verr) || len(verr.Issues) != 1 || verr.Issues[0].Path != "model" {
+		t.Fatalf("expected the custom schema to apply, got %v", err)
+	}
+
+	if err := afero.WriteFile(fs, mgr.paths.ConfigPath(), []byte(`{"schema": {"file": "~/missing.json"}}`), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if err := mgr.LoadConfig(); err == nil {
+		t.Fatal("expected error for missing schema file")
+	}
+}
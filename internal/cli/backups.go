@@ -0,0 +1,347 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenGG/claude-code-switch-settings/internal/ccs"
+)
+
+func newBackupsCommand(mgr *ccs.Manager, prompter Prompter, stdout io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "backups",
+		Short: "Inspect and maintain the backup store",
+	}
+
+	cmd.AddCommand(newBackupsListCommand(mgr, stdout))
+	cmd.AddCommand(newBackupsPinCommand(mgr, stdout, true))
+	cmd.AddCommand(newBackupsPinCommand(mgr, stdout, false))
+	cmd.AddCommand(newBackupsNoteCommand(mgr, stdout))
+	cmd.AddCommand(newBackupsPromoteCommand(mgr, prompter, stdout))
+	cmd.AddCommand(newBackupsExportCommand(mgr, stdout))
+	cmd.AddCommand(newBackupsImportCommand(mgr, stdout))
+	cmd.AddCommand(newBackupsSyncMirrorCommand(mgr, stdout))
+	cmd.AddCommand(newBackupsCompressCommand(mgr, stdout))
+	cmd.AddCommand(newBackupsRekeyCommand(mgr, stdout))
+	cmd.AddCommand(newBackupsMigrateLayoutCommand(mgr, stdout))
+
+	return cmd
+}
+
+func newBackupsListCommand(mgr *ccs.Manager, stdout io.Writer) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List operation snapshots, newest first",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			snaps, err := mgr.Snapshots()
+			if err != nil {
+				return err
+			}
+			annotations, err := mgr.BackupAnnotations()
+			if err != nil {
+				return err
+			}
+			if len(snaps) == 0 {
+				fmt.Fprintln(stdout, "No snapshots found.")
+			}
+			for _, snap := range snaps {
+				fmt.Fprintln(stdout, snapshotLabel(snap, nil))
+				for _, f := range snap.Files {
+					fmt.Fprintf(stdout, "    %s  %s%s\n", f.Path, ccs.ShortHash(f.Hash), annotationSuffix(annotations[f.Hash]))
+				}
+			}
+			if len(annotations) > 0 {
+				hashes := make([]string, 0, len(annotations))
+				for hash := range annotations {
+					hashes = append(hashes, hash)
+				}
+				sort.Strings(hashes)
+				fmt.Fprintln(stdout, "\nPinned and annotated backups:")
+				for _, hash := range hashes {
+					fmt.Fprintf(stdout, "    %s%s\n", ccs.ShortHash(hash), annotationSuffix(annotations[hash]))
+				}
+			}
+			return nil
+		},
+	}
+}
+
+func newBackupsPinCommand(mgr *ccs.Manager, stdout io.Writer, pin bool) *cobra.Command {
+	use, short, done := "pin <hash>", "Protect a backup from pruning", "Pinned"
+	if !pin {
+		use, short, done = "unpin <hash>", "Allow a pinned backup to be pruned again", "Unpinned"
+	}
+	return &cobra.Command{
+		Use:   use,
+		Short: short,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var hash string
+			var err error
+			if pin {
+				hash, err = mgr.PinBackup(args[0])
+			} else {
+				hash, err = mgr.UnpinBackup(args[0])
+			}
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(stdout, "%s backup %s.\n", done, ccs.ShortHash(hash))
+			return nil
+		},
+	}
+}
+
+func newBackupsNoteCommand(mgr *ccs.Manager, stdout io.Writer) *cobra.Command {
+	return &cobra.Command{
+		Use:   "note <hash> <text>",
+		Short: "Attach a note to a backup (an empty text removes it)",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			hash, err := mgr.NoteBackup(args[0], args[1])
+			if err != nil {
+				return err
+			}
+			if strings.TrimSpace(args[1]) == "" {
+				fmt.Fprintf(stdout, "Removed note from backup %s.\n", ccs.ShortHash(hash))
+				return nil
+			}
+			fmt.Fprintf(stdout, "Noted backup %s.\n", ccs.ShortHash(hash))
+			return nil
+		},
+	}
+}
+
+func newBackupsPromoteCommand(mgr *ccs.Manager, prompter Prompter, stdout io.Writer) *cobra.Command {
+	var force, activate, noValidate bool
+
+	cmd := &cobra.Command{
+		Use:   "promote <hash> <name>",
+		Short: "Store a backup as a named settings profile",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[1]
+			opts := ccs.PromoteOptions{Force: force, NoValidate: noValidate}
+			result, err := mgr.PromoteBackupWithOptions(args[0], name, opts)
+			if errors.Is(err, ccs.ErrSettingsExist) {
+				confirm, perr := prompter.Confirm(fmt.Sprintf("Overwrite %s? (y/N)", name), false)
+				if perr != nil {
+					return perr
+				}
+				if !confirm {
+					fmt.Fprintln(stdout, "Aborted promoting backup.")
+					return nil
+				}
+				opts.Force = true
+				result, err = mgr.PromoteBackupWithOptions(args[0], name, opts)
+			}
+			if err != nil {
+				return withValidationHint(err)
+			}
+			printSchemaWarnings(cmd.ErrOrStderr(), result.Warnings)
+			fmt.Fprintf(stdout, "Promoted backup %s to settings: %s\n", ccs.ShortHash(result.Hash), name)
+			if activate {
+				used, err := mgr.UseWithOptions(name, ccs.UseOptions{NoValidate: noValidate})
+				if err != nil {
+					return withValidationHint(err)
+				}
+				printSchemaWarnings(cmd.ErrOrStderr(), used.Warnings)
+				fmt.Fprintf(stdout, "Successfully switched to settings: %s\n", name)
+			} else if result.Active {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Warning: %s is the active profile; settings.json still holds the previous settings. Run 'ccs use %s' to apply them.\n", name, name)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&force, "force", false, "Overwrite an existing profile without prompting")
+	cmd.Flags().BoolVar(&activate, "use", false, "Activate the profile after promoting")
+	cmd.Flags().BoolVar(&noValidate, "no-validate", false, "Skip checking the backup against the settings schema")
+
+	return cmd
+}
+
+func newBackupsExportCommand(mgr *ccs.Manager, stdout io.Writer) *cobra.Command {
+	var opts ccs.TransferOptions
+
+	cmd := &cobra.Command{
+		Use:   "export <file.tar.gz>",
+		Short: "Export all backups and snapshots to an archive",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			result, err := mgr.ExportBackupsWithOptions(args[0], opts)
+			if err != nil {
+				return err
+			}
+			printSecretWarnings(cmd.ErrOrStderr(), result.Secrets)
+			fmt.Fprintf(stdout, "Exported %d backup(s) to %s.\n", result.Exported, args[0])
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.Strict, "strict", false, "Refuse to export backups containing values that look like secrets")
+
+	return cmd
+}
+
+func newBackupsImportCommand(mgr *ccs.Manager, stdout io.Writer) *cobra.Command {
+	var opts ccs.TransferOptions
+
+	cmd := &cobra.Command{
+		Use:   "import <file.tar.gz>",
+		Short: "Merge backups and snapshots from an exported archive",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			result, err := mgr.ImportBackupsWithOptions(args[0], opts)
+			if err != nil {
+				return withValidationHint(err)
+			}
+			printSchemaWarnings(cmd.ErrOrStderr(), result.Warnings)
+			printSecretWarnings(cmd.ErrOrStderr(), result.Secrets)
+			fmt.Fprintf(stdout, "Imported %d backup(s), skipped %d already present, added %d snapshot(s).\n",
+				result.Imported, result.Skipped, result.Snapshots)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.Strict, "strict", false, "Refuse to import backups containing values that look like secrets")
+	cmd.Flags().BoolVar(&opts.NoValidate, "no-validate", false, "Skip checking the archived backups against the settings schema")
+
+	return cmd
+}
+
+func newBackupsSyncMirrorCommand(mgr *ccs.Manager, stdout io.Writer) *cobra.Command {
+	return &cobra.Command{
+		Use:   "sync-mirror",
+		Short: "Reconcile the backup directory with the configured mirror",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			result, err := mgr.SyncMirror()
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(stdout, "Copied %d backup(s) to the mirror and %d back from it.\n", result.ToMirror, result.FromMirror)
+			if result.Removed > 0 {
+				fmt.Fprintf(stdout, "Removed %d deleted backup(s) from the mirror.\n", result.Removed)
+			}
+			return nil
+		},
+	}
+}
+
+func newBackupsCompressCommand(mgr *ccs.Manager, stdout io.Writer) *cobra.Command {
+	var algorithm string
+
+	cmd := &cobra.Command{
+		Use:   "compress",
+		Short: "Rewrite existing backups using compression",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			count, err := mgr.CompressBackups(algorithm)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(stdout, "Rewrote %d backup(s).\n", count)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&algorithm, "algorithm", "", "Target encoding: gzip or none (default: configured compression, else gzip)")
+
+	return cmd
+}
+
+func newBackupsMigrateLayoutCommand(mgr *ccs.Manager, stdout io.Writer) *cobra.Command {
+	var layout string
+
+	cmd := &cobra.Command{
+		Use:   "migrate-layout",
+		Short: "Move existing backups into the flat or sharded directory layout",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			count, err := mgr.MigrateBackupLayout(layout)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(stdout, "Moved %d backup(s).\n", count)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&layout, "layout", "", "Target layout: sharded or flat (default: configured layout, else sharded)")
+
+	return cmd
+}
+
+func newBackupsRekeyCommand(mgr *ccs.Manager, stdout io.Writer) *cobra.Command {
+	var opts ccs.RekeyOptions
+
+	cmd := &cobra.Command{
+		Use:   "rekey",
+		Short: "Re-encrypt all backups with a new key",
+		Long: "Re-encrypt all backups with a new key file or passphrase, or decrypt them.\n" +
+			"Backups are read with the currently configured key. The configuration file is\n" +
+			"not changed; update backup.encryption to the new key afterwards.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			result, err := mgr.RekeyBackups(opts)
+			if result.GeneratedKeyFile != "" {
+				fmt.Fprintf(stdout, "Generated new key file: %s\n", result.GeneratedKeyFile)
+			}
+			if err != nil {
+				return err
+			}
+			if opts.Decrypt {
+				fmt.Fprintf(stdout, "Decrypted %d backup(s). Remove backup.encryption from %s.\n", result.Rewritten, mgr.ConfigPath())
+				return nil
+			}
+			fmt.Fprintf(stdout, "Re-encrypted %d backup(s). Point backup.encryption in %s at the new key.\n", result.Rewritten, mgr.ConfigPath())
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.NewKeyFile, "new-key-file", "", "Key file to encrypt with (generated if missing; must be outside ~/.claude)")
+	cmd.Flags().StringVar(&opts.NewPassphraseEnv, "new-passphrase-env", "", "Environment variable holding the new passphrase")
+	cmd.Flags().BoolVar(&opts.Decrypt, "decrypt", false, "Rewrite all backups unencrypted")
+
+	return cmd
+}
+
+// snapshotLabel renders a snapshot as a single line for listings and pickers.
+// When annotations are given, pins and notes of the snapshot's files are
+// appended so known-good states stand out.
+func snapshotLabel(snap ccs.Snapshot, annotations map[string]ccs.BlobMeta) string {
+	label := fmt.Sprintf("%s  %s  %s", snap.ID, snap.Time.Local().Format("2006-01-02 15:04:05"), snap.Operation)
+	var merged ccs.BlobMeta
+	var notes []string
+	for _, f := range snap.Files {
+		meta, ok := annotations[f.Hash]
+		if !ok {
+			continue
+		}
+		merged.Pinned = merged.Pinned || meta.Pinned
+		if meta.Note != "" {
+			notes = append(notes, meta.Note)
+		}
+	}
+	merged.Note = strings.Join(notes, "; ")
+	return label + annotationSuffix(merged)
+}
+
+// annotationSuffix renders a backup's pin and note for display.
+func annotationSuffix(meta ccs.BlobMeta) string {
+	suffix := ""
+	if meta.Pinned {
+		suffix += "  [pinned]"
+	}
+	if meta.Note != "" {
+		suffix += fmt.Sprintf("  %q", meta.Note)
+	}
+	return suffix
+}
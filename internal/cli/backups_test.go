@@ -0,0 +1,302 @@
+package cli
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+func TestBackupsCompressCommand(t *testing.T) {
+	mgr := newTestCommandManager(t)
+	blob := filepath.Join(mgr.BackupDir(), "44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a.json")
+	if err := afero.WriteFile(mgr.FileSystem(), blob, []byte("{}"), 0o600); err != nil {
+		t.Fatalf("write backup: %v", err)
+	}
+
+	buf := &bytes.Buffer{}
+	cmd := newBackupsCompressCommand(mgr, buf)
+	if err := cmd.RunE(cmd, nil); err != nil {
+		t.Fatalf("RunE compress: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Rewrote 1 backup(s).") {
+		t.Fatalf("unexpected output: %s", buf.String())
+	}
+}
+
+func TestBackupsRekeyCommandGeneratesKeyFile(t *testing.T) {
+	mgr := newTestCommandManager(t)
+
+	buf := &bytes.Buffer{}
+	cmd := newBackupsRekeyCommand(mgr, buf)
+	if err := cmd.Flags().Set("new-key-file", "/keys/ccs.key"); err != nil {
+		t.Fatalf("set flag: %v", err)
+	}
+	if err := cmd.RunE(cmd, nil); err != nil {
+		t.Fatalf("RunE rekey: %v", err)
+	}
+	output := buf.String()
+	if !strings.Contains(output, "Generated new key file: /keys/ccs.key") || !strings.Contains(output, "Re-encrypted 0 backup(s).") {
+		t.Fatalf("unexpected output: %s", output)
+	}
+	if exists, _ := afero.Exists(mgr.FileSystem(), "/keys/ccs.key"); !exists {
+		t.Fatalf("expected key file to be generated")
+	}
+}
+
+func TestBackupsListAndRestoreCommands(t *testing.T) {
+	mgr := newTestCommandManager(t)
+	if err := afero.WriteFile(mgr.FileSystem(), mgr.ActiveSettingsPath(), []byte(`{"a":1}`), 0o644); err != nil {
+		t.Fatalf("write active: %v", err)
+	}
+	if err := afero.WriteFile(mgr.FileSystem(), filepath.Join(mgr.SettingsStoreDir(), "work.json"), []byte(`{"b":2}`), 0o644); err != nil {
+		t.Fatalf("write stored: %v", err)
+	}
+	if err := mgr.Use("work"); err != nil {
+		t.Fatalf("use: %v", err)
+	}
+
+	buf := &bytes.Buffer{}
+	list := newBackupsListCommand(mgr, buf)
+	if err := list.RunE(list, nil); err != nil {
+		t.Fatalf("RunE list: %v", err)
+	}
+	output := buf.String()
+	if !strings.Contains(output, "use work") || !strings.Contains(output, "settings.json.active  (absent)") {
+		t.Fatalf("unexpected list output: %s", output)
+	}
+
+	buf.Reset()
+	prompter := &stubPrompter{
+		selects:  []selectResponse{{index: 0}},
+		confirms: []confirmResponse{{value: true}},
+	}
+	restore := newRestoreCommand(mgr, prompter, buf)
+	if err := restore.RunE(restore, nil); err != nil {
+		t.Fatalf("RunE restore: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Restored snapshot") {
+		t.Fatalf("unexpected restore output: %s", buf.String())
+	}
+	content, err := afero.ReadFile(mgr.FileSystem(), mgr.ActiveSettingsPath())
+	if err != nil {
+		t.Fatalf("read active: %v", err)
+	}
+	if string(content) != `{"a":1}` {
+		t.Fatalf("expected original settings restored, got %s", content)
+	}
+}
+
+func TestRestoreCommandCancelled(t *testing.T) {
+	mgr := newTestCommandManager(t)
+	buf := &bytes.Buffer{}
+	prompter := &stubPrompter{confirms: []confirmResponse{{value: false}}}
+	cmd := newRestoreCommand(mgr, prompter, buf)
+	if err := cmd.RunE(cmd, []string{"20240101"}); err != nil {
+		t.Fatalf("RunE restore: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Restore cancelled.") {
+		t.Fatalf("unexpected output: %s", buf.String())
+	}
+}
+
+func TestBackupsPinAndNoteCommands(t *testing.T) {
+	mgr := newTestCommandManager(t)
+	blob := "44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a"
+	if err := afero.WriteFile(mgr.FileSystem(), filepath.Join(mgr.BackupDir(), blob+".json"), []byte("{}"), 0o600); err != nil {
+		t.Fatalf("write backup: %v", err)
+	}
+
+	buf := &bytes.Buffer{}
+	pin := newBackupsPinCommand(mgr, buf, true)
+	if err := pin.RunE(pin, []string{"44136f"}); err != nil {
+		t.Fatalf("RunE pin: %v", err)
+	}
+	note := newBackupsNoteCommand(mgr, buf)
+	if err := note.RunE(note, []string{"44136f", "known-good"}); err != nil {
+		t.Fatalf("RunE note: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Pinned backup 44136fa355b3.") || !strings.Contains(buf.String(), "Noted backup 44136fa355b3.") {
+		t.Fatalf("unexpected output: %s", buf.String())
+	}
+
+	buf.Reset()
+	list := newBackupsListCommand(mgr, buf)
+	if err := list.RunE(list, nil); err != nil {
+		t.Fatalf("RunE list: %v", err)
+	}
+	if !strings.Contains(buf.String(), `44136fa355b3  [pinned]  "known-good"`) {
+		t.Fatalf("expected annotations in listing, got: %s", buf.String())
+	}
+
+	buf.Reset()
+	unpin := newBackupsPinCommand(mgr, buf, false)
+	if err := unpin.RunE(unpin, []string{blob}); err != nil {
+		t.Fatalf("RunE unpin: %v", err)
+	}
+	annotations, err := mgr.BackupAnnotations()
+	if err != nil {
+		t.Fatalf("annotations: %v", err)
+	}
+	if annotations[blob].Pinned || annotations[blob].Note != "known-good" {
+		t.Fatalf("unexpected annotation after unpin: %+v", annotations[blob])
+	}
+}
+
+func TestBackupsPromoteCommand(t *testing.T) {
+	mgr := newTestCommandManager(t)
+	blob := "44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a"
+	if err := afero.WriteFile(mgr.FileSystem(), filepath.Join(mgr.BackupDir(), blob+".json"), []byte("{}"), 0o600); err != nil {
+		t.Fatalf("write backup: %v", err)
+	}
+	existing := filepath.Join(mgr.SettingsStoreDir(), "work.json")
+	if err := afero.WriteFile(mgr.FileSystem(), existing, []byte(`{"old":true}`), 0o644); err != nil {
+		t.Fatalf("write profile: %v", err)
+	}
+
+	// Declining the overwrite leaves the profile alone
+	buf := &bytes.Buffer{}
+	cmd := newBackupsPromoteCommand(mgr, &stubPrompter{confirms: []confirmResponse{{value: false}}}, buf)
+	if err := cmd.RunE(cmd, []string{"44136f", "work"}); err != nil {
+		t.Fatalf("RunE promote: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Aborted promoting backup.") {
+		t.Fatalf("unexpected output: %s", buf.String())
+	}
+
+	buf.Reset()
+	cmd = newBackupsPromoteCommand(mgr, &stubPrompter{confirms: []confirmResponse{{value: true}}}, buf)
+	if err := cmd.Flags().Set("use", "true"); err != nil {
+		t.Fatalf("set flag: %v", err)
+	}
+	if err := cmd.RunE(cmd, []string{"44136f", "work"}); err != nil {
+		t.Fatalf("RunE promote: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Promoted backup 44136fa355b3 to settings: work") {
+		t.Fatalf("unexpected output: %s", buf.String())
+	}
+	content, err := afero.ReadFile(mgr.FileSystem(), mgr.ActiveSettingsPath())
+	if err != nil {
+		t.Fatalf("read active: %v", err)
+	}
+	if string(content) != "{}" || mgr.GetActiveSettingsName() != "work" {
+		t.Fatalf("expected promoted profile to be active, got %s (%s)", content, mgr.GetActiveSettingsName())
+	}
+}
+
+func TestBackupsPromoteCommandWarnsForActiveProfile(t *testing.T) {
+	mgr := newTestCommandManager(t)
+	blob := "44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a"
+	if err := afero.WriteFile(mgr.FileSystem(), filepath.Join(mgr.BackupDir(), blob+".json"), []byte("{}"), 0o600); err != nil {
+		t.Fatalf("write backup: %v", err)
+	}
+	if err := afero.WriteFile(mgr.FileSystem(), mgr.ActiveSettingsPath(), []byte(`{"old":true}`), 0o644); err != nil {
+		t.Fatalf("write active: %v", err)
+	}
+	if err := mgr.Save("work"); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	buf, errBuf := &bytes.Buffer{}, &bytes.Buffer{}
+	cmd := newBackupsPromoteCommand(mgr, &stubPrompter{}, buf)
+	cmd.SetErr(errBuf)
+	if err := cmd.Flags().Set("force", "true"); err != nil {
+		t.Fatalf("set flag: %v", err)
+	}
+	if err := cmd.RunE(cmd, []string{"44136f", "work"}); err != nil {
+		t.Fatalf("RunE promote: %v", err)
+	}
+	if !strings.Contains(errBuf.String(), "Warning: work is the active profile") {
+		t.Fatalf("expected an active profile warning, got %q", errBuf.String())
+	}
+}
+
+func TestBackupsExportAndImportCommands(t *testing.T) {
+	mgr := newTestCommandManager(t)
+	blob := "44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a"
+	if err := afero.WriteFile(mgr.FileSystem(), filepath.Join(mgr.BackupDir(), blob+".json"), []byte("{}"), 0o600); err != nil {
+		t.Fatalf("write backup: %v", err)
+	}
+
+	buf := &bytes.Buffer{}
+	export := newBackupsExportCommand(mgr, buf)
+	if err := export.RunE(export, []string{"/tmp/backups.tar.gz"}); err != nil {
+		t.Fatalf("RunE export: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Exported 1 backup(s) to /tmp/backups.tar.gz.") {
+		t.Fatalf("unexpected output: %s", buf.String())
+	}
+
+	buf.Reset()
+	imp := newBackupsImportCommand(mgr, buf)
+	if err := imp.RunE(imp, []string{"/tmp/backups.tar.gz"}); err != nil {
+		t.Fatalf("RunE import: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Imported 0 backup(s), skipped 1 already present, added 0 snapshot(s).") {
+		t.Fatalf("unexpected output: %s", buf.String())
+	}
+}
+
+func TestBackupsMigrateLayoutCommand(t *testing.T) {
+	mgr := newTestCommandManager(t)
+	blob := "44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a"
+	if err := afero.WriteFile(mgr.FileSystem(), filepath.Join(mgr.BackupDir(), blob+".json"), []byte("{}"), 0o600); err != nil {
+		t.Fatalf("write backup: %v", err)
+	}
+
+	buf := &bytes.Buffer{}
+	cmd := newBackupsMigrateLayoutCommand(mgr, buf)
+	if err := cmd.RunE(cmd, nil); err != nil {
+		t.Fatalf("RunE migrate-layout: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Moved 1 backup(s).") {
+		t.Fatalf("unexpected output: %s", buf.String())
+	}
+	if exists, _ := afero.Exists(mgr.FileSystem(), filepath.Join(mgr.BackupDir(), "44", blob[2:]+".json")); !exists {
+		t.Fatalf("expected backup in shard directory")
+	}
+}
+
+func TestRestoreKeysCommandPreviewsAndApplies(t *testing.T) {
+	mgr := newTestCommandManager(t)
+	if err := afero.WriteFile(mgr.FileSystem(), mgr.ActiveSettingsPath(), []byte(`{"model":"opus","env":{"A":"1"}}`), 0o644); err != nil {
+		t.Fatalf("write active: %v", err)
+	}
+	if err := afero.WriteFile(mgr.FileSystem(), filepath.Join(mgr.SettingsStoreDir(), "work.json"), []byte(`{"model":"sonnet","env":{"A":"2"}}`), 0o644); err != nil {
+		t.Fatalf("write stored: %v", err)
+	}
+	if err := mgr.Use("work"); err != nil {
+		t.Fatalf("use: %v", err)
+	}
+	snaps, err := mgr.Snapshots()
+	if err != nil {
+		t.Fatalf("snapshots: %v", err)
+	}
+	hash := snaps[0].Files[0].Hash
+
+	buf := &bytes.Buffer{}
+	prompter := &stubPrompter{confirms: []confirmResponse{{value: true}}}
+	cmd := newRestoreCommand(mgr, prompter, buf)
+	if err := cmd.Flags().Set("keys", "env.A"); err != nil {
+		t.Fatalf("set flag: %v", err)
+	}
+	if err := cmd.RunE(cmd, []string{hash[:12]}); err != nil {
+		t.Fatalf("RunE restore keys: %v", err)
+	}
+	output := buf.String()
+	for _, want := range []string{"  env.A", `  - "2"`, `  + "1"`, "Restored 1 key(s)"} {
+		if !strings.Contains(output, want) {
+			t.Fatalf("expected %q in output:\n%s", want, output)
+		}
+	}
+	content, _ := afero.ReadFile(mgr.FileSystem(), mgr.ActiveSettingsPath())
+	if !strings.Contains(string(content), `"model": "sonnet"`) || !strings.Contains(string(content), `"A": "1"`) {
+		t.Fatalf("unexpected merged settings: %s", content)
+	}
+
+	if err := cmd.RunE(cmd, nil); err == nil {
+		t.Fatal("expected error when --keys has no backup hash")
+	}
+}
@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenGG/claude-code-switch-settings/internal/ccs"
+	"github.com/OpenGG/claude-code-switch-settings/internal/ccs/jsondoc"
+)
+
+func newBlameCommand(mgr *ccs.Manager, stdout io.Writer) *cobra.Command {
+	var profile string
+
+	cmd := &cobra.Command{
+		Use:   "blame <json.path>",
+		Short: "Show when the current value of a settings key appeared",
+		Long: "Walk the backup history of settings.json (or a stored profile) and report\n" +
+			"the backup where the current value of a key was first seen, and the value\n" +
+			"it replaced. Paths are dotted (permissions.allow[0]) or JSON pointers\n" +
+			"(/permissions/allow/0).",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			result, err := mgr.Blame(args[0], profile)
+			if err != nil {
+				return err
+			}
+			annotations, err := mgr.BackupAnnotations()
+			if err != nil {
+				return err
+			}
+
+			if result.Present {
+				fmt.Fprintf(stdout, "%s = %s\n", result.Key, renderValue(result.Value))
+			} else {
+				fmt.Fprintf(stdout, "%s is not set\n", result.Key)
+			}
+			switch {
+			case result.Versions == 0:
+				fmt.Fprintf(stdout, "No backup history for %s.\n", result.File)
+				return nil
+			case result.Since == nil && result.ChangedAfter == nil:
+				fmt.Fprintln(stdout, "No readable backups to compare against.")
+			case result.Since == nil:
+				fmt.Fprintln(stdout, "First seen: current file (changed after the latest backup)")
+			case result.ChangedAfter == nil:
+				fmt.Fprintf(stdout, "Unchanged since the oldest backup: %s\n", snapshotLabel(*result.Since, annotations))
+			default:
+				fmt.Fprintf(stdout, "First seen: %s\n", snapshotLabel(*result.Since, annotations))
+			}
+			if result.ChangedAfter != nil {
+				previous := "(absent)"
+				if result.PreviousPresent {
+					previous = renderValue(result.Previous)
+				}
+				fmt.Fprintf(stdout, "Replaced:   %s\n", previous)
+				fmt.Fprintf(stdout, "Changed by: %s (or an edit after it)\n", snapshotLabel(*result.ChangedAfter, annotations))
+			}
+			if result.Skipped > 0 {
+				fmt.Fprintf(stdout, "Skipped %d unreadable backup(s).\n", result.Skipped)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&profile, "profile", "", "Blame a stored profile instead of settings.json")
+
+	return cmd
+}
+
+// renderValue formats a settings value as compact JSON.
+func renderValue(v any) string {
+	data, err := jsondoc.Marshal(v, "")
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+func TestBlameCommandReportsPreviousValue(t *testing.T) {
+	mgr := newTestCommandManager(t)
+	if err := afero.WriteFile(mgr.FileSystem(), mgr.ActiveSettingsPath(), []byte(`{"model":"haiku"}`), 0o644); err != nil {
+		t.Fatalf("write active: %v", err)
+	}
+	if err := afero.WriteFile(mgr.FileSystem(), filepath.Join(mgr.SettingsStoreDir(), "work.json"), []byte(`{"model":"opus"}`), 0o644); err != nil {
+		t.Fatalf("write stored: %v", err)
+	}
+	if err := mgr.Use("work"); err != nil {
+		t.Fatalf("use: %v", err)
+	}
+
+	buf := &bytes.Buffer{}
+	cmd := newBlameCommand(mgr, buf)
+	if err := cmd.RunE(cmd, []string{"model"}); err != nil {
+		t.Fatalf("RunE blame: %v", err)
+	}
+	output := buf.String()
+	for _, want := range []string{`model = "opus"`, "First seen: current file", `Replaced:   "haiku"`, "use work"} {
+		if !strings.Contains(output, want) {
+			t.Fatalf("expected %q in output:\n%s", want, output)
+		}
+	}
+
+	buf.Reset()
+	if err := cmd.Flags().Set("profile", "work"); err != nil {
+		t.Fatalf("set flag: %v", err)
+	}
+	if err := cmd.RunE(cmd, []string{"/missing"}); err != nil {
+		t.Fatalf("RunE blame profile: %v", err)
+	}
+	if !strings.Contains(buf.String(), "missing is not set") || !strings.Contains(buf.String(), "No backup history") {
+		t.Fatalf("unexpected profile output:\n%s", buf.String())
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"strconv"
 	"strings"
 	"time"
 
@@ -12,6 +11,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/OpenGG/claude-code-switch-settings/internal/ccs"
+	"github.com/OpenGG/claude-code-switch-settings/internal/ccs/config"
 )
 
 // NewRootCommand constructs the root Cobra command for ccs.
@@ -20,6 +20,11 @@ func NewRootCommand(mgr *ccs.Manager, prompter Prompter, stdout, stderr io.Write
 		Use:   "ccs",
 		Short: "Claude Code Switcher",
 		Long:  "ccs helps manage multiple Claude Code settings safely.",
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			if cmd.Name() != "recover" {
+				warnIfSettingsCorrupt(mgr, stderr)
+			}
+		},
 	}
 
 	cmd.SetOut(stdout)
@@ -29,6 +34,18 @@ func NewRootCommand(mgr *ccs.Manager, prompter Prompter, stdout, stderr io.Write
 	cmd.AddCommand(newUseCommand(mgr, prompter, stdout))
 	cmd.AddCommand(newSaveCommand(mgr, prompter))
 	cmd.AddCommand(newPruneCommand(mgr, prompter, stdout))
+	cmd.AddCommand(newRestoreCommand(mgr, prompter, stdout))
+	cmd.AddCommand(newBackupsCommand(mgr, prompter, stdout))
+	cmd.AddCommand(newBlameCommand(mgr, stdout))
+	cmd.AddCommand(newRecoverCommand(mgr, prompter, stdout))
+	cmd.AddCommand(newLintCommand(mgr, stdout))
+	cmd.AddCommand(newGetCommand(mgr, stdout))
+	cmd.AddCommand(newSetCommand(mgr, stdout))
+	cmd.AddCommand(newUnsetCommand(mgr, stdout))
+	cmd.AddCommand(newFmtCommand(mgr, stdout))
+	cmd.AddCommand(newMatrixCommand(mgr, stdout))
+	cmd.AddCommand(newGrepCommand(mgr, stdout))
+	cmd.AddCommand(newPatchCommand(mgr, stdout))
 
 	return cmd
 }
@@ -62,6 +79,8 @@ func newListCommand(mgr *ccs.Manager, stdout io.Writer) *cobra.Command {
 }
 
 func newUseCommand(mgr *ccs.Manager, prompter Prompter, stdout io.Writer) *cobra.Command {
+	var noSticky, noValidate bool
+
 	cmd := &cobra.Command{
 		Use:   "use [name]",
 		Short: "Load and activate a stored settings profile",
@@ -89,20 +108,31 @@ func newUseCommand(mgr *ccs.Manager, prompter Prompter, stdout io.Writer) *cobra
 				}
 				name = selected
 			}
-			if err := mgr.Use(name); err != nil {
-				return err
+			result, err := mgr.UseWithOptions(name, ccs.UseOptions{NoSticky: noSticky, NoValidate: noValidate})
+			if err != nil {
+				return withValidationHint(err)
 			}
+			printSchemaWarnings(cmd.ErrOrStderr(), result.Warnings)
 			fmt.Fprintf(stdout, "Successfully switched to settings: %s\n", name)
+			if len(result.Carried) > 0 {
+				fmt.Fprintf(stdout, "Kept sticky keys from the previous settings.json: %s\n", strings.Join(result.Carried, ", "))
+			}
 			return nil
 		},
 	}
+
+	cmd.Flags().BoolVar(&noSticky, "no-sticky", false, "Copy the profile verbatim without carrying over sticky keys")
+	cmd.Flags().BoolVar(&noValidate, "no-validate", false, "Skip checking the profile against the settings schema")
+
 	return cmd
 }
 
 const newSettingsLabel = "[New Settings]"
 
 func newSaveCommand(mgr *ccs.Manager, prompter Prompter) *cobra.Command {
-	return &cobra.Command{
+	var noValidate, strict, format bool
+
+	cmd := &cobra.Command{
 		Use:   "save",
 		Short: "Save current settings and activate them",
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -164,13 +194,22 @@ func newSaveCommand(mgr *ccs.Manager, prompter Prompter) *cobra.Command {
 				}
 			}
 
-			if err := mgr.Save(target); err != nil {
-				return err
+			result, err := mgr.SaveWithOptions(target, ccs.SaveOptions{NoValidate: noValidate, Strict: strict, Format: format})
+			if err != nil {
+				return withValidationHint(err)
 			}
+			printSchemaWarnings(cmd.ErrOrStderr(), result.Warnings)
+			printSecretWarnings(cmd.ErrOrStderr(), result.Secrets)
 			fmt.Fprintf(cmd.OutOrStdout(), "Successfully saved and activated settings: %s\n", target)
 			return nil
 		},
 	}
+
+	cmd.Flags().BoolVar(&noValidate, "no-validate", false, "Skip checking settings.json against the settings schema")
+	cmd.Flags().BoolVar(&strict, "strict", false, "Refuse to save settings containing values that look like secrets")
+	cmd.Flags().BoolVar(&format, "fmt", false, "Format settings.json like ccs fmt before saving")
+
+	return cmd
 }
 
 func newPruneCommand(mgr *ccs.Manager, prompter Prompter, stdout io.Writer) *cobra.Command {
@@ -231,41 +270,9 @@ func newPruneCommand(mgr *ccs.Manager, prompter Prompter, stdout io.Writer) *cob
 	return cmd
 }
 
+// parseHumanDuration parses durations such as "30d" or "12h".
 func parseHumanDuration(value string) (time.Duration, error) {
-	value = strings.TrimSpace(strings.ToLower(value))
-	if value == "" {
-		return 0, errors.New("duration cannot be empty")
-	}
-	if strings.HasSuffix(value, "d") {
-		days := strings.TrimSuffix(value, "d")
-		v, err := parseDays(days)
-		if err != nil {
-			return 0, fmt.Errorf("invalid day duration: %w", err)
-		}
-		return v, nil
-	}
-	if strings.HasSuffix(value, "h") || strings.HasSuffix(value, "m") || strings.HasSuffix(value, "s") {
-		dur, err := time.ParseDuration(value)
-		if err != nil {
-			return 0, err
-		}
-		if dur < 0 {
-			return 0, fmt.Errorf("duration cannot be negative")
-		}
-		return dur, nil
-	}
-	return 0, fmt.Errorf("unsupported duration format: %s", value)
-}
-
-func parseDays(value string) (time.Duration, error) {
-	d, err := strconv.Atoi(value)
-	if err != nil {
-		return 0, fmt.Errorf("invalid day duration: %w", err)
-	}
-	if d < 0 {
-		return 0, fmt.Errorf("invalid day duration: %d", d)
-	}
-	return time.Duration(d) * 24 * time.Hour, nil
+	return config.ParseDuration(value)
 }
 
 // reorderWithDefault moves the default value to the front of the list.
@@ -297,3 +304,33 @@ func reorderWithDefault(items []string, defaultValue string) []string {
 
 	return reordered
 }
+
+// printSchemaWarnings reports schema warnings, such as unknown keys, that do
+// not stop a command.
+func printSchemaWarnings(w io.Writer, warnings []ccs.SchemaIssue) {
+	for _, issue := range warnings {
+		fmt.Fprintf(w, "Warning: %s\n", issue)
+	}
+}
+
+// printSecretWarnings reports values that look like secrets, with a hint on
+// moving them out of the settings.
+func printSecretWarnings(w io.Writer, reports []ccs.SecretReport) {
+	for _, r := range reports {
+		for _, f := range r.Findings {
+			fmt.Fprintf(w, "Warning: %s: %s looks like a secret\n", r.Source, f)
+		}
+	}
+	if len(reports) > 0 {
+		fmt.Fprintln(w, "Move secrets to environment references, or use --strict (secrets.mode \"strict\") to refuse them.")
+	}
+}
+
+// withValidationHint points at --no-validate when err is a schema failure.
+func withValidationHint(err error) error {
+	var verr *ccs.ValidationError
+	if errors.As(err, &verr) {
+		return fmt.Errorf("%w\nUse --no-validate to skip the schema check", err)
+	}
+	return err
+}
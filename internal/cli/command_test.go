@@ -131,10 +131,10 @@ func TestUseCommandInteractive(t *testing.T) {
 	if err != nil {
 		t.Fatalf("stored path: %v", err)
 	}
-	if err := afero.WriteFile(mgr.FileSystem(), path, []byte("stored"), 0o644); err != nil {
+	if err := afero.WriteFile(mgr.FileSystem(), path, []byte(`{"model": "stored"}`), 0o644); err != nil {
 		t.Fatalf("write store: %v", err)
 	}
-	if err := afero.WriteFile(mgr.FileSystem(), mgr.ActiveSettingsPath(), []byte("old"), 0o644); err != nil {
+	if err := afero.WriteFile(mgr.FileSystem(), mgr.ActiveSettingsPath(), []byte(`{"model": "old"}`), 0o644); err != nil {
 		t.Fatalf("write active: %v", err)
 	}
 
@@ -148,7 +148,7 @@ func TestUseCommandInteractive(t *testing.T) {
 	if err != nil {
 		t.Fatalf("read active: %v", err)
 	}
-	if string(content) != "stored" {
+	if string(content) != `{"model": "stored"}` {
 		t.Fatalf("expected stored content, got %s", content)
 	}
 }
@@ -159,10 +159,10 @@ func TestUseCommandArgument(t *testing.T) {
 	if err != nil {
 		t.Fatalf("stored path: %v", err)
 	}
-	if err := afero.WriteFile(mgr.FileSystem(), path, []byte("stored"), 0o644); err != nil {
+	if err := afero.WriteFile(mgr.FileSystem(), path, []byte(`{"model": "stored"}`), 0o644); err != nil {
 		t.Fatalf("write store: %v", err)
 	}
-	if err := afero.WriteFile(mgr.FileSystem(), mgr.ActiveSettingsPath(), []byte("old"), 0o644); err != nil {
+	if err := afero.WriteFile(mgr.FileSystem(), mgr.ActiveSettingsPath(), []byte(`{"model": "old"}`), 0o644); err != nil {
 		t.Fatalf("write active: %v", err)
 	}
 	mgr.SetNow(func() time.Time { return time.Unix(0, 0) })
@@ -181,16 +181,85 @@ func TestUseCommandArgument(t *testing.T) {
 	}
 }
 
+func TestUseCommandReportsStickyKeys(t *testing.T) {
+	mgr := newTestCommandManager(t)
+	if err := afero.WriteFile(mgr.FileSystem(), mgr.ConfigPath(), []byte(`{"use": {"sticky": ["statusLine"]}}`), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if err := mgr.LoadConfig(); err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	path, err := mgr.StoredSettingsPath("work")
+	if err != nil {
+		t.Fatalf("stored path: %v", err)
+	}
+	if err := afero.WriteFile(mgr.FileSystem(), path, []byte(`{"model":"opus"}`), 0o644); err != nil {
+		t.Fatalf("write store: %v", err)
+	}
+	if err := afero.WriteFile(mgr.FileSystem(), mgr.ActiveSettingsPath(), []byte(`{"statusLine":{"type":"command","command":"local"}}`), 0o644); err != nil {
+		t.Fatalf("write active: %v", err)
+	}
+
+	buf := &bytes.Buffer{}
+	cmd := newUseCommand(mgr, &stubPrompter{}, buf)
+	if err := cmd.RunE(cmd, []string{"work"}); err != nil {
+		t.Fatalf("RunE use: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Kept sticky keys from the previous settings.json: statusLine") {
+		t.Fatalf("unexpected output: %s", buf.String())
+	}
+
+	buf.Reset()
+	if err := cmd.Flags().Set("no-sticky", "true"); err != nil {
+		t.Fatalf("set flag: %v", err)
+	}
+	if err := cmd.RunE(cmd, []string{"work"}); err != nil {
+		t.Fatalf("RunE use --no-sticky: %v", err)
+	}
+	content, _ := afero.ReadFile(mgr.FileSystem(), mgr.ActiveSettingsPath())
+	if strings.Contains(buf.String(), "sticky") || string(content) != `{"model":"opus"}` {
+		t.Fatalf("expected verbatim copy, got %q / %s", buf.String(), content)
+	}
+}
+
+func TestUseCommandSchemaWarningsAndErrors(t *testing.T) {
+	mgr := newTestCommandManager(t)
+	for name, content := range map[string]string{"typo": `{"modle":"opus"}`, "broken": `{"model":1}`} {
+		path, err := mgr.StoredSettingsPath(name)
+		if err != nil {
+			t.Fatalf("stored path: %v", err)
+		}
+		if err := afero.WriteFile(mgr.FileSystem(), path, []byte(content), 0o644); err != nil {
+			t.Fatalf("write store: %v", err)
+		}
+	}
+
+	stderr := &bytes.Buffer{}
+	cmd := newUseCommand(mgr, &stubPrompter{}, bytes.NewBuffer(nil))
+	cmd.SetErr(stderr)
+	if err := cmd.RunE(cmd, []string{"typo"}); err != nil {
+		t.Fatalf("RunE use typo: %v", err)
+	}
+	if !strings.Contains(stderr.String(), `Warning: modle: unknown key (did you mean "model"?)`) {
+		t.Fatalf("unexpected warnings: %q", stderr.String())
+	}
+
+	err := cmd.RunE(cmd, []string{"broken"})
+	if err == nil || !strings.Contains(err.Error(), "model: expected string, got number") || !strings.Contains(err.Error(), "--no-validate") {
+		t.Fatalf("expected validation error with hint, got %v", err)
+	}
+}
+
 func TestSaveCommandOverwriteFlow(t *testing.T) {
 	mgr := newTestCommandManager(t)
 	path, err := mgr.StoredSettingsPath("personal")
 	if err != nil {
 		t.Fatalf("stored path: %v", err)
 	}
-	if err := afero.WriteFile(mgr.FileSystem(), path, []byte("old"), 0o644); err != nil {
+	if err := afero.WriteFile(mgr.FileSystem(), path, []byte(`{"model": "old"}`), 0o644); err != nil {
 		t.Fatalf("write store: %v", err)
 	}
-	if err := afero.WriteFile(mgr.FileSystem(), mgr.ActiveSettingsPath(), []byte("Mod"), 0o644); err != nil {
+	if err := afero.WriteFile(mgr.FileSystem(), mgr.ActiveSettingsPath(), []byte(`{"model": "Mod"}`), 0o644); err != nil {
 		t.Fatalf("write active: %v", err)
 	}
 
@@ -209,14 +278,14 @@ func TestSaveCommandOverwriteFlow(t *testing.T) {
 	if err != nil {
 		t.Fatalf("read personal: %v", err)
 	}
-	if string(content) != "Mod" {
+	if string(content) != `{"model": "Mod"}` {
 		t.Fatalf("expected updated content, got %s", content)
 	}
 }
 
 func TestSaveCommandNewValidation(t *testing.T) {
 	mgr := newTestCommandManager(t)
-	if err := afero.WriteFile(mgr.FileSystem(), mgr.ActiveSettingsPath(), []byte("data"), 0o644); err != nil {
+	if err := afero.WriteFile(mgr.FileSystem(), mgr.ActiveSettingsPath(), []byte(`{"model": "data"}`), 0o644); err != nil {
 		t.Fatalf("write active: %v", err)
 	}
 
@@ -297,15 +366,6 @@ func TestParseHumanDurationInvalid(t *testing.T) {
 	}
 }
 
-func TestParseDaysInvalid(t *testing.T) {
-	if _, err := parseDays("abc"); err == nil {
-		t.Fatalf("expected parse error")
-	}
-	if _, err := parseDays("-1"); err == nil {
-		t.Fatalf("expected negative error")
-	}
-}
-
 func TestReorderWithDefault(t *testing.T) {
 	items := []string{"a", "b", "c"}
 	reordered := reorderWithDefault(items, "b")
@@ -326,8 +386,8 @@ func TestNewRootCommand(t *testing.T) {
 	if root == nil {
 		t.Fatalf("expected root command")
 	}
-	if len(root.Commands()) != 4 {
-		t.Fatalf("expected 4 subcommands, got %d", len(root.Commands()))
+	if len(root.Commands()) != 16 {
+		t.Fatalf("expected 16 subcommands, got %d", len(root.Commands()))
 	}
 }
 
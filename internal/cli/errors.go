@@ -1,6 +1,19 @@
 package cli
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 // ErrPromptCancelled indicates that the user aborted an interactive prompt.
 var ErrPromptCancelled = errors.New("prompt cancelled")
+
+// ExitError asks the caller to exit with Code without printing an error,
+// because the command has already reported its result, such as lint findings.
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
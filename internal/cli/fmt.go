@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenGG/claude-code-switch-settings/internal/ccs"
+)
+
+// fmtExitUnformatted is the exit status of ccs fmt --check when files need
+// formatting. Status 1 is left for failures.
+const fmtExitUnformatted = 2
+
+func newFmtCommand(mgr *ccs.Manager, stdout io.Writer) *cobra.Command {
+	var all, check bool
+
+	cmd := &cobra.Command{
+		Use:   "fmt [names...|--all]",
+		Short: "Rewrite settings in a canonical style",
+		Long: "Rewrite settings.json, the named profiles, or every stored profile with\n" +
+			"--all with sorted keys, sorted and deduplicated permission rules and\n" +
+			"two-space indentation. With --check, nothing is written and the command\n" +
+			"exits with status 2 when a file needs formatting.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			results, err := mgr.FormatSettings(args, all, check)
+			if err != nil {
+				return err
+			}
+			changed := 0
+			for _, r := range results {
+				if !r.Changed {
+					continue
+				}
+				changed++
+				name := r.Name
+				if name == "" {
+					name = "settings.json"
+				}
+				if check {
+					fmt.Fprintf(stdout, "%s needs formatting\n", name)
+				} else {
+					fmt.Fprintf(stdout, "Formatted %s\n", name)
+				}
+			}
+			switch {
+			case len(results) == 0:
+				fmt.Fprintln(stdout, "No saved settings found.")
+			case changed == 0:
+				fmt.Fprintf(stdout, "%d file(s) already formatted.\n", len(results))
+			case check:
+				return &ExitError{Code: fmtExitUnformatted}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&all, "all", false, "Format every stored profile")
+	cmd.Flags().BoolVar(&check, "check", false, "Report files that need formatting without changing them")
+
+	return cmd
+}
@@ -0,0 +1,54 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+func TestFmtCommandCheckAndWrite(t *testing.T) {
+	mgr := newTestCommandManager(t)
+	for name, content := range map[string]string{"messy": `{"b": 1, "a": 2}`, "tidy": "{\n  \"a\": 1\n}\n"} {
+		if err := afero.WriteFile(mgr.FileSystem(), filepath.Join(mgr.SettingsStoreDir(), name+".json"), []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	cmd := newFmtCommand(mgr, buf)
+	if err := cmd.Flags().Set("all", "true"); err != nil {
+		t.Fatalf("set flag: %v", err)
+	}
+	if err := cmd.Flags().Set("check", "true"); err != nil {
+		t.Fatalf("set flag: %v", err)
+	}
+	var exitErr *ExitError
+	if err := cmd.RunE(cmd, nil); !errors.As(err, &exitErr) || exitErr.Code != fmtExitUnformatted {
+		t.Fatalf("expected exit status %d, got %v", fmtExitUnformatted, err)
+	}
+	if buf.String() != "messy needs formatting\n" {
+		t.Fatalf("unexpected check output: %q", buf.String())
+	}
+
+	buf.Reset()
+	if err := cmd.Flags().Set("check", "false"); err != nil {
+		t.Fatalf("set flag: %v", err)
+	}
+	if err := cmd.RunE(cmd, nil); err != nil {
+		t.Fatalf("fmt --all: %v", err)
+	}
+	if buf.String() != "Formatted messy\n" {
+		t.Fatalf("unexpected output: %q", buf.String())
+	}
+
+	buf.Reset()
+	if err := cmd.RunE(cmd, nil); err != nil {
+		t.Fatalf("second fmt --all: %v", err)
+	}
+	if buf.String() != "2 file(s) already formatted.\n" {
+		t.Fatalf("unexpected output: %q", buf.String())
+	}
+}
@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenGG/claude-code-switch-settings/internal/ccs"
+)
+
+func newGrepCommand(mgr *ccs.Manager, stdout io.Writer) *cobra.Command {
+	var opts ccs.GrepOptions
+
+	cmd := &cobra.Command{
+		Use:   "grep <pattern>",
+		Short: "Search keys and values in stored profiles",
+		Long: "Search the keys and values of every stored profile for pattern and print\n" +
+			"the profile and JSON path of each match. The pattern is literal text\n" +
+			"unless --regexp is given. --active also searches settings.json and\n" +
+			"--backups also searches every backup.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			result, err := mgr.Grep(args[0], opts)
+			if err != nil {
+				return err
+			}
+			for _, w := range result.Warnings {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Warning: %s\n", w)
+			}
+			for _, match := range result.Matches {
+				fmt.Fprintf(stdout, "%s: %s = %s\n", match.Source, match.Path, truncateCell(formatCellValue(match.Value)))
+			}
+			if len(result.Matches) == 0 {
+				fmt.Fprintln(stdout, "No matches.")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVarP(&opts.Regexp, "regexp", "E", false, "Treat the pattern as a regular expression")
+	cmd.Flags().BoolVarP(&opts.IgnoreCase, "ignore-case", "i", false, "Match without regard to case")
+	cmd.Flags().BoolVar(&opts.KeysOnly, "keys", false, "Match object keys only")
+	cmd.Flags().BoolVar(&opts.ValuesOnly, "values", false, "Match values only")
+	cmd.Flags().BoolVar(&opts.Active, "active", false, "Also search settings.json")
+	cmd.Flags().BoolVar(&opts.Backups, "backups", false, "Also search every backup")
+	cmd.MarkFlagsMutuallyExclusive("keys", "values")
+
+	return cmd
+}
@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+func TestGrepCommand(t *testing.T) {
+	mgr := newTestCommandManager(t)
+	content := `{"permissions": {"allow": ["Bash(rm:*)"]}, "hooks": {"Stop": [{"hooks": []}]}}`
+	if err := afero.WriteFile(mgr.FileSystem(), filepath.Join(mgr.SettingsStoreDir(), "work.json"), []byte(content), 0o644); err != nil {
+		t.Fatalf("write profile: %v", err)
+	}
+
+	buf := &bytes.Buffer{}
+	cmd := newGrepCommand(mgr, buf)
+	if err := cmd.RunE(cmd, []string{"rm:"}); err != nil {
+		t.Fatalf("grep: %v", err)
+	}
+	if buf.String() != "work: permissions.allow[0] = Bash(rm:*)\n" {
+		t.Fatalf("unexpected output: %q", buf.String())
+	}
+
+	buf.Reset()
+	if err := cmd.Flags().Set("keys", "true"); err != nil {
+		t.Fatalf("set flag: %v", err)
+	}
+	if err := cmd.RunE(cmd, []string{"stop"}); err != nil {
+		t.Fatalf("grep --keys: %v", err)
+	}
+	if buf.String() != "No matches.\n" {
+		t.Fatalf("unexpected output: %q", buf.String())
+	}
+
+	buf.Reset()
+	if err := cmd.Flags().Set("ignore-case", "true"); err != nil {
+		t.Fatalf("set flag: %v", err)
+	}
+	if err := cmd.RunE(cmd, []string{"stop"}); err != nil {
+		t.Fatalf("grep --keys -i: %v", err)
+	}
+	if buf.String() != "work: hooks.Stop = [{\"hooks\":[]}]\n" {
+		t.Fatalf("unexpected output: %q", buf.String())
+	}
+}
@@ -0,0 +1,195 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenGG/claude-code-switch-settings/internal/ccs"
+	"github.com/OpenGG/claude-code-switch-settings/internal/ccs/jsondoc"
+)
+
+const keyPathHelp = "Paths are dotted (env.ANTHROPIC_MODEL, permissions.allow[0]) or JSON\n" +
+	"pointers (/permissions/allow/0)."
+
+func newGetCommand(mgr *ccs.Manager, stdout io.Writer) *cobra.Command {
+	var profile string
+
+	cmd := &cobra.Command{
+		Use:   "get <json.path>",
+		Short: "Print a settings value",
+		Long: "Print the value at a key path in settings.json or a stored profile.\n" +
+			"Strings are printed as they are, other values as JSON. " + keyPathHelp,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			value, present, err := mgr.GetKey(args[0], profile)
+			if err != nil {
+				return err
+			}
+			if !present {
+				return fmt.Errorf("%s is not set", args[0])
+			}
+			if s, ok := value.(string); ok {
+				fmt.Fprintln(stdout, s)
+				return nil
+			}
+			data, err := jsondoc.Marshal(value, "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(stdout, string(data))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&profile, "profile", "", "Read a stored profile instead of settings.json")
+
+	return cmd
+}
+
+func newSetCommand(mgr *ccs.Manager, stdout io.Writer) *cobra.Command {
+	var opts ccs.KeyEditOptions
+	var bulk ccs.BulkEditOptions
+
+	cmd := &cobra.Command{
+		Use:   "set <json.path> <value>",
+		Short: "Change a settings value",
+		Long: "Set the value at a key path in settings.json, a stored profile, or many\n" +
+			"profiles at once with --all, --profiles or --tag. The value is parsed as\n" +
+			"JSON when possible (true, 3, [\"Read\"]) and stored as a string otherwise.\n" +
+			"Files are backed up first and other keys keep their order. A bulk edit\n" +
+			"changes every selected profile or none of them.\n" + keyPathHelp,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			value := parseValueArg(args[1])
+			if isBulk(bulk) {
+				if opts.Profile != "" {
+					return errors.New("--profile cannot be combined with --all, --profiles or --tag")
+				}
+				bulk.NoValidate = opts.NoValidate
+				edits, err := mgr.BulkSetKey(args[0], value, bulk)
+				if err != nil {
+					return withValidationHint(err)
+				}
+				printBulkEdits(cmd.ErrOrStderr(), stdout, edits)
+				return nil
+			}
+			edit, err := mgr.SetKey(args[0], value, opts)
+			if err != nil {
+				return withValidationHint(err)
+			}
+			printSchemaWarnings(cmd.ErrOrStderr(), edit.Warnings)
+			printKeyEdit(stdout, edit)
+			return nil
+		},
+	}
+
+	addKeyEditFlags(cmd, &opts, &bulk)
+
+	return cmd
+}
+
+func newUnsetCommand(mgr *ccs.Manager, stdout io.Writer) *cobra.Command {
+	var opts ccs.KeyEditOptions
+	var bulk ccs.BulkEditOptions
+
+	cmd := &cobra.Command{
+		Use:   "unset <json.path>",
+		Short: "Remove a settings value",
+		Long: "Remove the value at a key path from settings.json, a stored profile, or\n" +
+			"many profiles at once with --all, --profiles or --tag. Files are backed up\n" +
+			"first and other keys keep their order. " + keyPathHelp,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if isBulk(bulk) {
+				if opts.Profile != "" {
+					return errors.New("--profile cannot be combined with --all, --profiles or --tag")
+				}
+				bulk.NoValidate = opts.NoValidate
+				edits, err := mgr.BulkUnsetKey(args[0], bulk)
+				if err != nil {
+					return withValidationHint(err)
+				}
+				printBulkEdits(cmd.ErrOrStderr(), stdout, edits)
+				return nil
+			}
+			edit, err := mgr.UnsetKey(args[0], opts)
+			if err != nil {
+				return withValidationHint(err)
+			}
+			printSchemaWarnings(cmd.ErrOrStderr(), edit.Warnings)
+			printKeyEdit(stdout, edit)
+			return nil
+		},
+	}
+
+	addKeyEditFlags(cmd, &opts, &bulk)
+
+	return cmd
+}
+
+func addKeyEditFlags(cmd *cobra.Command, opts *ccs.KeyEditOptions, bulk *ccs.BulkEditOptions) {
+	cmd.Flags().StringVar(&opts.Profile, "profile", "", "Edit a stored profile instead of settings.json")
+	cmd.Flags().BoolVar(&opts.NoValidate, "no-validate", false, "Skip checking the result against the settings schema")
+	cmd.Flags().BoolVar(&bulk.All, "all", false, "Edit every stored profile")
+	cmd.Flags().StringSliceVar(&bulk.Profiles, "profiles", nil, "Edit these stored profiles (comma-separated)")
+	cmd.Flags().StringVar(&bulk.Tag, "tag", "", "Edit the profiles listed under this tag in the config file")
+}
+
+func isBulk(bulk ccs.BulkEditOptions) bool {
+	return bulk.All || len(bulk.Profiles) > 0 || bulk.Tag != ""
+}
+
+// printBulkEdits summarizes a bulk edit: the value change per profile,
+// followed by the profiles that already held the value.
+func printBulkEdits(stderr, stdout io.Writer, edits []ccs.KeyEdit) {
+	var changed, unchanged []string
+	for _, edit := range edits {
+		for _, issue := range edit.Warnings {
+			fmt.Fprintf(stderr, "Warning: %s: %s\n", edit.Profile, issue)
+		}
+		if !edit.Changed {
+			unchanged = append(unchanged, edit.Profile)
+			continue
+		}
+		change := edit.Change
+		changed = append(changed, fmt.Sprintf("  %s: %s -> %s", edit.Profile,
+			keyValueLabel(change.Old, change.OldPresent), keyValueLabel(change.New, change.NewPresent)))
+	}
+	if len(edits) > 0 {
+		fmt.Fprintf(stdout, "Updated %s in %d of %d profile(s)\n", edits[0].Change.Key, len(changed), len(edits))
+	}
+	for _, line := range changed {
+		fmt.Fprintln(stdout, line)
+	}
+	if len(unchanged) > 0 {
+		fmt.Fprintf(stdout, "Unchanged: %s\n", strings.Join(unchanged, ", "))
+	}
+}
+
+// parseValueArg decodes a command-line value as JSON, falling back to the
+// literal string.
+func parseValueArg(arg string) any {
+	if value, err := jsondoc.Parse([]byte(arg)); err == nil {
+		return value
+	}
+	return arg
+}
+
+func printKeyEdit(w io.Writer, edit ccs.KeyEdit) {
+	change := edit.Change
+	if !edit.Changed {
+		if change.NewPresent {
+			fmt.Fprintf(w, "%s is already %s in %s\n", change.Key, renderValue(change.New), edit.File)
+		} else {
+			fmt.Fprintf(w, "%s is not set in %s\n", change.Key, edit.File)
+		}
+		return
+	}
+	fmt.Fprintf(w, "Updated %s in %s\n", change.Key, edit.File)
+	fmt.Fprintf(w, "  - %s\n", keyValueLabel(change.Old, change.OldPresent))
+	fmt.Fprintf(w, "  + %s\n", keyValueLabel(change.New, change.NewPresent))
+}
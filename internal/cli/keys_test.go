@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+func TestGetSetUnsetCommands(t *testing.T) {
+	mgr := newTestCommandManager(t)
+	profile := filepath.Join(mgr.SettingsStoreDir(), "work.json")
+	if err := afero.WriteFile(mgr.FileSystem(), profile, []byte(`{"model": "opus", "env": {}}`), 0o644); err != nil {
+		t.Fatalf("write profile: %v", err)
+	}
+
+	buf := &bytes.Buffer{}
+	set := newSetCommand(mgr, buf)
+	if err := set.Flags().Set("profile", "work"); err != nil {
+		t.Fatalf("set flag: %v", err)
+	}
+	if err := set.RunE(set, []string{"permissions.allow", `["Read"]`}); err != nil {
+		t.Fatalf("set allow: %v", err)
+	}
+	if err := set.RunE(set, []string{"env.MODE", "fast mode"}); err != nil {
+		t.Fatalf("set env: %v", err)
+	}
+	for _, want := range []string{"Updated permissions.allow", "  - (absent)", `  + ["Read"]`, `  + "fast mode"`} {
+		if !strings.Contains(buf.String(), want) {
+			t.Fatalf("expected %q in output:\n%s", want, buf.String())
+		}
+	}
+
+	buf.Reset()
+	get := newGetCommand(mgr, buf)
+	if err := get.Flags().Set("profile", "work"); err != nil {
+		t.Fatalf("set flag: %v", err)
+	}
+	if err := get.RunE(get, []string{"env.MODE"}); err != nil {
+		t.Fatalf("get env: %v", err)
+	}
+	if err := get.RunE(get, []string{"permissions"}); err != nil {
+		t.Fatalf("get permissions: %v", err)
+	}
+	if want := "fast mode\n{\n  \"allow\": [\n    \"Read\"\n  ]\n}\n"; buf.String() != want {
+		t.Fatalf("get output = %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	unset := newUnsetCommand(mgr, buf)
+	if err := unset.Flags().Set("profile", "work"); err != nil {
+		t.Fatalf("set flag: %v", err)
+	}
+	if err := unset.RunE(unset, []string{"model"}); err != nil {
+		t.Fatalf("unset: %v", err)
+	}
+	if err := get.RunE(get, []string{"model"}); err == nil || !strings.Contains(err.Error(), "model is not set") {
+		t.Fatalf("expected not set error, got %v", err)
+	}
+
+	err := set.RunE(set, []string{"permissions.allow", "Read"})
+	if err == nil || !strings.Contains(err.Error(), "--no-validate") {
+		t.Fatalf("expected a schema error with a hint, got %v", err)
+	}
+}
+
+func TestSetCommandBulkEdit(t *testing.T) {
+	mgr := newTestCommandManager(t)
+	for name, content := range map[string]string{"a": `{"model": "haiku"}`, "b": `{"model": "opus"}`, "c": `{}`} {
+		if err := afero.WriteFile(mgr.FileSystem(), filepath.Join(mgr.SettingsStoreDir(), name+".json"), []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	set := newSetCommand(mgr, buf)
+	if err := set.Flags().Set("profiles", "a,b"); err != nil {
+		t.Fatalf("set flag: %v", err)
+	}
+	if err := set.RunE(set, []string{"model", "opus"}); err != nil {
+		t.Fatalf("bulk set: %v", err)
+	}
+	want := "Updated model in 1 of 2 profile(s)\n  a: \"haiku\" -> \"opus\"\nUnchanged: b\n"
+	if buf.String() != want {
+		t.Fatalf("output = %q, want %q", buf.String(), want)
+	}
+
+	if err := set.Flags().Set("profile", "c"); err != nil {
+		t.Fatalf("set flag: %v", err)
+	}
+	if err := set.RunE(set, []string{"model", "opus"}); err == nil || !strings.Contains(err.Error(), "cannot be combined") {
+		t.Fatalf("expected a flag conflict error, got %v", err)
+	}
+
+	buf.Reset()
+	unset := newUnsetCommand(mgr, buf)
+	if err := unset.Flags().Set("all", "true"); err != nil {
+		t.Fatalf("set flag: %v", err)
+	}
+	if err := unset.RunE(unset, []string{"model"}); err != nil {
+		t.Fatalf("bulk unset: %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "Updated model in 2 of 3 profile(s)\n") || !strings.Contains(buf.String(), "Unchanged: c") {
+		t.Fatalf("unexpected output:\n%s", buf.String())
+	}
+}
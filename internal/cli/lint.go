@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenGG/claude-code-switch-settings/internal/ccs"
+	"github.com/OpenGG/claude-code-switch-settings/internal/ccs/lint"
+)
+
+// Exit statuses of ccs lint. Status 1 is left for failures to run the lint.
+const (
+	lintExitWarning = 2
+	lintExitError   = 3
+)
+
+type lintFileJSON struct {
+	Name     string            `json:"name"`
+	File     string            `json:"file"`
+	Findings []ccs.LintFinding `json:"findings"`
+}
+
+type lintOutputJSON struct {
+	Files   []lintFileJSON `json:"files"`
+	Summary map[string]int `json:"summary"`
+}
+
+func newLintCommand(mgr *ccs.Manager, stdout io.Writer) *cobra.Command {
+	var all bool
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "lint [names...|--all]",
+		Short: "Check settings against the schema and lint rules",
+		Long: "Check settings.json, the named profiles, or every stored profile with --all\n" +
+			"against the settings schema, the built-in rules and the rules file set by\n" +
+			"lint.rulesFile in the config file.\n\n" +
+			"Exits with status 2 when the worst finding is a warning and 3 when it is\n" +
+			"an error.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != "text" && format != "json" {
+				return fmt.Errorf("unknown format %q (want text or json)", format)
+			}
+			reports, err := mgr.Lint(args, all)
+			if err != nil {
+				return err
+			}
+
+			summary := map[string]int{"error": 0, "warning": 0, "info": 0}
+			var findings []ccs.LintFinding
+			for _, r := range reports {
+				for _, f := range r.Findings {
+					summary[f.Severity.String()]++
+				}
+				findings = append(findings, r.Findings...)
+			}
+
+			if format == "json" {
+				out := lintOutputJSON{Files: []lintFileJSON{}, Summary: summary}
+				for _, r := range reports {
+					file := lintFileJSON{Name: lintLabel(r), File: r.File, Findings: r.Findings}
+					if file.Findings == nil {
+						file.Findings = []ccs.LintFinding{}
+					}
+					out.Files = append(out.Files, file)
+				}
+				enc := json.NewEncoder(stdout)
+				enc.SetIndent("", "  ")
+				if err := enc.Encode(out); err != nil {
+					return err
+				}
+			} else {
+				for _, r := range reports {
+					if len(r.Findings) == 0 {
+						fmt.Fprintf(stdout, "%s: ok\n", lintLabel(r))
+					}
+					for _, f := range r.Findings {
+						fmt.Fprintf(stdout, "%s: %s\n", lintLabel(r), f)
+					}
+				}
+				if len(reports) == 0 {
+					fmt.Fprintln(stdout, "No saved settings found.")
+				} else if len(findings) > 0 {
+					fmt.Fprintf(stdout, "%d error(s), %d warning(s), %d info\n", summary["error"], summary["warning"], summary["info"])
+				}
+			}
+
+			switch highest, _ := lint.Highest(findings); highest {
+			case lint.Error:
+				return &ExitError{Code: lintExitError}
+			case lint.Warning:
+				return &ExitError{Code: lintExitWarning}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&all, "all", false, "Lint every stored profile")
+	cmd.Flags().StringVar(&format, "format", "text", "Output format: text or json")
+
+	return cmd
+}
+
+// lintLabel names the linted file: the profile name or settings.json.
+func lintLabel(r ccs.LintReport) string {
+	if r.Name == "" {
+		return "settings.json"
+	}
+	return r.Name
+}
@@ -0,0 +1,66 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+func TestLintCommandOutputAndExitCodes(t *testing.T) {
+	mgr := newTestCommandManager(t)
+	fs := mgr.FileSystem()
+	profiles := map[string]string{
+		"clean":  `{"model": "opus"}`,
+		"typo":   `{"modle": "opus"}`,
+		"danger": `{"permissions": {"defaultMode": "bypassPermissions"}}`,
+	}
+	for name, content := range profiles {
+		if err := afero.WriteFile(fs, filepath.Join(mgr.SettingsStoreDir(), name+".json"), []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	cmd := newLintCommand(mgr, buf)
+	if err := cmd.RunE(cmd, []string{"clean"}); err != nil {
+		t.Fatalf("lint clean: %v", err)
+	}
+	if buf.String() != "clean: ok\n" {
+		t.Fatalf("unexpected output: %q", buf.String())
+	}
+
+	buf.Reset()
+	var exitErr *ExitError
+	if err := cmd.RunE(cmd, []string{"typo"}); !errors.As(err, &exitErr) || exitErr.Code != lintExitWarning {
+		t.Fatalf("expected warning exit, got %v", err)
+	}
+	if !strings.Contains(buf.String(), "typo: warning [schema] modle:") {
+		t.Fatalf("unexpected output:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := cmd.Flags().Set("all", "true"); err != nil {
+		t.Fatalf("set flag: %v", err)
+	}
+	if err := cmd.Flags().Set("format", "json"); err != nil {
+		t.Fatalf("set flag: %v", err)
+	}
+	if err := cmd.RunE(cmd, nil); !errors.As(err, &exitErr) || exitErr.Code != lintExitError {
+		t.Fatalf("expected error exit, got %v", err)
+	}
+	var out lintOutputJSON
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatalf("decode JSON output: %v\n%s", err, buf.String())
+	}
+	if len(out.Files) != 3 || out.Summary["error"] != 1 || out.Summary["warning"] != 1 {
+		t.Fatalf("unexpected JSON output:\n%s", buf.String())
+	}
+	if !strings.Contains(buf.String(), `"severity": "error"`) || !strings.Contains(buf.String(), `"rule": "no-bypass-permissions"`) {
+		t.Fatalf("findings missing from JSON output:\n%s", buf.String())
+	}
+}
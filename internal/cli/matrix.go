@@ -0,0 +1,162 @@
+package cli
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenGG/claude-code-switch-settings/internal/ccs"
+	"github.com/OpenGG/claude-code-switch-settings/internal/ccs/jsondoc"
+)
+
+// matrixCellWidth caps the width of a table cell; longer values are cut.
+const matrixCellWidth = 40
+
+type matrixProfileJSON struct {
+	Name   string         `json:"name"`
+	Active bool           `json:"active,omitempty"`
+	Values map[string]any `json:"values"`
+	Error  string         `json:"error,omitempty"`
+}
+
+type matrixOutputJSON struct {
+	Keys     []string            `json:"keys"`
+	Profiles []matrixProfileJSON `json:"profiles"`
+}
+
+func newMatrixCommand(mgr *ccs.Manager, stdout io.Writer) *cobra.Command {
+	var format string
+	var showSecrets bool
+
+	cmd := &cobra.Command{
+		Use:   "matrix [paths...]",
+		Short: "Compare keys across stored profiles",
+		Long: "Show a table of stored profiles against JSON key paths such as model or\n" +
+			"env.ANTHROPIC_BASE_URL. Without paths, " + strings.Join(ccs.DefaultMatrixKeys, ", ") + "\n" +
+			"are shown. Values that look like secrets are redacted unless --show-secrets\n" +
+			"is given.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != "table" && format != "csv" && format != "json" {
+				return fmt.Errorf("unknown format %q (want table, csv or json)", format)
+			}
+			matrix, err := mgr.Matrix(args, showSecrets)
+			if err != nil {
+				return err
+			}
+			switch format {
+			case "json":
+				return writeMatrixJSON(stdout, matrix)
+			case "csv":
+				return writeMatrixCSV(stdout, matrix)
+			}
+			if len(matrix.Rows) == 0 {
+				fmt.Fprintln(stdout, "No saved settings found.")
+				return nil
+			}
+			return writeMatrixTable(stdout, matrix)
+		},
+	}
+
+	cmd.Flags().StringVar(&format, "format", "table", "Output format: table, csv or json")
+	cmd.Flags().BoolVar(&showSecrets, "show-secrets", false, "Show values that look like secrets in full")
+
+	return cmd
+}
+
+func writeMatrixTable(w io.Writer, matrix ccs.Matrix) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "PROFILE\t%s\n", strings.Join(matrix.Keys, "\t"))
+	for _, row := range matrix.Rows {
+		name := "  " + row.Name
+		if row.Active {
+			name = "* " + row.Name
+		}
+		if row.Err != "" {
+			fmt.Fprintf(tw, "%s\t(%s)\n", name, row.Err)
+			continue
+		}
+		cells := make([]string, len(row.Cells))
+		for i, cell := range row.Cells {
+			cells[i] = truncateCell(summarizeCell(cell))
+		}
+		fmt.Fprintf(tw, "%s\t%s\n", name, strings.Join(cells, "\t"))
+	}
+	return tw.Flush()
+}
+
+func writeMatrixCSV(w io.Writer, matrix ccs.Matrix) error {
+	cw := csv.NewWriter(w)
+	header := append([]string{"profile", "active"}, matrix.Keys...)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, row := range matrix.Rows {
+		record := []string{row.Name, fmt.Sprint(row.Active)}
+		for i := range matrix.Keys {
+			value := ""
+			if i < len(row.Cells) && row.Cells[i].Present {
+				value = formatCellValue(row.Cells[i].Value)
+			}
+			record = append(record, value)
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeMatrixJSON(w io.Writer, matrix ccs.Matrix) error {
+	out := matrixOutputJSON{Keys: matrix.Keys, Profiles: []matrixProfileJSON{}}
+	for _, row := range matrix.Rows {
+		profile := matrixProfileJSON{Name: row.Name, Active: row.Active, Values: map[string]any{}, Error: row.Err}
+		for i, cell := range row.Cells {
+			if cell.Present {
+				profile.Values[matrix.Keys[i]] = cell.Value
+			}
+		}
+		out.Profiles = append(out.Profiles, profile)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+// summarizeCell renders a cell for the table: "-" when the key is not set,
+// the key names for objects and compact JSON for everything else.
+func summarizeCell(cell ccs.MatrixCell) string {
+	if !cell.Present {
+		return "-"
+	}
+	if obj, ok := cell.Value.(*jsondoc.Object); ok {
+		return "{" + strings.Join(obj.Keys(), ", ") + "}"
+	}
+	return formatCellValue(cell.Value)
+}
+
+// formatCellValue renders strings as-is and other values as compact JSON.
+func formatCellValue(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+func truncateCell(s string) string {
+	s = strings.ReplaceAll(s, "\t", " ")
+	runes := []rune(s)
+	if len(runes) <= matrixCellWidth {
+		return s
+	}
+	return string(runes[:matrixCellWidth-3]) + "..."
+}
@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+func TestMatrixCommandFormats(t *testing.T) {
+	mgr := newTestCommandManager(t)
+	for name, content := range map[string]string{
+		"work": `{"model": "opus", "hooks": {"Stop": [], "PreToolUse": []}}`,
+		"home": `{"model": "sonnet, fast"}`,
+	} {
+		if err := afero.WriteFile(mgr.FileSystem(), filepath.Join(mgr.SettingsStoreDir(), name+".json"), []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	cmd := newMatrixCommand(mgr, buf)
+	if err := cmd.RunE(cmd, []string{"model", "hooks"}); err != nil {
+		t.Fatalf("matrix: %v", err)
+	}
+	want := "PROFILE  model         hooks\n" +
+		"  home   sonnet, fast  -\n" +
+		"  work   opus          {Stop, PreToolUse}\n"
+	if buf.String() != want {
+		t.Fatalf("unexpected table:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := cmd.Flags().Set("format", "csv"); err != nil {
+		t.Fatalf("set flag: %v", err)
+	}
+	if err := cmd.RunE(cmd, []string{"model", "hooks"}); err != nil {
+		t.Fatalf("matrix csv: %v", err)
+	}
+	want = "profile,active,model,hooks\n" +
+		"home,false,\"sonnet, fast\",\n" +
+		"work,false,opus,\"{\"\"Stop\"\":[],\"\"PreToolUse\"\":[]}\"\n"
+	if buf.String() != want {
+		t.Fatalf("unexpected csv:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := cmd.Flags().Set("format", "json"); err != nil {
+		t.Fatalf("set flag: %v", err)
+	}
+	if err := cmd.RunE(cmd, []string{"model"}); err != nil {
+		t.Fatalf("matrix json: %v", err)
+	}
+	var out matrixOutputJSON
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatalf("decode json: %v", err)
+	}
+	if len(out.Profiles) != 2 || out.Profiles[1].Values["model"] != "opus" {
+		t.Fatalf("unexpected json output: %s", buf.String())
+	}
+
+	if err := cmd.Flags().Set("format", "yaml"); err != nil {
+		t.Fatalf("set flag: %v", err)
+	}
+	if err := cmd.RunE(cmd, nil); err == nil {
+		t.Fatalf("expected unknown format error")
+	}
+}
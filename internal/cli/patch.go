@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+
+	"github.com/OpenGG/claude-code-switch-settings/internal/ccs"
+	"github.com/OpenGG/claude-code-switch-settings/internal/ccs/textdiff"
+)
+
+func newPatchCommand(mgr *ccs.Manager, stdout io.Writer) *cobra.Command {
+	var opts ccs.PatchOptions
+	var current bool
+
+	cmd := &cobra.Command{
+		Use:   "patch <profile|--current> <patchfile|->",
+		Short: "Apply a JSON Patch or merge patch to settings",
+		Long: "Apply a patch file to a stored profile, or to settings.json with --current.\n" +
+			"A file holding a JSON array is an RFC 6902 JSON Patch; one holding an\n" +
+			"object is an RFC 7386 merge patch. Use - to read the patch from stdin.\n\n" +
+			"The patch applies all or nothing: if any operation fails, including a\n" +
+			"\"test\" operation, nothing is written. The file is backed up first.\n" +
+			"--dry-run shows the resulting diff without writing it.",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if current {
+				return cobra.ExactArgs(1)(cmd, args)
+			}
+			if len(args) != 2 {
+				return errors.New("expected a profile and a patch file, or --current and a patch file")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !current {
+				opts.Profile = args[0]
+			}
+			source := args[len(args)-1]
+			var data []byte
+			var err error
+			if source == "-" {
+				data, err = io.ReadAll(cmd.InOrStdin())
+			} else {
+				data, err = afero.ReadFile(mgr.FileSystem(), source)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to read patch: %w", err)
+			}
+
+			result, err := mgr.PatchSettings(data, opts)
+			if err != nil {
+				return withValidationHint(err)
+			}
+			printSchemaWarnings(cmd.ErrOrStderr(), result.Warnings)
+			switch {
+			case !result.Changed:
+				fmt.Fprintf(stdout, "%s already matches the %s.\n", result.File, result.Kind)
+			case opts.DryRun:
+				fmt.Fprint(stdout, textdiff.Unified(string(result.Before), string(result.After), result.File+" (current)", result.File+" (patched)", 3))
+				fmt.Fprintln(stdout, "Dry run: nothing was written.")
+			default:
+				fmt.Fprintf(stdout, "Applied %s to %s.\n", result.Kind, result.File)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&current, "current", false, "Patch settings.json instead of a stored profile")
+	cmd.Flags().BoolVar(&opts.DryRun, "dry-run", false, "Show the resulting diff without writing it")
+	cmd.Flags().BoolVar(&opts.NoValidate, "no-validate", false, "Skip the settings schema check")
+
+	return cmd
+}
@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+func TestPatchCommandDryRunAndApply(t *testing.T) {
+	mgr := newTestCommandManager(t)
+	fs := mgr.FileSystem()
+	file := filepath.Join(mgr.SettingsStoreDir(), "work.json")
+	if err := afero.WriteFile(fs, file, []byte("{\n  \"model\": \"opus\"\n}\n"), 0o644); err != nil {
+		t.Fatalf("write profile: %v", err)
+	}
+	if err := afero.WriteFile(fs, "/tmp/model.json", []byte(`[{"op": "replace", "path": "/model", "value": "sonnet"}]`), 0o644); err != nil {
+		t.Fatalf("write patch: %v", err)
+	}
+
+	buf := &bytes.Buffer{}
+	cmd := newPatchCommand(mgr, buf)
+	if err := cmd.Flags().Set("dry-run", "true"); err != nil {
+		t.Fatalf("set flag: %v", err)
+	}
+	if err := cmd.RunE(cmd, []string{"work", "/tmp/model.json"}); err != nil {
+		t.Fatalf("patch --dry-run: %v", err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "-  \"model\": \"opus\"") || !strings.Contains(out, "+  \"model\": \"sonnet\"") || !strings.HasSuffix(out, "Dry run: nothing was written.\n") {
+		t.Fatalf("unexpected dry run output:\n%s", out)
+	}
+
+	buf.Reset()
+	if err := cmd.Flags().Set("dry-run", "false"); err != nil {
+		t.Fatalf("set flag: %v", err)
+	}
+	if err := cmd.RunE(cmd, []string{"work", "/tmp/model.json"}); err != nil {
+		t.Fatalf("patch: %v", err)
+	}
+	if buf.String() != "Applied JSON patch to "+file+".\n" {
+		t.Fatalf("unexpected output: %q", buf.String())
+	}
+}
+
+func TestPatchCommandCurrentFromStdin(t *testing.T) {
+	mgr := newTestCommandManager(t)
+	buf := &bytes.Buffer{}
+	cmd := newPatchCommand(mgr, buf)
+	cmd.SetIn(strings.NewReader(`{"model": "haiku"}`))
+	if err := cmd.Flags().Set("current", "true"); err != nil {
+		t.Fatalf("set flag: %v", err)
+	}
+	if err := cmd.Args(cmd, []string{"work", "-"}); err == nil {
+		t.Fatalf("expected --current to take only the patch file")
+	}
+	if err := cmd.RunE(cmd, []string{"-"}); err != nil {
+		t.Fatalf("patch --current: %v", err)
+	}
+	data, err := afero.ReadFile(mgr.FileSystem(), mgr.ActiveSettingsPath())
+	if err != nil || !strings.Contains(string(data), `"model": "haiku"`) {
+		t.Fatalf("unexpected settings.json: %s, %v", data, err)
+	}
+	if buf.String() != "Applied merge patch to "+mgr.ActiveSettingsPath()+".\n" {
+		t.Fatalf("unexpected output: %q", buf.String())
+	}
+}
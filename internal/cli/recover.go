@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenGG/claude-code-switch-settings/internal/ccs"
+	"github.com/OpenGG/claude-code-switch-settings/internal/ccs/textdiff"
+)
+
+func newRecoverCommand(mgr *ccs.Manager, prompter Prompter, stdout io.Writer) *cobra.Command {
+	var force bool
+
+	cmd := &cobra.Command{
+		Use:   "recover",
+		Short: "Restore a corrupted settings.json from its last valid backup",
+		Long: "When settings.json is not valid JSON, find the most recent backup of it\n" +
+			"that parses, show the difference and restore it. The broken file is\n" +
+			"snapshotted first.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			plan, err := mgr.PlanRecovery()
+			if err != nil {
+				return err
+			}
+			annotations, err := mgr.BackupAnnotations()
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(stdout, "Most recent valid backup: %s\n", snapshotLabel(plan.Snapshot, annotations))
+			fmt.Fprint(stdout, textdiff.Unified(string(plan.Current), string(plan.Content), "settings.json (current)", "settings.json (backup "+ccs.ShortHash(plan.Hash)+")", 3))
+
+			if !force {
+				confirm, err := prompter.Confirm("Restore settings.json from this backup? (y/N)", false)
+				if err != nil {
+					return err
+				}
+				if !confirm {
+					fmt.Fprintln(stdout, "Recovery cancelled.")
+					return nil
+				}
+			}
+
+			if err := mgr.ApplyRecovery(plan); err != nil {
+				return err
+			}
+			fmt.Fprintf(stdout, "Recovered settings.json from snapshot %s.\n", plan.Snapshot.ID)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&force, "force", false, "Do not prompt for confirmation")
+
+	return cmd
+}
+
+// warnIfSettingsCorrupt prints a prominent warning when settings.json exists
+// but is not valid JSON, since Claude Code cannot start with it.
+func warnIfSettingsCorrupt(mgr *ccs.Manager, stderr io.Writer) {
+	err := mgr.CheckActiveSettings()
+	if err == nil {
+		return
+	}
+	fmt.Fprintf(stderr, "WARNING: %s: %v\n", mgr.ActiveSettingsPath(), err)
+	fmt.Fprintln(stderr, "WARNING: Claude Code will fail to start. Run `ccs recover` to restore the last valid backup.")
+}
@@ -0,0 +1,43 @@
+package cli
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+func TestRecoverCommandShowsDiffAndRestores(t *testing.T) {
+	mgr := newTestCommandManager(t)
+	fs := mgr.FileSystem()
+	if err := afero.WriteFile(fs, mgr.ActiveSettingsPath(), []byte("{\n  \"model\": \"haiku\"\n}\n"), 0o644); err != nil {
+		t.Fatalf("write active: %v", err)
+	}
+	if err := afero.WriteFile(fs, filepath.Join(mgr.SettingsStoreDir(), "work.json"), []byte(`{"model":"opus"}`), 0o644); err != nil {
+		t.Fatalf("write stored: %v", err)
+	}
+	if err := mgr.Use("work"); err != nil {
+		t.Fatalf("use: %v", err)
+	}
+	if err := afero.WriteFile(fs, mgr.ActiveSettingsPath(), []byte("{\n  \"model\": \n"), 0o644); err != nil {
+		t.Fatalf("corrupt active: %v", err)
+	}
+
+	buf := &bytes.Buffer{}
+	prompter := &stubPrompter{confirms: []confirmResponse{{value: true}}}
+	cmd := newRecoverCommand(mgr, prompter, buf)
+	if err := cmd.RunE(cmd, nil); err != nil {
+		t.Fatalf("RunE recover: %v", err)
+	}
+	output := buf.String()
+	for _, want := range []string{"use work", "-  \"model\": ", "+  \"model\": \"haiku\"", "Recovered settings.json"} {
+		if !strings.Contains(output, want) {
+			t.Fatalf("expected %q in output:\n%s", want, output)
+		}
+	}
+	if err := mgr.CheckActiveSettings(); err != nil {
+		t.Fatalf("expected settings.json to be valid: %v", err)
+	}
+}
@@ -0,0 +1,142 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenGG/claude-code-switch-settings/internal/ccs"
+)
+
+func newRestoreCommand(mgr *ccs.Manager, prompter Prompter, stdout io.Writer) *cobra.Command {
+	var (
+		force      bool
+		noValidate bool
+		keys       []string
+		profile    string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "restore [snapshot-id | backup-hash --keys a,b]",
+		Short: "Restore ccs-managed files from an operation snapshot",
+		Long: "Restore settings.json, the active state file and stored profiles to the\n" +
+			"content they had when a snapshot was taken. The current content is\n" +
+			"snapshotted first, so a restore can itself be undone.\n\n" +
+			"With --keys, only the given JSON paths are merged from a backup into\n" +
+			"settings.json (or the profile named by --profile), leaving every other\n" +
+			"key untouched.",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(keys) > 0 {
+				if len(args) == 0 {
+					return errors.New("restore command: --keys requires a backup hash")
+				}
+				opts := ccs.KeyRestoreOptions{Profile: profile, NoValidate: noValidate}
+				return withValidationHint(restoreKeys(mgr, prompter, stdout, cmd.ErrOrStderr(), args[0], keys, opts, force))
+			}
+			if profile != "" {
+				return errors.New("restore command: --profile requires --keys")
+			}
+			if noValidate {
+				return errors.New("restore command: --no-validate requires --keys")
+			}
+			id := ""
+			if len(args) > 0 {
+				id = args[0]
+			} else {
+				snaps, err := mgr.Snapshots()
+				if err != nil {
+					return err
+				}
+				if len(snaps) == 0 {
+					return errors.New("restore command: no snapshots available")
+				}
+				annotations, err := mgr.BackupAnnotations()
+				if err != nil {
+					return err
+				}
+				items := make([]string, len(snaps))
+				for i, snap := range snaps {
+					items[i] = snapshotLabel(snap, annotations)
+				}
+				idx, _, err := prompter.Select("Select snapshot to restore", items, items[0])
+				if err != nil {
+					return err
+				}
+				id = snaps[idx].ID
+			}
+
+			if !force {
+				confirm, err := prompter.Confirm(fmt.Sprintf("Restore snapshot %s? (y/N)", id), false)
+				if err != nil {
+					return err
+				}
+				if !confirm {
+					fmt.Fprintln(stdout, "Restore cancelled.")
+					return nil
+				}
+			}
+
+			snap, err := mgr.RestoreSnapshot(id)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(stdout, "Restored snapshot %s (%s).\n", snap.ID, snap.Operation)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&force, "force", false, "Do not prompt for confirmation")
+	cmd.Flags().StringSliceVar(&keys, "keys", nil, "Restore only these comma-separated JSON paths from a backup")
+	cmd.Flags().StringVar(&profile, "profile", "", "With --keys, restore into a stored profile instead of settings.json")
+	cmd.Flags().BoolVar(&noValidate, "no-validate", false, "With --keys, skip checking the result against the settings schema")
+
+	return cmd
+}
+
+// restoreKeys merges selected keys from a backup after previewing the change.
+func restoreKeys(mgr *ccs.Manager, prompter Prompter, stdout, stderr io.Writer, hash string, keys []string, opts ccs.KeyRestoreOptions, force bool) error {
+	plan, err := mgr.PlanKeyRestore(hash, keys, opts)
+	if err != nil {
+		return err
+	}
+	printSchemaWarnings(stderr, plan.Warnings)
+	if len(plan.Changes) == 0 {
+		fmt.Fprintf(stdout, "%s already matches backup %s for the selected keys.\n", plan.File, ccs.ShortHash(plan.Hash))
+		return nil
+	}
+
+	fmt.Fprintf(stdout, "Changes to %s from backup %s:\n", plan.File, ccs.ShortHash(plan.Hash))
+	for _, change := range plan.Changes {
+		fmt.Fprintf(stdout, "  %s\n", change.Key)
+		fmt.Fprintf(stdout, "  - %s\n", keyValueLabel(change.Old, change.OldPresent))
+		fmt.Fprintf(stdout, "  + %s\n", keyValueLabel(change.New, change.NewPresent))
+	}
+
+	if !force {
+		confirm, err := prompter.Confirm(fmt.Sprintf("Apply %d change(s)? (y/N)", len(plan.Changes)), false)
+		if err != nil {
+			return err
+		}
+		if !confirm {
+			fmt.Fprintln(stdout, "Restore cancelled.")
+			return nil
+		}
+	}
+
+	if err := mgr.ApplyKeyRestore(plan); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Restored %d key(s) into %s.\n", len(plan.Changes), plan.File)
+	return nil
+}
+
+// keyValueLabel renders a key's value, or "(absent)" when it is not set.
+func keyValueLabel(value any, present bool) string {
+	if !present {
+		return "(absent)"
+	}
+	return renderValue(value)
+}
@@ -0,0 +1,47 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+func TestSaveCommandWarnsAboutSecretsAndStrictRefuses(t *testing.T) {
+	mgr := newTestCommandManager(t)
+	settings := `{"env": {"ANTHROPIC_AUTH_TOKEN": "sk-ant-REDACTED"}}`
+	if err := afero.WriteFile(mgr.FileSystem(), mgr.ActiveSettingsPath(), []byte(settings), 0o644); err != nil {
+		t.Fatalf("write active: %v", err)
+	}
+
+	prompter := &stubPrompter{
+		selects: []selectResponse{{value: newSettingsLabel}, {value: newSettingsLabel}},
+		prompts: []promptResponse{{value: "strict"}, {value: "work"}},
+	}
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	cmd := newSaveCommand(mgr, prompter)
+	cmd.SetOut(stdout)
+	cmd.SetErr(stderr)
+	if err := cmd.Flags().Set("strict", "true"); err != nil {
+		t.Fatalf("set flag: %v", err)
+	}
+	err := cmd.RunE(cmd, nil)
+	if err == nil || !strings.Contains(err.Error(), "refusing to store values that look like secrets") {
+		t.Fatalf("expected a strict refusal, got %v", err)
+	}
+
+	if err := cmd.Flags().Set("strict", "false"); err != nil {
+		t.Fatalf("set flag: %v", err)
+	}
+	if err := cmd.RunE(cmd, nil); err != nil {
+		t.Fatalf("RunE save: %v", err)
+	}
+	want := "Warning: settings.json: env.ANTHROPIC_AUTH_TOKEN: Anthropic API key (sk-a****) looks like a secret"
+	if !strings.Contains(stderr.String(), want) {
+		t.Fatalf("expected %q in stderr:\n%s", want, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "Successfully saved and activated settings: work") {
+		t.Fatalf("unexpected stdout:\n%s", stdout.String())
+	}
+}